@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: MIT
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-github/v82/github"
+	"github.com/spf13/cobra"
+
+	"github.com/esacteksab/gh-actlock/pinner"
+)
+
+// defaultAuditDepth is how deep --transitive follows dependencies unless --depth says otherwise.
+const defaultAuditDepth = 5
+
+var (
+	auditTransitive bool // Whether to follow composite actions and reusable workflows
+	auditDepth      int  // How many levels of dependencies to follow
+)
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+	auditCmd.Flags().
+		BoolVar(&auditTransitive, "transitive", false,
+			"also audit the actions and workflows used by composite actions and reusable workflows")
+	auditCmd.Flags().
+		IntVar(&auditDepth, "depth", defaultAuditDepth, "maximum depth of dependencies to follow with --transitive")
+}
+
+var auditCmd = &cobra.Command{
+	Use:   "audit [file|dir]...",
+	Short: "Show the actions and workflows each workflow depends on, as a tree",
+	Long: `Prints every action and reusable workflow reference in the workflow and
+action files as a tree, marking the ones that are not pinned to a commit SHA.
+
+With --transitive, the action.yml of every action and the file of every reusable
+workflow is fetched at the pinned SHA using the contents API, and the references
+it contains are audited too, up to --depth levels deep. Pinning owner/repo@sha
+doesn't protect against a composite action that itself uses other/action@v1.
+
+Exits with a non-zero status if an unpinned reference is found, or a dependency
+can't be fetched. Like the root command, files and directories can be passed to
+audit exactly those.`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+
+		if auditDepth < 1 {
+			return fmt.Errorf("invalid --depth value %d: must be at least 1", auditDepth)
+		}
+		if err := loadConfig(cmd); err != nil {
+			return err
+		}
+
+		files, err := collectTargetFiles(Root, args)
+		if err != nil {
+			return err
+		}
+
+		// Only --transitive needs to talk to GitHub.
+		var client *github.Client
+		maxDepth := 0
+		if auditTransitive {
+			client, err = newClient(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to initialize GitHub client: %w", err)
+			}
+			maxDepth = auditDepth
+		}
+		p, err := pinnerFromFlags(client)
+		if err != nil {
+			return err
+		}
+		a := pinner.NewAuditor(p, maxDepth)
+
+		for _, filePath := range files {
+			Logger.Debugf("Auditing workflow: %s", filePath)
+			root, err := a.Audit(ctx, filePath)
+			if err != nil {
+				return fmt.Errorf("failed to audit %s: %w", filePath, err)
+			}
+			root.Print(cmd.OutOrStdout())
+		}
+
+		switch {
+		case a.Failed > 0:
+			return fmt.Errorf("found %d unpinned reference(s), and %d dependency(ies) couldn't be audited",
+				a.Unpinned, a.Failed)
+		case a.Unpinned > 0:
+			return fmt.Errorf("found %d unpinned reference(s)", a.Unpinned)
+		}
+		Logger.Printf("✅  All actions and workflows are pinned to commit SHAs")
+		return nil
+	},
+}
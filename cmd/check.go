@@ -0,0 +1,101 @@
+// SPDX-License-Identifier: MIT
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/google/go-github/v82/github"
+)
+
+// checkVerifyCommits makes check also verify that pinned commits belong to their repositories.
+var checkVerifyCommits bool
+
+func init() {
+	checkCmd.Flags().BoolVar(&checkVerifyCommits, "verify-commits", false,
+		"verify that every pinned commit is reachable from a branch or tag of its repository (needs a GitHub token)")
+	rootCmd.AddCommand(checkCmd)
+}
+
+var checkCmd = &cobra.Command{
+	Use:   "check [file|dir]...",
+	Short: "Report actions and workflows that are not pinned to a SHA",
+	Long: `Scans the same workflow and action files as the root command without
+modifying them, and reports every 'uses:' reference that is not pinned to a
+full 40-character commit SHA. Exits with a non-zero status when any are found,
+so it can be used as a gate in CI. No GitHub token or network access is needed.
+
+With --verify-commits, every commit SHA that is already pinned is also checked to
+be reachable from a branch or tag of the referenced repository. GitHub serves
+commits pushed to any fork through the parent repository, so a SHA alone doesn't
+prove the code came from the repository it names. This check uses the GitHub API.
+
+Like the root command, files and directories can be passed to check exactly those.`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		out := cmd.OutOrStdout()
+
+		// Skip patterns and file filters from the configuration apply to check too.
+		if err := loadConfig(cmd); err != nil {
+			return err
+		}
+
+		files, err := collectTargetFiles(Root, args)
+		if err != nil {
+			return err
+		}
+
+		// The commits are only verified on request, as it is the one part of check that needs the API.
+		var client *github.Client
+		if checkVerifyCommits {
+			client, err = newClient(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to initialize GitHub client: %w", err)
+			}
+		}
+		p, err := pinnerFromFlags(client)
+		if err != nil {
+			return err
+		}
+
+		violations, impostors, unverified := 0, 0, 0
+		for _, filePath := range files {
+			Logger.Debugf("Checking workflow: %s", filePath)
+
+			result, err := p.Check(ctx, filePath)
+			if err != nil {
+				return fmt.Errorf("failed to check %s: %w", filePath, err)
+			}
+			for _, finding := range result.Unpinned {
+				fmt.Fprintf(out, "%s:%d: %s\n", filePath, finding.Line, finding.Message)
+			}
+			for _, finding := range result.Impostors {
+				fmt.Fprintf(out, "%s:%d: %s\n", filePath, finding.Line, finding.Message)
+			}
+			violations += len(result.Unpinned)
+			impostors += len(result.Impostors)
+			unverified += result.Unverified
+		}
+
+		switch {
+		case impostors > 0 && violations > 0:
+			return fmt.Errorf("found %d unpinned action(s) or workflow(s), and %d impostor commit(s)",
+				violations, impostors)
+		case impostors > 0:
+			return fmt.Errorf("found %d reference(s) pinned to an impostor commit", impostors)
+		}
+		// A commit that doesn't exist, or couldn't be verified for any reason, fails the check rather than passing it.
+		if unverified > 0 {
+			return fmt.Errorf("could not verify %d pinned commit(s)", unverified)
+		}
+		if violations > 0 {
+			return fmt.Errorf("found %d unpinned action(s) or workflow(s)", violations)
+		}
+		Logger.Printf("✅  All actions and workflows are pinned to commit SHAs")
+		return nil
+	},
+}
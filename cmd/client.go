@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: MIT
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/google/go-github/v82/github"
+
+	"github.com/esacteksab/gh-actlock/githubclient"
+)
+
+// actlockAPIURL names the environment variable that points actlock at another GitHub
+// API, such as a proxy or a fake one.
+const actlockAPIURL = "ACTLOCK_API_URL"
+
+// actlockAPIToken names the environment variable with the token for the API at
+// ACTLOCK_API_URL. The GitHub.com token is never sent there.
+const actlockAPIToken = "ACTLOCK_API_TOKEN"
+
+// Hostname is the GitHub host given by --hostname. When empty, GH_HOST is used, as
+// the gh CLI does, and then github.com.
+var Hostname string
+
+// githubHost returns the GitHub host references are resolved against.
+//
+// Returns: The hostname, e.g. "github.com" or a GitHub Enterprise Server instance.
+func githubHost() string {
+	if Hostname != "" {
+		return Hostname
+	}
+	if host := os.Getenv("GH_HOST"); host != "" {
+		return host
+	}
+	return githubclient.DefaultHost
+}
+
+// newClient creates the GitHub client every command that talks to GitHub uses. When
+// ACTLOCK_API_URL is set, the client talks to the GitHub.com API there instead, e.g. to
+// a fake API in tests, authenticated with ACTLOCK_API_TOKEN only. With --offline, the
+// client only reads the cache, and the project's lockfile is loaded for the lookups to
+// try first.
+//
+// - ctx: The context for the client.
+// Returns: The client for githubHost, and an error if it can't be set up or if both
+// ACTLOCK_API_URL and --hostname are given.
+func newClient(ctx context.Context) (*github.Client, error) {
+	host := githubHost()
+	apiURL := os.Getenv(actlockAPIURL)
+	if apiURL != "" && Hostname != "" {
+		return nil, fmt.Errorf("--hostname can't be used with %s, which sets the API to talk to", actlockAPIURL)
+	}
+	if apiURL != "" {
+		Logger.Debugf("Using the GitHub API at %s (from %s)", apiURL, actlockAPIURL)
+	} else {
+		Logger.Debugf("Using GitHub host: %s", host)
+	}
+	// Responses from ACTLOCK_API_URL are cached by its host, apart from GitHub.com's.
+	target, cacheHost := githubclient.WithHost(host), host
+	if apiURL != "" {
+		target, cacheHost = githubclient.WithBaseURL(apiURL), apiURL
+	}
+	cache, err := githubclient.HostCache(cacheHost)
+	if err != nil {
+		return nil, err
+	}
+	opts := []githubclient.Option{githubclient.WithLogger(Logger), target, githubclient.WithCache(cache)}
+	if Offline {
+		if err := loadOfflineLock(); err != nil {
+			return nil, err
+		}
+		Logger.Printf("📴  Offline: resolving references from the lockfile and the cache only")
+		return githubclient.New(append(opts, githubclient.WithOffline())...)
+	}
+
+	credentials := githubclient.WithCredential(githubclient.Credential{Token: os.Getenv(actlockAPIToken), Source: actlockAPIToken})
+	if apiURL == "" {
+		if credentials, err = githubclient.HostCredentials(ctx, host, Logger); err != nil {
+			return nil, err
+		}
+	}
+	client, err := githubclient.New(append(opts, githubclient.WithMaxWait(MaxWait), credentials)...)
+	if err != nil {
+		return nil, err
+	}
+	githubclient.ReportRateLimit(ctx, client)
+	return client, nil
+}
@@ -0,0 +1,70 @@
+// SPDX-License-Identifier: MIT
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/esacteksab/gh-actlock/config"
+	"github.com/esacteksab/gh-actlock/githubclient"
+)
+
+// ConfigFile is the path given by --config. When empty, <root>/.github/actlock.yml
+// (or actlock.yaml) is used if it exists.
+var ConfigFile string
+
+// cfg is the loaded configuration. It stays nil when there is no configuration file,
+// and every *config.Config method treats nil as "use the defaults".
+var cfg *config.Config
+
+// loadConfig reads and validates the configuration file, then applies the settings
+// that have command line equivalents. Flags given explicitly take precedence.
+//
+// - cmd: The command being run, used to tell which flags were set explicitly.
+// Returns: An error if the configuration file can't be read or is invalid.
+func loadConfig(cmd *cobra.Command) error {
+	path := ConfigFile
+	if path == "" {
+		path = config.Find(Root)
+	}
+	if path == "" {
+		Logger.Debugf("No config file found in %s", Root)
+		applyUpdatePolicy(cmd)
+		return nil
+	}
+
+	loaded, err := config.Load(path)
+	if err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	cfg = loaded
+	Logger.Debugf("Loaded config file: %s", path)
+	applyUpdatePolicy(cmd)
+
+	if flag := cmd.Flags().Lookup("pin-docker"); flag != nil && !flag.Changed && cfg.PinDocker {
+		PinDocker = true
+	}
+	if flag := cmd.Flags().Lookup("lock"); flag != nil && !flag.Changed && cfg.Lock != nil {
+		Lock = *cfg.Lock
+	}
+	if flag := cmd.Flags().Lookup("comment-tag"); flag != nil && !flag.Changed && cfg.Comment.Tag != "" {
+		CommentTag = cfg.Comment.Tag
+	}
+	return nil
+}
+
+// applyUpdatePolicy sets the policy -u/--update uses when --policy isn't given: the
+// configured one, or 'latest' without one, as -u/--update always did.
+//
+// - cmd: The command being run, used to tell whether --policy was given.
+func applyUpdatePolicy(cmd *cobra.Command) {
+	if flag := cmd.Flags().Lookup("policy"); flag == nil || flag.Changed {
+		return
+	}
+	UpdatePolicy = string(githubclient.UpdateLatest)
+	if cfg != nil && cfg.Update.Policy != "" {
+		UpdatePolicy = cfg.Update.Policy
+	}
+}
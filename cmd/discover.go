@@ -0,0 +1,268 @@
+// SPDX-License-Identifier: MIT
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/esacteksab/gh-actlock/utils"
+)
+
+// collectTargetFiles returns the files a command should process. With no path
+// arguments it discovers the workflow and action files under the project root's
+// .github directory, filtered by the configured include and exclude patterns;
+// otherwise it expands exactly the given files and directories.
+//
+// - root: The project root directory.
+// - paths: The file and directory arguments given on the command line, may be empty.
+// Returns: The paths of the files to process, and an error if an argument cannot be read.
+func collectTargetFiles(root string, paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		return cfg.SelectFiles(root, discoverWorkflowFiles(root)), nil
+	}
+
+	var files []string
+	seen := make(map[string]bool) // Avoid processing a file twice when arguments overlap
+	for _, path := range paths {
+		expanded, err := expandPathArg(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range expanded {
+			if !seen[file] {
+				seen[file] = true
+				files = append(files, file)
+			}
+		}
+	}
+	return files, nil
+}
+
+// expandPathArg turns a single command line argument into the files it refers to.
+// A file is returned as-is, whatever its name. A directory is walked recursively
+// for YAML files that look like actions or workflows.
+//
+// - path: A file or directory path.
+// Returns: The files found, and an error if the path doesn't exist or can't be walked.
+func expandPathArg(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot access '%s': %w", path, err)
+	}
+	if !info.IsDir() {
+		return []string{filepath.Clean(path)}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			// Never descend into git's own metadata.
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isYAMLFile(d.Name()) && isActionCandidate(filePath) {
+			files = append(files, filePath)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error walking directory '%s': %w", path, err)
+	}
+	return files, nil
+}
+
+// isYAMLFile reports whether a file name has a .yml or .yaml extension.
+// Files starting with '.' (like .gitignore) are never considered.
+//
+// - name: The base name of the file.
+// Returns: true if the file is a visible YAML file.
+func isYAMLFile(name string) bool {
+	if strings.HasPrefix(name, ".") {
+		return false
+	}
+	return strings.HasSuffix(name, ".yml") || strings.HasSuffix(name, ".yaml")
+}
+
+// isActionCandidate reports whether a YAML file is worth processing: either it is
+// action metadata (action.yml/action.yaml) or it contains a 'uses:' key.
+//
+// - filePath: The path of the YAML file.
+// Returns: true if the file should be processed.
+func isActionCandidate(filePath string) bool {
+	// Fast pre-check: files specifically named action.yml/action.yaml (action metadata)
+	// are always candidates.
+	nameLower := strings.ToLower(filepath.Base(filePath))
+	if nameLower == "action.yml" || nameLower == "action.yaml" {
+		return true
+	}
+
+	data, err := os.ReadFile(filePath) //nolint:gosec
+	if err != nil {
+		Logger.Errorf("❌  Failed to read %s: %v", filePath, err)
+		return false
+	}
+	// dependabot.yml and releases.yml exists in .github, which previously got scanned
+	// we don't want to scan them, so this does some (hopefully not) naive searching for 'uses:'
+	// which is the yaml key we use to update actions & workflows. This is an attempt to not scan things
+	// that don't have that string in them.
+	if !strings.Contains(string(data), "uses:") {
+		Logger.Debugf("Skipping non-action file: %s", filePath)
+		return false
+	}
+	return true
+}
+
+// discoverWorkflowFiles returns the workflow and action files that should be processed.
+// It reads the YAML files in .github/workflows and the top level of .github, skipping
+// files in .github that neither look like action metadata nor contain a 'uses:' key,
+// and then adds every action.yml/action.yaml found anywhere under the root.
+//
+// - root: The project root directory containing .github.
+// Returns: The paths of the candidate files, workflows first, then .github, then other actions.
+func discoverWorkflowFiles(root string) []string {
+	var files []string
+
+	// Construct the paths to the GitHub and workflows directories.
+	githubDir := filepath.Join(root, ghDir)
+	workflowsDir := filepath.Join(githubDir, wfDir)
+	// Read the workflows directory entries.
+	workflows, err := os.ReadDir(workflowsDir)
+	if err != nil {
+		// If the directory doesn't exist, provide a specific error message.
+		if errors.Is(err, fs.ErrNotExist) {
+			Logger.Errorf("Workflows directory not found: %s", workflowsDir)
+		}
+		// For any other error reading the directory, log a error.
+		Logger.Errorf("Error reading workflows directory '%s': %v", workflowsDir, err)
+	}
+
+	// If no files are found in the directory, print a message.
+	if len(workflows) == 0 {
+		Logger.Printf("No workflow files found in %s", workflowsDir)
+	}
+	actions, err := os.ReadDir(githubDir)
+	if err != nil {
+		// if the directory doesn't exist, provide a specific error message.
+		if errors.Is(err, fs.ErrNotExist) {
+			Logger.Fatalf("GitHub directory not found: %s", githubDir)
+		}
+		// for any other error reading the directory, log a fatal error.
+		Logger.Fatalf("Error reading GitHub directory '%s': %v", githubDir, err)
+	}
+
+	Logger.Debugf("Found %d potential workflow files in %s", len(workflows), workflowsDir)
+
+	// Iterate through each entry found in the workflows directory.
+	for _, wf := range workflows {
+		// Skip directories, hidden files and anything that isn't YAML.
+		if wf.IsDir() {
+			continue
+		}
+		if !isYAMLFile(wf.Name()) {
+			Logger.Debugf("Skipping non-YAML file: %s", wf.Name())
+			continue
+		}
+
+		// Construct the full path to the workflow file.
+		files = append(files, filepath.Join(workflowsDir, wf.Name()))
+	}
+	// Iterate through each entry found in the GitHub directory.
+	for _, action := range actions {
+		// Skip directories, hidden files and anything that isn't YAML.
+		if action.IsDir() {
+			continue
+		}
+		if !isYAMLFile(action.Name()) {
+			Logger.Debugf("Skipping non-YAML file: %s", action.Name())
+			continue
+		}
+
+		// Construct the full path to the action file.
+		filePath := filepath.Join(githubDir, action.Name())
+		if !isActionCandidate(filePath) {
+			continue
+		}
+
+		Logger.Debugf("Found action: %s", filePath)
+		files = append(files, filePath)
+	}
+
+	// Composite actions usually live in their own directory (e.g. .github/actions/<name>/action.yml),
+	// so also search the whole project for action metadata files.
+	seen := make(map[string]bool, len(files))
+	for _, file := range files {
+		seen[file] = true
+	}
+	for _, filePath := range findActionMetadataFiles(root) {
+		if !seen[filePath] {
+			Logger.Debugf("Found action: %s", filePath)
+			files = append(files, filePath)
+		}
+	}
+
+	return files
+}
+
+// findActionMetadataFiles walks the project root recursively for action.yml and
+// action.yaml files, skipping the .git directory and anything matched by the
+// project's .gitignore files (including nested ones and .git/info/exclude).
+//
+// - root: The project root directory.
+// Returns: The paths of the action metadata files found, in lexical order.
+func findActionMetadataFiles(root string) []string {
+	var files []string
+	ignore := &utils.GitIgnore{}
+	if err := ignore.AddFile(filepath.Join(root, ".git", "info", "exclude"), ""); err != nil {
+		Logger.Debugf("Ignoring unreadable exclude file: %v", err)
+	}
+
+	err := filepath.WalkDir(root, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable directories are skipped rather than aborting discovery.
+			Logger.Debugf("Skipping %s: %v", filePath, err)
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if rel == "." {
+				rel = ""
+			} else if d.Name() == ".git" || ignore.Ignored(rel, true) {
+				return filepath.SkipDir
+			}
+			// Rules in a directory's .gitignore apply to everything below it.
+			if err := ignore.AddFile(filepath.Join(filePath, ".gitignore"), rel); err != nil {
+				Logger.Debugf("Ignoring unreadable .gitignore: %v", err)
+			}
+			return nil
+		}
+
+		nameLower := strings.ToLower(d.Name())
+		if (nameLower == "action.yml" || nameLower == "action.yaml") && !ignore.Ignored(rel, false) {
+			files = append(files, filePath)
+		}
+		return nil
+	})
+	if err != nil {
+		Logger.Errorf("Error searching %s for actions: %v", root, err)
+	}
+	return files
+}
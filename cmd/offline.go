@@ -0,0 +1,34 @@
+// SPDX-License-Identifier: MIT
+
+package cmd
+
+import (
+	"errors"
+	"io/fs"
+
+	"github.com/esacteksab/gh-actlock/lockfile"
+)
+
+// Offline is set by --offline: references are resolved from the lockfile and the HTTP
+// cache only, and no connection is ever opened.
+var Offline bool
+
+// offlineLock is the project's lockfile, which offline lookups try before the cache.
+// It is nil when not offline or when the project has no lockfile.
+var offlineLock *lockfile.Lockfile
+
+// loadOfflineLock loads the project's lockfile for offline lookups.
+//
+// Returns: An error if the lockfile exists but can't be read.
+func loadOfflineLock() error {
+	lock, err := lockfile.Load(lockfile.Path(Root))
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		Logger.Debugf("Offline: no lockfile, resolving from the cache only")
+		return nil
+	case err != nil:
+		return err
+	}
+	offlineLock = lock
+	return nil
+}
@@ -0,0 +1,54 @@
+// SPDX-License-Identifier: MIT
+
+package cmd
+
+import (
+	"io"
+	"os"
+
+	"github.com/google/go-github/v82/github"
+
+	"github.com/esacteksab/gh-actlock/githubclient"
+	"github.com/esacteksab/gh-actlock/pinner"
+	"github.com/esacteksab/gh-actlock/registry"
+)
+
+// pinnerFromFlags creates the Pinner the commands use, from their flags and the
+// configuration loaded by loadConfig.
+//
+// - client: The GitHub client, nil for commands that only scan.
+// Returns: The Pinner, and an error if a flag's value is invalid.
+func pinnerFromFlags(client *github.Client) (*pinner.Pinner, error) {
+	opts := []pinner.Option{
+		pinner.WithLogger(Logger),
+		pinner.WithConfig(cfg),
+		pinner.WithRoot(Root),
+		pinner.WithJobs(Jobs),
+		pinner.WithCommentTag(CommentTag),
+	}
+	if Update {
+		opts = append(opts, pinner.WithUpdatePolicy(githubclient.UpdatePolicy(UpdatePolicy)))
+	}
+	if DryRun {
+		// A structured report owns stdout, so the diff is only printed in text mode.
+		var diff io.Writer = os.Stdout
+		if results != nil {
+			diff = nil
+		}
+		opts = append(opts, pinner.WithDryRun(diff))
+	}
+	// Registry lookups are opt-in; without a client, docker:// references are skipped.
+	if PinDocker {
+		opts = append(opts, pinner.WithRegistry(registry.NewClient(nil)))
+	}
+	if results != nil {
+		opts = append(opts, pinner.WithReport(results))
+	}
+	if Offline {
+		opts = append(opts, pinner.WithOffline(offlineLock))
+	}
+	if VerifyCommits {
+		opts = append(opts, pinner.WithVerifyCommits())
+	}
+	return pinner.New(client, opts...)
+}
@@ -0,0 +1,11 @@
+// SPDX-License-Identifier: MIT
+
+package cmd
+
+import (
+	"time"
+)
+
+// MaxWait is the --max-wait budget: how long a run waits out GitHub's rate limits, in
+// total, before the references it couldn't resolve are skipped.
+var MaxWait time.Duration
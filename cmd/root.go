@@ -4,21 +4,15 @@ package cmd
 
 import (
 	"context"
-	"errors"
-	"fmt"
-	"io/fs"
 	"os"
-	"path/filepath"
 	"strconv"
-	"strings"
 
 	"github.com/charmbracelet/log"
-	"github.com/google/go-github/v82/github"
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v3"
 
 	"github.com/esacteksab/gh-actlock/githubclient"
-	"github.com/esacteksab/gh-actlock/parser"
+	"github.com/esacteksab/gh-actlock/pinner"
+	"github.com/esacteksab/gh-actlock/report"
 	"github.com/esacteksab/gh-actlock/utils"
 )
 
@@ -30,17 +24,30 @@ const (
 
 // Variables to hold build information, populated at build time.
 var (
-	Version string // Application version
-	Date    string // Build date
-	Commit  string // Git commit hash
-	BuiltBy string // Builder identifier
-	Update  bool   // Whether to update SHAs
-	Clear   bool   // Whether to clear cache
-	Logger  *log.Logger
+	Version       string // Application version
+	Date          string // Build date
+	Commit        string // Git commit hash
+	BuiltBy       string // Builder identifier
+	Update        bool   // Whether to update SHAs
+	UpdatePolicy  string // How update mode moves a reference: "tracked", "patch", "minor", "major", or "latest"
+	DryRun        bool   // Whether to print a diff instead of writing files
+	Output        string // Report format: "text", "json", or "sarif"
+	Root          string // Project root containing .github; files outside it are never modified
+	Clear         bool   // Whether to clear cache
+	PinDocker     bool   // Whether to pin docker:// images to digests
+	Jobs          int    // How many references to resolve concurrently
+	Lock          bool   // Whether to record resolved references in the lockfile
+	VerifyCommits bool   // Whether to check already pinned commits belong to their repositories
+	CommentTag    string // Which tag inline comments name: "ref", "specific", or "both"
+	Logger        *log.Logger
 )
 
 const actlockDebug = "ACTLOCK_DEBUG"
 
+// results collects a report entry for every 'uses:' reference when a structured
+// --output format is requested. It is nil in the default text mode.
+var results *report.Report
+
 // init is automatically run before the main function.
 // It sets the version information for the root command using build-time variables.
 func init() {
@@ -49,6 +56,39 @@ func init() {
 	// SetVersionTemplate customizes how the version is printed.
 	rootCmd.SetVersionTemplate(`{{printf "Version %s" .Version}}`)
 	rootCmd.Flags().BoolVarP(&Update, "update", "u", false, "update SHAs")
+	rootCmd.Flags().StringVar(&UpdatePolicy, "policy", "",
+		"`policy` -u/--update moves references by: tracked, patch, minor, major, or latest (default the config's update.policy, or latest)")
+	rootCmd.PersistentFlags().
+		StringVar(&Root, "root", ".", "project root containing the .github directory")
+	rootCmd.PersistentFlags().
+		StringVar(&ConfigFile, "config", "", "config file (default <root>/.github/actlock.yml)")
+	rootCmd.PersistentFlags().
+		StringVar(&Hostname, "hostname", "",
+			"GitHub `host` to resolve references against, e.g. a GitHub Enterprise Server (default $GH_HOST or github.com)")
+	rootCmd.PersistentFlags().
+		DurationVar(&MaxWait, "max-wait", githubclient.DefaultMaxWait,
+			"how long to wait out GitHub rate limits, in total, before skipping references (0 to never wait)")
+	rootCmd.PersistentFlags().
+		BoolVar(&Offline, "offline", false,
+			"resolve references from the lockfile and the cache only, without network access")
+	rootCmd.Flags().
+		BoolVar(&DryRun, "dry-run", false, "print a unified diff of the changes instead of writing files")
+	// --diff is an alias for --dry-run; both flags share the same variable.
+	rootCmd.Flags().BoolVar(&DryRun, "diff", false, "alias for --dry-run")
+	rootCmd.Flags().
+		StringVarP(&Output, "output", "o", "text", "report format written to stdout: text, json, or sarif")
+	rootCmd.Flags().
+		BoolVar(&PinDocker, "pin-docker", false, "pin docker:// actions and job container and service images to their manifest digests")
+	rootCmd.Flags().
+		BoolVar(&Lock, "lock", true, "record every pinned reference in <root>/.github/actlock.lock, which verify checks against (--lock=false to skip)")
+	rootCmd.Flags().
+		IntVarP(&Jobs, "jobs", "j", pinner.DefaultJobs, "number of references to resolve concurrently")
+	rootCmd.Flags().
+		BoolVar(&VerifyCommits, "verify-commits", false,
+			"also check that already pinned commits are reachable from a branch or tag of their repository")
+	rootCmd.Flags().
+		StringVar(&CommentTag, "comment-tag", pinner.CommentTagRef,
+			"tag named in inline comments: ref, specific (the most specific tag, e.g. v4.2.2), or both")
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -83,818 +123,137 @@ func Execute() {
 // rootCmd represents the base command when called without any subcommands.
 // It is the entry point for the actlock application.
 var rootCmd = &cobra.Command{
-	Use:   "actlock [arg]",                                              // How the command is invoked
+	Use:   "actlock [file|dir]...",                                      // How the command is invoked
 	Short: "actlock locks GitHub Actions to SHAs for greater security.", // Short description
+	Long: `actlock locks GitHub Actions to SHAs for greater security.
+
+Without arguments, the workflow and action files under <root>/.github are
+processed. Pass files or directories to process exactly those instead;
+directories are searched recursively for action and workflow YAML files.`,
 	// SilenceUsage prevents usage being printed on error (errors are handled explicitly).
 	SilenceUsage: true,
-	Args:         cobra.MaximumNArgs(1),
+	Args:         cobra.ArbitraryArgs,
 	// Run defines the main logic of the command when it's executed.
 	Run: func(cmd *cobra.Command, args []string) {
-		if len(args) > 0 {
-			fmt.Println("Echo: ", args[0])
+		// The configuration file may set a default update policy and turn on --pin-docker.
+		if err := loadConfig(cmd); err != nil {
+			Logger.Fatalf("%v", err)
 		}
 
+		// A policy only says how to update, so it's meaningless without -u/--update.
+		if cmd.Flags().Changed("policy") && !Update {
+			Logger.Fatalf("--policy only applies with -u/--update")
+		}
 		if Update {
-			Logger.Debugf("Running in update mode: will update actions to latest versions")
+			if _, err := githubclient.ParseUpdatePolicy(UpdatePolicy); err != nil {
+				Logger.Fatalf("%v", err)
+			}
+			Logger.Debugf("Running in update mode: will update actions using the %s policy", UpdatePolicy)
 		} else {
 			Logger.Debugf("Running in pin mode: will pin actions to specific SHAs")
 		}
+		if DryRun {
+			Logger.Debugf("Running in dry-run mode: files will not be modified")
+		}
+		if Jobs < 1 {
+			Logger.Fatalf("Invalid --jobs value %d: must be at least 1", Jobs)
+		}
+		switch CommentTag {
+		case pinner.CommentTagRef, pinner.CommentTagSpecific, pinner.CommentTagBoth:
+		default:
+			Logger.Fatalf("Unknown --comment-tag %q: expected ref, specific, or both", CommentTag)
+		}
+
+		// A structured report collects an entry for every reference and is written once all files are processed.
+		switch Output {
+		case "text":
+		case "json", "sarif":
+			results = report.New()
+		default:
+			Logger.Fatalf("Unknown output format %q: expected text, json, or sarif", Output)
+		}
 		// context.Background() is the default context, suitable for the top-level command.
 		ctx := context.Background()
 
 		// Initialize the GitHub client using the dedicated package.
-		client, err := githubclient.NewClient(ctx)
+		client, err := newClient(ctx)
 		if err != nil {
 			// Log a fatal error and exit if the client cannot be initialized.
 			Logger.Fatalf("Failed to initialize GitHub client: %v", err)
 		}
 
-		// Construct the path to the workflows directory.
-		workflowsDir := filepath.Join(ghDir, wfDir)
-		// Read the workflows directory entries.
-		workflows, err := os.ReadDir(workflowsDir)
+		// The flags, checked above, configure how references are pinned.
+		p, err := pinnerFromFlags(client)
 		if err != nil {
-			// If the directory doesn't exist, provide a specific error message.
-			if errors.Is(err, fs.ErrNotExist) {
-				Logger.Errorf("Workflows directory not found: %s", workflowsDir)
-			}
-			// For any other error reading the directory, log a error.
-			Logger.Errorf("Error reading workflows directory '%s': %v", workflowsDir, err)
+			Logger.Fatalf("%v", err)
 		}
 
-		// If no files are found in the directory, print a message.
-		if len(workflows) == 0 {
-			Logger.Printf("No workflow files found in %s", workflowsDir)
-		}
-		actions, err := os.ReadDir(ghDir)
+		// Work out which files to process before making any API calls.
+		files, err := collectTargetFiles(Root, args)
 		if err != nil {
-			// if the directory doesn't exist, provide a specific error message.
-			if errors.Is(err, fs.ErrNotExist) {
-				Logger.Fatalf("GitHub directory not found: %s", ghDir)
-			}
-			// for any other error reading the directory, log a fatal error.
-			Logger.Fatalf("Error reading GitHub directory '%s': %v", ghDir, err)
+			Logger.Fatalf("%v", err)
 		}
 
-		Logger.Debugf("Found %d potential workflow files in %s", len(workflows), workflowsDir)
-		totalUpdates := 0
-
-		// Iterate through each entry found in the workflows directory.
-		for _, wf := range workflows {
-			// Skip directories and files starting with '.' (like .gitignore).
-			if wf.IsDir() || strings.HasPrefix(wf.Name(), ".") {
-				continue
-			}
-			// Only process files with .yml or .yaml extensions (case-insensitive comparison isn't strictly needed here based on typical filenames).
-			if !strings.HasSuffix(wf.Name(), ".yml") && !strings.HasSuffix(wf.Name(), ".yaml") {
-				Logger.Debugf("Skipping non-YAML file: %s", wf.Name())
-				continue
-			}
-
-			// Construct the full path to the workflow file.
-			filePath := filepath.Join(workflowsDir, wf.Name())
-			Logger.Printf("Processing workflow: %s", filePath)
-
-			// Call the function to update SHAs within this specific workflow file.
-			updated, err := UpdateWorkflowActionSHAs(ctx, client, filePath)
-			if err != nil {
-				// Log errors related to processing a single file but continue to the next.
-				Logger.Errorf("❌  Failed to process %s: %v", filePath, err)
-			} else if updated > 0 {
-				// Log success if updates were made.
-				Logger.Printf("✅  Updated %d action(s) in %s", updated, filePath)
-				totalUpdates += updated
-			} else {
-				// Log if no updates were needed for the file.
-				Logger.Printf("ℹ️  No actions needed updating in %s", filePath)
-			}
+		// Every file is scanned before any is written, so references shared between files are resolved only once.
+		run, err := p.PinFiles(ctx, files)
+		if err != nil {
+			Logger.Fatalf("%v", err)
 		}
-		// Iterate through each entry found in the GitHub directory.
-		for _, action := range actions {
-			// Skip directories and files starting with '.' (like .gitignore).
-			if action.IsDir() || strings.HasPrefix(action.Name(), ".") {
-				continue
-			}
-			// Only process files with .yml or .yaml extensions (case-insensitive comparison isn't strictly needed here based on typical filenames).
-			if !strings.HasSuffix(action.Name(), ".yml") &&
-				!strings.HasSuffix(action.Name(), ".yaml") {
-				Logger.Debugf("Skipping non-YAML file: %s", action.Name())
-				continue
-			}
-
-			// Construct the full path to the action file.
-			filePath := filepath.Join(ghDir, action.Name())
-
-			// Fast pre-check: only process files that are likely to contain 'uses:' (action/workflow candidates),
-			// or files specifically named action.yml/action.yaml (action metadata).
-			nameLower := strings.ToLower(action.Name())
-			candidateByName := nameLower == "action.yml" || nameLower == "action.yaml"
-
-			data, err := os.ReadFile(filePath) //nolint:gosec
-			if err != nil {
-				Logger.Errorf("❌  Failed to read %s: %v", filePath, err)
-				continue
-			}
-			content := string(data)
-			// dependabot.yml and releases.yml exists in .github, which previously got scanned
-			// we don't want to scan them, so this does some (hopefully not) naive searching for 'uses:'
-			// which is the yaml key we use to update actions & workflows. This is an attempt to not scan things
-			// that don't have that string in them.
-			if !candidateByName && !strings.Contains(content, "uses:") {
-				Logger.Debugf("Skipping non-action file: %s", filePath)
-				continue
-			}
-
-			Logger.Debugf("Processing action: %s", filePath)
 
-			// Call the function to update SHAs within this specific workflow file.
-			updated, err := UpdateWorkflowActionSHAs(ctx, client, filePath)
+		// Record what every reference was pinned to. A partial run would drop the entries of
+		// the files that failed, so the lockfile is only written when every file was processed.
+		if Lock && DryRun {
+			Logger.Debugf("Dry run: not updating the lockfile")
+		} else if Lock && run.Unreadable > 0 {
+			Logger.Errorf("❌  Not updating the lockfile: some files could not be processed")
+		} else if Lock {
+			path, err := p.UpdateLockfile(run, len(args) > 0)
 			if err != nil {
-				// Log errors related to processing a single file but continue to the next.
-				Logger.Errorf("❌  Failed to process %s: %v", filePath, err)
-			} else if updated > 0 {
-				// Log success if updates were made.
-				Logger.Printf("✅  Updated %d action(s) in %s", updated, filePath)
-				totalUpdates += updated
+				Logger.Errorf("❌  %v", err)
 			} else {
-				// Log if no updates were needed for the file.
-				Logger.Printf("ℹ️  No actions needed updating in %s", filePath)
+				Logger.Printf("🔒  Updated lockfile %s", path)
 			}
 		}
-		// Final summary of total updates made across all files.
-		Logger.Printf(
-			"Finished processing. Total actions updated across all files: %d",
-			totalUpdates,
-		)
-	},
-}
-
-// findUpdatesInNodes recursively searches a YAML node tree for 'uses:' keys,
-// processes their values, and populates a map with line numbers requiring updates.
-//
-// - ctx: The context for API calls, allows for cancellation/timeouts.
-// - client: The initialized GitHub client for resolving SHAs.
-// - node: The current YAML node being processed.
-// - updates: A map where line numbers are keys and the desired new 'uses:' string values are the values.
-// - updatesMade: A pointer to an integer counter tracking the total number of updates found.
-// Returns: An error if a critical issue occurs during traversal or processing, otherwise nil.
-func findUpdatesInNodes(
-	ctx context.Context,
-	client *github.Client,
-	node *yaml.Node,
-	updates map[int]string,
-	updatesMade *int,
-) error {
-	// Different processing based on the type of YAML node
-	switch node.Kind {
-	case yaml.DocumentNode:
-		// A document node represents the root of a YAML document. Iterate its content.
-		for _, contentNode := range node.Content {
-			// Recursively call findUpdatesInNodes on the content node.
-			if err := findUpdatesInNodes(ctx, client, contentNode, updates, updatesMade); err != nil {
-				return err // Propagate errors from deeper levels.
-			}
-		}
-	case yaml.MappingNode:
-		// A mapping node represents key-value pairs (like a dictionary).
-		// Content is a slice of nodes: [key1, value1, key2, value2, ...].
-		for i := 0; i < len(node.Content); i += 2 {
-			keyNode := node.Content[i]     // The key node (e.g., 'uses')
-			valueNode := node.Content[i+1] // The value node (e.g., 'actions/checkout@v4')
 
-			// Check if the current key is 'uses' and the value is a simple scalar (a single string).
-			if keyNode.Kind == yaml.ScalarNode && keyNode.Value == "uses" &&
-				valueNode.Kind == yaml.ScalarNode {
-				// If it's a 'uses:' entry, handle its specific value.
-				err := handleUsesValue(ctx, client, valueNode, updates, updatesMade)
-				if err != nil {
-					// Log the error from handling the 'uses' value but continue processing other parts of the file.
-					Logger.Errorf(
-						"Error processing 'uses' value on line %d: %v. Skipping this entry.",
-						valueNode.Line,
-						err,
-					)
-					// The decision to continue or halt on error here is a design choice.
-					// Returning the error (`return err`) would stop processing the current file.
-					// Continuing (`continue`) processes other key-value pairs in the same mapping.
-					continue // Continue to the next key-value pair in the mapping.
-				}
-			} else {
-				// If the key is not 'uses' or the value is not a scalar (could be a map or list),
-				// recursively check the value node for nested 'uses' entries.
-				if err := findUpdatesInNodes(ctx, client, valueNode, updates, updatesMade); err != nil {
-					return err // Propagate errors from deeper levels.
-				}
-			}
-		}
-	case yaml.SequenceNode:
-		// A sequence node represents a list (e.g., a list of steps).
-		// Iterate through each item in the sequence.
-		for _, itemNode := range node.Content {
-			// Recursively call findUpdatesInNodes on each item.
-			if err := findUpdatesInNodes(ctx, client, itemNode, updates, updatesMade); err != nil {
-				return err // Propagate errors from deeper levels.
+		// Write the structured report, if one was requested, to stdout.
+		if results != nil {
+			if err := results.Write(cmd.OutOrStdout(), Output, Version); err != nil {
+				Logger.Fatalf("Failed to write %s report: %v", Output, err)
 			}
 		}
-		// Scalar nodes (simple values) and Alias nodes do not contain nested 'uses' entries,
-		// so no recursive call is needed for those kinds.
-	}
-	return nil // Return nil if traversal of this node and its children completes without a critical error.
-}
-
-// handleUsesValue processes a single YAML node representing the value of a 'uses:' key.
-// It parses the action reference, resolves the SHA, and adds an entry to the updates map if necessary.
-//
-// - ctx: The context for API calls.
-// - client: The initialized GitHub client.
-// - valueNode: The YAML scalar node containing the action string (e.g., "actions/checkout@v4").
-// - updates: The map to store line number -> new 'uses:' string mappings.
-// - updatesMade: A pointer to an integer counter to increment if an update is added.
-// Returns: An error if a significant issue occurs during SHA resolution, otherwise nil.
-func handleUsesValue(
-	ctx context.Context,
-	client *github.Client,
-	valueNode *yaml.Node,
-	updates map[int]string,
-	updatesMade *int,
-) error {
-	usesValue := valueNode.Value // Get the string value from the node
-	lineNum := valueNode.Line    // Get the original line number of this value
-
-	// Check if we have already identified an update for this specific line number
-	// This can happen if an alias points to a node containing 'uses', though rare
-	// It's a safety check to prevent duplicate processing of the same line
-	if _, exists := updates[lineNum]; exists {
-		return nil // This line is already scheduled for an update, skip reprocessing
-	}
-
-	// Use the parser package to break down the 'uses' string (e.g. owner/repo/action@ref)
-	action, err := parser.ParseActionReference(usesValue)
-	if err != nil {
-		// If parsing fails, log a warning and skip this action reference
-		// This is not a fatal error for the entire file
-		Logger.Errorf(
-			"⚠️ Skipping 'uses: %s' on line %d due to parsing error: %v",
-			usesValue,
-			lineNum,
-			err,
-		)
-		return nil // Indicate that this specific 'uses' value processing failed non-fatally
-	}
-
-	// We are only interested in pinning standard GitHub actions referenced as owner/repo/action@ref.
-	// Skip if it's not a 'github' type action (e.g., 'docker://...'), or if any required part is missing.
-	// Optionally uncomment the log below for more verbose output on skipped items.
-	// log.Printf("Skipping non-GitHub action or incomplete reference: %s", usesValue)
-	if action.Type != "github" || action.Name == "" || action.Repo == "" {
-		return nil
-	}
-
-	// Check if the ref is already a full SHA
-	isSHA := len(action.Ref) == githubclient.SHALength && githubclient.IsHexString(action.Ref)
-
-	// Check if it's likely a reusable workflow
-	isWorkflow := strings.Contains(action.Repo, ".yml") || strings.Contains(action.Repo, ".yaml")
-
-	// Delegate to the appropriate handler
-	if isWorkflow {
-		return handleWorkflowReference(
-			ctx,
-			client,
-			action,
-			usesValue,
-			lineNum,
-			updates,
-			updatesMade,
-			isSHA,
-		)
-	}
-	return handleActionReference(
-		ctx,
-		client,
-		action,
-		usesValue,
-		lineNum,
-		updates,
-		updatesMade,
-		isSHA,
-	)
-}
-
-// handleWorkflowReference processes a reusable workflow reference and either updates it to the latest version
-// or pins it to a specific SHA based on the Update flag. This function handles the specific complexities
-// of reusable workflows, which may include subpaths within repositories.
-//
-// - ctx: The context for API calls, allows for cancellation/timeouts.
-// - client: The initialized GitHub client for making API requests.
-// - action: The parsed workflow action containing owner, repo, and reference details.
-// - usesValue: The original workflow reference string from the workflow file for logging.
-// - lineNum: The line number in the workflow file where this workflow reference appears.
-// - updates: A map to store line numbers and their replacement strings.
-// - updatesMade: A pointer to an integer counter that tracks the number of updates.
-// - isSHA: A boolean indicating if the current reference is already a full SHA.
-//
-// Returns: An error if a critical operation fails, otherwise nil.
-func handleWorkflowReference(
-	ctx context.Context,
-	client *github.Client,
-	action parser.WorkflowAction, // Use the specific type from parser
-	usesValue string, // Original value for logging/context
-	lineNum int,
-	updates map[int]string,
-	updatesMade *int,
-	isSHA bool,
-) error {
-	owner := action.Name     // Repository owner (user or organization)
-	repoField := action.Repo // Repository name potentially with subpath (e.g., "repo/path/to/workflow.yml")
-	ref := action.Ref        // Current reference (tag, branch, or SHA)
-
-	// Extract repository name for API calls
-	// For reusable workflows, the repo field might contain a path to the workflow file
-	// We need to split at the first slash to get just the repo name for API calls
-	repoParts := strings.SplitN(repoField, "/", 2) //nolint:mnd
-	repoNameForAPI := repoParts[0]                 // Just the repository name without subpath
-
-	// Validate that we were able to extract a repository name
-	if repoNameForAPI == "" {
-		Logger.Debugf(
-			"❌ Could not extract repository name from '%s' for workflow on line %d. Skipping.",
-			repoField,
-			lineNum,
-		)
-		return nil // Continue processing other references
-	}
-
-	// Construct the full path for the 'uses' string (owner/repo/path)
-	// This is the complete reference as it appears in the workflow file
-	fullPathForUses := fmt.Sprintf("%s/%s", owner, repoField)
-
-	// --- Workflow Update Mode ---
-	// When Update is true, we're finding the latest version and updating all references
-	if Update {
-		Logger.Debugf("🔍 Finding latest version for workflow: %s (repo: %s/%s) (line %d)",
-			fullPathForUses, owner, repoNameForAPI, lineNum)
-
-		// Get the latest reference and its commit SHA for the repository
-		latestRef, commitSHA, err := githubclient.GetLatestActionRef(
-			ctx,
-			client,
-			owner,
-			repoNameForAPI,
-		)
-		if err != nil || commitSHA == "" || latestRef == "" {
-			// Log an error if latest version discovery fails
-			Logger.Errorf(
-				"❌ Error finding latest ref/SHA for workflow repo %s/%s: %v. Skipping update for line %d.",
-				owner,
-				repoNameForAPI,
-				err,
-				lineNum,
-			)
-			return nil // Continue processing other references
-		}
-
-		// Create the new workflow reference string with SHA + comment
-		newUsesValue := fmt.Sprintf("%s@%s  # %s", fullPathForUses, commitSHA, latestRef)
-
-		// Log the update details
-		Logger.Debugf(
-			"  Updating workflow %s to SHA %s (latest ref: %s)",
-			fullPathForUses,
-			commitSHA[:8], // Show only first 8 chars of SHA for readability
-			latestRef,
-		)
-
-		// Check if the workflow is already up-to-date
-		if isSHA && ref == commitSHA {
-			// If current reference is already the latest SHA, no update needed
-			Logger.Debugf(
-				"  Workflow %s already up-to-date with SHA %s (latest ref: %s). No change needed.",
-				fullPathForUses,
-				commitSHA[:8],
-				latestRef,
-			)
-		} else {
-			// Store the update in the map and increment counter
-			updates[lineNum] = newUsesValue
-			*updatesMade++
-		}
-
-		return nil // Successfully processed workflow in update mode
-
-		// --- Workflow Pinning Mode ---
-		// When Update is false, we're pinning existing references to their current SHA
-	} else {
-		// If the reference is already a SHA, no need to pin it
-		if isSHA {
-			Logger.Debugf("ℹ️  Workflow '%s' on line %d already pinned to SHA: %s", usesValue, lineNum, ref)
-			return nil // Already pinned, no update needed
-		}
-
-		// Resolve the branch/ref to use (handles empty refs by finding default branch)
-		branchName, originalRefForComment, err := resolveWorkflowRef(ctx, client, owner, repoNameForAPI, ref, fullPathForUses)
-		if err != nil {
-			// Log an error if we can't resolve the reference
-			Logger.Errorf("❌  Skipping pin for workflow '%s' on line %d: %v", usesValue, lineNum, err)
-			return nil // Continue processing other references
-		}
-
-		// Log the pinning operation
-		Logger.Debugf("🔍  Pinning workflow: %s@%s (line %d) (repo: %s)", fullPathForUses, branchName, lineNum, repoNameForAPI)
-
-		// Resolve the branch/ref to its commit SHA
-		commitSHA, err := githubclient.ResolveRefToSHA(ctx, client, owner, repoNameForAPI, branchName)
-		if err != nil || commitSHA == "" {
-			// Log an error if we can't resolve the SHA
-			Logger.Errorf("❌  Error resolving ref '%s' to SHA for workflow %s/%s: %v. Skipping update for line %d.",
-				branchName, owner, repoNameForAPI, err, lineNum)
-			return nil // Continue processing other references
-		}
-
-		// Create the new workflow reference string with SHA + comment
-		newUsesValue := fmt.Sprintf("%s@%s  # %s", fullPathForUses, commitSHA, originalRefForComment)
-		Logger.Debugf("  Pinned workflow %s@%s to SHA %s", fullPathForUses, originalRefForComment, commitSHA[:8])
-
-		// Store the update in the map and increment counter
-		updates[lineNum] = newUsesValue
-		*updatesMade++
-
-		return nil // Successfully processed workflow in pinning mode
-	}
-}
-
-// handleActionReference processes a GitHub Action reference and either updates it to the latest version
-// or pins it to a specific SHA based on the Update flag. This function handles the core logic
-// of determining what changes to make to action references in workflow files.
-//
-// - ctx: The context for API calls, allows for cancellation/timeouts.
-// - client: The initialized GitHub client for making API requests.
-// - action: The parsed workflow action containing owner, repo, and reference details.
-// - usesValue: The original action reference string from the workflow file for logging.
-// - lineNum: The line number in the workflow file where this action reference appears.
-// - updates: A map to store line numbers and their replacement strings.
-// - updatesMade: A pointer to an integer counter that tracks the number of updates.
-// - isSHA: A boolean indicating if the current reference is already a full SHA.
-//
-// Returns: An error if a critical operation fails, otherwise nil.
-func handleActionReference(
-	ctx context.Context,
-	client *github.Client,
-	action parser.WorkflowAction, // Use the specific type from parser
-	usesValue string, // Original value for logging/context
-	lineNum int,
-	updates map[int]string,
-	updatesMade *int,
-	isSHA bool,
-) error {
-	owner := action.Name    // Repository owner (user or organization)
-	repoName := action.Repo // Repository name without owner prefix potentially with subpath
-	ref := action.Ref       // Current reference (tag, branch, or SHA)
-
-	// Extract repository name for API calls
-	// For actions with subpaths like "owner/repo/subpath", we just need "repo" for the API
-	repoParts := strings.SplitN(repoName, "/", 2) //nolint:mnd
-	repoNameForAPI := repoParts[0]                // Just the repository name without subpath
-
-	// Validate that we were able to extract a repository name
-	if repoNameForAPI == "" {
-		Logger.Debugf(
-			"❌ Could not extract repository name from '%s' for action on line %d. Skipping.",
-			repoName,
-			lineNum,
-		)
-		return nil // Continue processing other references
-	}
-
-	// Construct the full path for the 'uses' string (owner/repo/subpath)
-	// This is the complete reference as it appears in the workflow file
-	fullPathForUses := fmt.Sprintf("%s/%s", owner, repoName)
-
-	// Check if we're in update mode (updating existing SHAs to latest)
-	if Update {
-		// Update mode: Find the latest version of the action
-		Logger.Debugf("🔍  Finding latest version for action: %s (repo: %s/%s) (line %d)",
-			fullPathForUses, owner, repoNameForAPI, lineNum)
-
-		// Get the latest reference and its commit SHA
-		latestRef, commitSHA, err := githubclient.GetLatestActionRef(
-			ctx,
-			client,
-			owner,
-			repoNameForAPI,
-		)
-		if err != nil || commitSHA == "" || latestRef == "" {
-			// Log an error if we can't find the latest version
-			Logger.Errorf(
-				"❌  Error finding latest ref/SHA for action %s/%s: %v. Skipping update for line %d.",
-				owner,
-				repoNameForAPI,
-				err,
-				lineNum,
-			)
-			return nil // Continue processing other actions
-		}
-
-		// Create the new action reference string with SHA + comment
-		newUsesValue := fmt.Sprintf(
-			"%s@%s  # %s", // Format: owner/repo/subpath@sha  # ref
-			fullPathForUses,
-			commitSHA, // Use the full SHA for pinning
-			latestRef, // Include latest reference as a comment
-		)
-
-		// Log the update details
-		Logger.Debugf(
-			"  Updating %s@%s to SHA %s (latest ref: %s)",
-			fullPathForUses,
-			ref,
-			commitSHA[:8], // Show only first 8 chars of SHA for readability
-			latestRef,
-		)
 
-		// Check if the action is already up-to-date
-		if isSHA && ref == commitSHA {
-			// If current reference is already the latest SHA, no update needed
-			Logger.Debugf(
-				"  Action %s already up-to-date with SHA %s (latest ref: %s). No change needed.",
-				fullPathForUses,
-				commitSHA[:8],
-				latestRef,
+		// Final summary of total updates made across all files.
+		if DryRun {
+			Logger.Printf(
+				"Finished processing (dry run). Total actions that would be updated across all files: %d",
+				run.Updated,
 			)
 		} else {
-			// Store the update in the map and increment counter
-			updates[lineNum] = newUsesValue
-			*updatesMade++
-		}
-
-		return nil // Successfully processed action in update mode
-	} else {
-		// Pin mode: Pin existing references to their current SHA
-
-		// If the reference is already a SHA, no need to pin it
-		if isSHA {
-			Logger.Debugf("ℹ️  Action '%s' on line %d already pinned to SHA: %s", usesValue, lineNum, ref)
-			return nil // Already pinned, no update needed
-		}
-
-		// Resolve the current reference to its commit SHA
-		Logger.Debugf("🔍  Resolving SHA for action: %s (repo: %s/%s) @%s (line %d)",
-			fullPathForUses, owner, repoNameForAPI, ref, lineNum)
-		commitSHA, err := githubclient.ResolveRefToSHA(ctx, client, owner, repoNameForAPI, ref)
-		if err != nil || commitSHA == "" {
-			// Log an error if we can't resolve the SHA
-			Logger.Errorf("❌  Error resolving ref '%s' to SHA for action %s/%s: %v. Skipping update for line %d.",
-				ref, owner, repoNameForAPI, err, lineNum)
-			return nil // Continue processing other actions
-		}
-
-		// Create the new action reference string with SHA + comment
-		newUsesValue := fmt.Sprintf("%s@%s  # %s", fullPathForUses, commitSHA, ref)
-		Logger.Debugf("  Pinned action %s@%s to SHA %s", fullPathForUses, ref, commitSHA[:8])
-
-		// Store the update in the map and increment counter
-		updates[lineNum] = newUsesValue
-		*updatesMade++
-
-		return nil // Successfully processed action in pin mode
-	}
-}
-
-// resolveWorkflowRef determines the appropriate Git reference to use for a reusable workflow.
-// If no reference is provided, it fetches the repository's default branch.
-//
-// - ctx: The context for API calls, allows for cancellation/timeouts.
-// - client: The initialized GitHub client for making API requests.
-// - owner: The owner (user or organization) of the GitHub repository.
-// - repoNameForAPI: The repository name to use in API calls (without subpaths).
-// - currentRef: The current reference specified in the workflow, may be empty.
-// - fullPathForUses: The complete "uses" path for logging purposes.
-//
-// Returns:
-//   - string: The resolved branch name (either the provided ref or default branch)
-//   - string: The reference to use in comments (for tracking original reference)
-//   - error: An error if default branch resolution fails when needed
-func resolveWorkflowRef(
-	ctx context.Context,
-	client *github.Client,
-	owner, repoNameForAPI, currentRef, fullPathForUses string,
-) (string, string, error) {
-	branchName := currentRef
-	originalRefForComment := currentRef
-
-	// Check if a reference was provided in the workflow file
-	if branchName == "" {
-		// No reference specified, so we need to get the default branch
-		Logger.Debugf(
-			"ℹ️ No ref specified for workflow %s. Resolving default branch for %s/%s.",
-			fullPathForUses,
-			owner,
-			repoNameForAPI,
-		)
-
-		// Make an API call to get repository information
-		// This will include the default branch name
-		repoInfo, _, err := client.Repositories.Get(ctx, owner, repoNameForAPI)
-		if err != nil {
-			return "", "", fmt.Errorf(
-				"error getting repository info for %s/%s to find default branch: %w",
-				owner,
-				repoNameForAPI,
-				err,
-			)
-		}
-
-		// Verify that the default branch information is available
-		// DefaultBranch is a pointer and could be nil, or could point to an empty string
-		if repoInfo.DefaultBranch == nil || *repoInfo.DefaultBranch == "" {
-			return "", "", fmt.Errorf(
-				"could not determine default branch for %s/%s",
-				owner,
-				repoNameForAPI,
+			Logger.Printf(
+				"Finished processing. Total actions updated across all files: %d",
+				run.Updated,
 			)
 		}
 
-		// Use the default branch as the reference
-		branchName = *repoInfo.DefaultBranch
-
-		// Store the default branch name as the original reference for commenting purposes
-		// This helps track that we automatically resolved to the default branch
-		originalRefForComment = branchName
-
-		Logger.Debugf("  Using default branch '%s' for %s/%s",
-			branchName,
-			owner,
-			repoNameForAPI,
-		)
-	}
-
-	// Return both the branch to use and the original reference (for comments)
-	return branchName, originalRefForComment, nil
-}
-
-// ApplyUpdatesToLines takes the original content of a file and a map of line numbers
-// to new string values, and reconstructs the content with the specified lines replaced.
-// It preserves original line endings and indentation where possible for 'uses:' lines.
-//
-// - originalContent: The string content of the file before modification.
-// - updates: A map where keys are 1-based line numbers and values are the replacement strings.
-//
-// Returns: The modified content as a string, and an error if processing fails
-func applyUpdatesToLines(originalContent string, updates map[int]string) (string, error) {
-	// Split the original content into individual lines. strings.Split handles various line endings.
-	lines := strings.Split(originalContent, "\n")
-	var output strings.Builder
-	// Pre-allocate capacity for the output string builder to improve performance,
-	// estimating the potential increase in size due to added comments.
-	output.Grow(
-		len(originalContent) + len(updates)*20,
-	) // Rough estimate: 20 characters per update comment.
-
-	// Iterate through the lines, using a 0-based index `i`.
-	for i, line := range lines {
-		// Calculate the 1-based line number for lookup in the 'updates' map.
-		lineNumber := i + 1
-		// Check if there is an update specified for the current line number.
-		if newUsesValue, ok := updates[lineNumber]; ok {
-			// An update exists for this line.
-			// Trim whitespace from the beginning of the line to check if it starts with 'uses:'.
-			trimmedLine := strings.TrimSpace(line)
-			// Verify that the line actually contains 'uses:' somewhere.
-			// Handle cases like "- uses:", nested "-  - uses:", or "uses:" with arbitrary indentation.
-			if strings.Contains(trimmedLine, "uses:") {
-				// Find the index of "uses:" in the original line to preserve exact leading whitespace and any dashes.
-				before, _, found := strings.Cut(line, "uses:") // go:build ignore
-				if !found {                                    // go:build ignore
-					// Fallback: if not found in original line (shouldn't happen since trimmedLine contains it), append original.
-					Logger.Debugf(
-						"Warning: couldn't locate 'uses:' position on line %d. Appending original.",
-						lineNumber,
-					)
-					output.WriteString(line)
-				} else {
-					// Replace from the "uses:" token onward with the new value while preserving prefix.
-					newLine := before + "uses: " + newUsesValue
-					output.WriteString(newLine)
-				}
-			} else {
-				// If an update was mapped to this line number, but the line content doesn't look like
-				// a 'uses:' entry, log a warning. This indicates a potential issue with the line
-				// number reported by the YAML parser for the 'uses' node. In this case, we append
-				// the original line to avoid corrupting the file.
-				Logger.Debugf("Warning: Update found for line %d, but line content '%s' does not look like a 'uses:' line. Appending original.", lineNumber, line)
-				output.WriteString(line)
-			}
-		} else {
-			// No update for this line, append the original line content.
-			output.WriteString(line)
-		}
-
-		// Add the newline character back. This is added after each line except potentially the very last one.
-		// strings.Split(content, "\n") will produce a final empty string if the original content ended with a newline.
-		// We want to preserve the original ending: if the original content ended with a newline, the split results
-		// in `len(lines)` items, and the last item is empty. If it didn't end with a newline, `len(lines)` is
-		// the number of visual lines, and the last item contains content.
-		// The condition `i < len(lines)-1` correctly adds a newline after every line *except* the last one produced by the split.
-		if i < len(lines)-1 {
-			output.WriteString("\n")
+		// A reference that couldn't be resolved offline was left as it was.
+		if run.OfflineMisses > 0 {
+			Logger.Fatalf("❌  %v", pinner.OfflineMissError(run.OfflineMisses))
 		}
-	}
-
-	// Return the accumulated content from the string builder.
-	return output.String(), nil
-}
-
-// UpdateWorkflowActionSHAs reads a workflow file, parses its YAML structure,
-// identifies GitHub Actions needing SHA pinning, resolves the SHAs, and
-// modifies the file content in memory before writing it back.
-//
-// - ctx: The context for API calls, allows for cancellation/timeouts.
-// - client: The initialized GitHub client for making API requests.
-// - filePath: The path to the workflow file to process.
-//
-// Returns:
-//   - int: The number of actions updated in the file
-//   - error: An error if reading, parsing, resolving, or writing fails
-func UpdateWorkflowActionSHAs(
-	ctx context.Context,
-	client *github.Client,
-	filePath string,
-) (int, error) {
-	// Validate the workflow file path to prevent security issues
-	// This ensures the path doesn't contain dangerous patterns like path traversal
-	if err := utils.ValidateWorkflowFilePath(filePath); err != nil {
-		return 0, err // Return the validation error without modification
-	}
-
-	// Read the file content into memory
-	// The nolint:gosec comment suppresses a security scanner warning about using
-	// a variable filepath - we've already validated it above
-	data, err := os.ReadFile(filePath) //nolint:gosec
-	if err != nil {
-		return 0, fmt.Errorf("error reading file %s: %w", filePath, err)
-	}
-
-	// Skip processing if the file is empty
-	if len(data) == 0 {
-		Logger.Debugf("Skipping empty file: %s", filePath)
-		return 0, nil // Return 0 updates and no error
-	}
-
-	// Parse the workflow YAML into a structured AST (Abstract Syntax Tree)
-	// This preserves line numbers and structure for precise updates
-	root, err := parser.ParseWorkflowYAML(filePath, data)
-	if err != nil {
-		return 0, err // Return any parsing errors
-	}
-
-	// If the parser returned nil (e.g., for an empty document), skip processing
-	if root == nil {
-		return 0, nil // Return 0 updates and no error
-	}
 
-	// Initialize a map to store the identified updates
-	// Keys are line numbers, values are the new 'uses:' strings
-	updates := make(map[int]string)
-	updatesMade := 0 // Counter for updates identified
-
-	// Recursively traverse the YAML AST to find 'uses:' keys and populate the updates map
-	// We start from the first content node of the root (usually a DocumentNode or MappingNode)
-	if len(root.Content) > 0 {
-		err = findUpdatesInNodes(ctx, client, root.Content[0], updates, &updatesMade)
-		if err != nil {
-			// Return the number of updates found before the error and the error itself
-			return updatesMade, err
+		// Nor was a reference whose lookups were refused by a rate limit for longer than --max-wait.
+		if run.RateLimited > 0 {
+			Logger.Fatalf("❌  Skipped %d reference(s) because of GitHub's rate limit: run again later, or raise --max-wait",
+				run.RateLimited)
 		}
-	}
 
-	// Apply updates if any were identified
-	if updatesMade > 0 {
-		Logger.Debugf("Applying %d update(s) to %s", updatesMade, filePath)
-
-		// Modify the original file content line by line with the updates
-		updatedContent, err := applyUpdatesToLines(string(data), updates)
-		if err != nil {
-			return updatesMade, fmt.Errorf(
-				"error applying updates to lines for %s: %w",
-				filePath,
-				err,
-			)
+		// A commit that doesn't belong to its repository is a failure, whatever else was done.
+		if run.Impostors > 0 {
+			Logger.Fatalf("🚨  Found %d reference(s) pinned to a commit that doesn't belong to its repository", run.Impostors)
 		}
 
-		// Write the modified content back to the original file
-		// The nolint comments suppress security scanner warnings:
-		// - gosec: for using a variable filepath (already validated)
-		// - mnd: for using a "magic number" for file permissions
-		err = os.WriteFile( //nolint:gosec //nolint:mnd
-			filePath,
-			[]byte(updatedContent),
-			0o640, //nolint:mnd
-		)
-		if err != nil {
-			return updatesMade, fmt.Errorf("error writing updated file %s: %w", filePath, err)
+		// Nor may a commit that couldn't be verified pass for one that was.
+		if run.Unverified > 0 {
+			Logger.Fatalf("❌  Could not verify %d pinned commit(s)", run.Unverified)
 		}
-	}
-
-	// Return the total number of updates made and nil error if successful
-	return updatesMade, nil
+	},
 }
@@ -3,28 +3,40 @@ package cmd_test
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/rogpeppe/go-internal/testscript"
+
+	"github.com/esacteksab/gh-actlock/cmd"
+	"github.com/esacteksab/gh-actlock/githubclient/githubtest"
 )
 
-func TestScripts(t *testing.T) {
-	token := os.Getenv("GITHUB_TOKEN")
-	if token == "" {
-		t.Skip("Skipping testscript tests: GITHUB_TOKEN environment variable not set")
-	}
+// TestMain lets scripts run actlock as a command, from the test binary itself.
+func TestMain(m *testing.M) {
+	testscript.Main(m, map[string]func(){"actlock": cmd.Execute})
+}
 
+func TestScripts(t *testing.T) {
 	testscript.Run(t, testscript.Params{
 		Dir:           "../testdata/script",
 		UpdateScripts: false,
 		Setup: func(env *testscript.Env) error {
-			// 1. Pass GITHUB_TOKEN to the script environment
-			env.Vars = append(env.Vars, "GITHUB_TOKEN="+token)
-			env.Vars = append(env.Vars, "ACTLOCK_DEBUG="+"true")
+			// Each script talks to its own fake GitHub API, never to github.com.
+			srv := githubtest.NewServer()
+			env.Defer(srv.Close)
+			if err := srv.SeedFile("../testdata/github.txtar"); err != nil {
+				return err
+			}
+			env.Setenv("ACTLOCK_API_URL", srv.APIURL())
+			env.Setenv("ACTLOCK_API_TOKEN", "githubtest-token")
+			// Keep the HTTP cache, and gh's credentials, in the script's own directory.
+			env.Setenv("HOME", filepath.Join(env.WorkDir, ".home"))
+			env.Setenv("ACTLOCK_DEBUG", "true")
+			// Collect the coverage of the actlock the scripts run, too.
 			if gocoverdir := os.Getenv("GOCOVERDIR"); gocoverdir != "" {
-				env.Vars = append(env.Vars, "GOCOVERDIR="+gocoverdir)
+				env.Setenv("GOCOVERDIR", gocoverdir)
 			}
-
 			return nil // Successful setup
 		},
 	})
@@ -0,0 +1,74 @@
+// SPDX-License-Identifier: MIT
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/esacteksab/gh-actlock/lockfile"
+)
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+}
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify [file|dir]...",
+	Short: "Verify that workflows match the lockfile",
+	Long: `Compares every action and reusable workflow reference in the workflow and
+action files with <root>/.github/actlock.lock, which every 'actlock' run writes
+unless it is given --lock=false or the configuration file sets 'lock: false'.
+
+Exits with a non-zero status if a reference is not pinned to a commit SHA, is
+pinned to a different SHA than the lockfile records for its ref, or is missing
+from the lockfile. No GitHub token or network access is needed.
+
+Like the root command, files and directories can be passed to verify exactly those.`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := cmd.OutOrStdout()
+
+		// Skip patterns and file filters from the configuration apply to verify too.
+		if err := loadConfig(cmd); err != nil {
+			return err
+		}
+
+		lock, err := lockfile.Load(lockfile.Path(Root))
+		if err != nil {
+			return fmt.Errorf("%w (run 'gh actlock' to create it)", err)
+		}
+
+		files, err := collectTargetFiles(Root, args)
+		if err != nil {
+			return err
+		}
+
+		p, err := pinnerFromFlags(nil)
+		if err != nil {
+			return err
+		}
+
+		mismatches := 0
+		for _, filePath := range files {
+			Logger.Debugf("Verifying workflow: %s", filePath)
+
+			// Nothing is resolved: the references are compared as they are written.
+			findings, err := p.VerifyLockfile(filePath, lock)
+			if err != nil {
+				return fmt.Errorf("failed to verify %s: %w", filePath, err)
+			}
+			for _, finding := range findings {
+				fmt.Fprintf(out, "%s:%d: %s\n", filePath, finding.Line, finding.Message)
+			}
+			mismatches += len(findings)
+		}
+
+		if mismatches > 0 {
+			return fmt.Errorf("found %d reference(s) that don't match the lockfile", mismatches)
+		}
+		Logger.Printf("✅  All actions and workflows match the lockfile")
+		return nil
+	},
+}
@@ -0,0 +1,80 @@
+// SPDX-License-Identifier: MIT
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/esacteksab/gh-actlock/pinner"
+)
+
+// verifyCommentsFix is the --fix mode, empty when mismatches are only reported.
+var verifyCommentsFix string
+
+func init() {
+	verifyCommentsCmd.Flags().StringVar(&verifyCommentsFix, "fix", "",
+		"fix mismatches by rewriting the comment (`mode` comment) or re-pinning to the comment's ref (pin)")
+	// A bare --fix keeps the pinned SHA, which is what actually runs, and corrects the comment.
+	verifyCommentsCmd.Flags().Lookup("fix").NoOptDefVal = string(pinner.FixComment)
+	rootCmd.AddCommand(verifyCommentsCmd)
+}
+
+var verifyCommentsCmd = &cobra.Command{
+	Use:   "verify-comments [file|dir]...",
+	Short: "Verify that inline comment refs point at the pinned SHAs",
+	Long: `Resolves the ref in the inline comment of every action and reusable workflow
+pinned to a commit SHA, e.g. v4.1.1 in 'actions/checkout@<sha>  # v4.1.1', and
+reports the references whose comment points at a different commit. This happens
+when a file is edited by hand, a tag is moved, or a comment is tampered with.
+
+With --fix (or --fix=comment), the comment is rewritten to a tag that points at
+the pinned SHA. With --fix=pin, the reference is re-pinned to the commit the
+comment's ref points at. Exits with a non-zero status if a mismatch remains.
+
+Like the root command, files and directories can be passed to verify exactly those.`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		out := cmd.OutOrStdout()
+
+		fix := pinner.CommentFix(verifyCommentsFix)
+		switch fix {
+		case pinner.FixNone, pinner.FixComment, pinner.FixPin:
+		default:
+			return fmt.Errorf("invalid --fix mode %q: expected comment or pin", verifyCommentsFix)
+		}
+		if err := loadConfig(cmd); err != nil {
+			return err
+		}
+
+		files, err := collectTargetFiles(Root, args)
+		if err != nil {
+			return err
+		}
+
+		client, err := newClient(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to initialize GitHub client: %w", err)
+		}
+		p, err := pinnerFromFlags(client)
+		if err != nil {
+			return err
+		}
+
+		mismatches, fixed, err := p.VerifyComments(ctx, out, files, fix)
+		if err != nil {
+			return err
+		}
+		if fixed > 0 {
+			Logger.Printf("✅  Fixed %d comment mismatch(es)", fixed)
+		}
+		if mismatches > 0 {
+			return fmt.Errorf("found %d comment(s) that don't match the pinned SHA", mismatches)
+		}
+		Logger.Printf("✅  All inline comments match the pinned SHAs")
+		return nil
+	},
+}
@@ -0,0 +1,206 @@
+// SPDX-License-Identifier: MIT
+
+// Package config loads the per-repository actlock configuration file,
+// .github/actlock.yml, and answers the policy questions the commands ask of it.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/esacteksab/gh-actlock/utils"
+)
+
+// FileNames are the names the configuration file is looked up by, in the .github directory.
+var FileNames = []string{"actlock.yml", "actlock.yaml"}
+
+// defaultCommentSpaces matches yamllint's default of two spaces before a comment.
+const defaultCommentSpaces = 2
+
+// Config is the contents of an actlock configuration file. The zero value, and a nil
+// *Config, mean "no configuration": every file and action is processed with the defaults.
+type Config struct {
+	Files     Files    `yaml:"files"`      // Which discovered files to process
+	Skip      []string `yaml:"skip"`       // owner/repo patterns that must never be touched
+	Update    Update   `yaml:"update"`     // Update mode policies
+	Comment   Comment  `yaml:"comment"`    // Inline comment style
+	PinDocker bool     `yaml:"pin-docker"` // Whether to pin docker images, like --pin-docker
+	Lock      *bool    `yaml:"lock"`       // Whether to write the lockfile, like --lock; nil for the default, true
+}
+
+// Files selects which discovered files are processed, using .gitignore-style patterns
+// relative to the project root.
+type Files struct {
+	Include []string `yaml:"include"` // If set, only matching files are processed
+	Exclude []string `yaml:"exclude"` // Matching files are never processed
+}
+
+// Update configures update mode.
+type Update struct {
+	Policy  string            `yaml:"policy"`  // Policy -u/--update uses without --policy
+	Actions map[string]string `yaml:"actions"` // owner/repo pattern -> policy
+}
+
+// Comment configures the inline comment written after a pinned reference.
+type Comment struct {
+	Spaces int    `yaml:"spaces"` // Spaces between the reference and the '#'
+	Tag    string `yaml:"tag"`    // Which tag to name, like --comment-tag: "ref", "specific", or "both"
+}
+
+// Find looks for a configuration file in the project root's .github directory.
+//
+// - root: The project root directory.
+// Returns: The path of the configuration file, or an empty string if there is none.
+func Find(root string) string {
+	for _, name := range FileNames {
+		candidate := filepath.Join(root, ".github", name)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// Load reads and validates a configuration file.
+//
+// - filePath: The path of the configuration file.
+// Returns: The parsed Config, and an error if the file can't be read or is invalid.
+func Load(filePath string) (*Config, error) {
+	data, err := os.ReadFile(filePath) //nolint:gosec
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("config file %s not found: %w", filePath, err)
+		}
+		return nil, fmt.Errorf("error reading config file %s: %w", filePath, err)
+	}
+	return Parse(filePath, data)
+}
+
+// Parse validates configuration data against the schema and decodes it. Every schema
+// violation is reported as a *ValidationError pointing at the offending key.
+//
+// - name: The file name used in error messages.
+// - data: The YAML content.
+// Returns: The parsed Config, and an error joining every validation error found.
+func Parse(name string, data []byte) (*Config, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing config file %s: %w", name, err)
+	}
+
+	cfg := &Config{}
+	// An empty file is a valid, empty configuration.
+	if len(doc.Content) == 0 {
+		return cfg, nil
+	}
+
+	v := &validator{file: name, root: rootSchema}
+	v.validate(doc.Content[0], rootSchema, "")
+	if len(v.errors) > 0 {
+		return nil, errors.Join(v.errors...)
+	}
+
+	if err := doc.Content[0].Decode(cfg); err != nil {
+		return nil, fmt.Errorf("error decoding config file %s: %w", name, err)
+	}
+	return cfg, nil
+}
+
+// SelectFiles filters discovered files through the include and exclude patterns.
+//
+// - root: The project root the patterns are relative to.
+// - files: The discovered file paths.
+// Returns: The files that should be processed, in their original order.
+func (c *Config) SelectFiles(root string, files []string) []string {
+	if c == nil || (len(c.Files.Include) == 0 && len(c.Files.Exclude) == 0) {
+		return files
+	}
+
+	include, exclude := &utils.GitIgnore{}, &utils.GitIgnore{}
+	for _, pattern := range c.Files.Include {
+		include.AddPattern(pattern, "")
+	}
+	for _, pattern := range c.Files.Exclude {
+		exclude.AddPattern(pattern, "")
+	}
+
+	var selected []string
+	for _, file := range files {
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		if len(c.Files.Include) > 0 && !include.Ignored(rel, false) {
+			continue
+		}
+		if exclude.Ignored(rel, false) {
+			continue
+		}
+		selected = append(selected, file)
+	}
+	return selected
+}
+
+// Skipped reports whether an action or reusable workflow must be left untouched.
+//
+// - owner: The repository owner.
+// - repo: The repository name, without any subpath.
+// Returns: true if owner/repo matches one of the skip patterns.
+func (c *Config) Skipped(owner, repo string) bool {
+	if c == nil {
+		return false
+	}
+	return slices.ContainsFunc(c.Skip, func(pattern string) bool {
+		return matchRepo(pattern, owner, repo)
+	})
+}
+
+// PolicyFor returns the update policy configured for an action. An exact owner/repo
+// key wins over patterns; between patterns, the first in sorted order wins.
+//
+// - owner: The repository owner.
+// - repo: The repository name, without any subpath.
+// Returns: The policy name, and whether one is configured for this action.
+func (c *Config) PolicyFor(owner, repo string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	if policy, ok := c.Update.Actions[owner+"/"+repo]; ok {
+		return policy, true
+	}
+	patterns := make([]string, 0, len(c.Update.Actions))
+	for pattern := range c.Update.Actions {
+		patterns = append(patterns, pattern)
+	}
+	slices.Sort(patterns)
+	for _, pattern := range patterns {
+		if matchRepo(pattern, owner, repo) {
+			return c.Update.Actions[pattern], true
+		}
+	}
+	return "", false
+}
+
+// CommentSpaces returns the number of spaces to write before an inline comment.
+//
+// Returns: The configured number, or 2 if none is set.
+func (c *Config) CommentSpaces() int {
+	if c == nil || c.Comment.Spaces == 0 {
+		return defaultCommentSpaces
+	}
+	return c.Comment.Spaces
+}
+
+// matchRepo reports whether owner/repo matches a glob pattern such as "my-org/*".
+func matchRepo(pattern, owner, repo string) bool {
+	matched, err := path.Match(pattern, owner+"/"+repo)
+	return err == nil && matched
+}
@@ -0,0 +1,156 @@
+// SPDX-License-Identifier: MIT
+
+package config_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/esacteksab/gh-actlock/config"
+)
+
+const validConfig = `
+files:
+  include: [".github/**"]
+  exclude: [".github/workflows/generated-*.yml"]
+skip:
+  - my-org/*
+  - actions/checkout
+update:
+  policy: minor
+  actions:
+    actions/setup-go: major
+    actions/*: patch
+comment:
+  spaces: 1
+  tag: both
+pin-docker: true
+lock: true
+`
+
+func TestParse(t *testing.T) {
+	cfg, err := config.Parse("actlock.yml", []byte(validConfig))
+	require.NoError(t, err)
+
+	assert.Equal(t, "minor", cfg.Update.Policy)
+	assert.True(t, cfg.PinDocker)
+	require.NotNil(t, cfg.Lock)
+	assert.True(t, *cfg.Lock)
+	assert.Equal(t, 1, cfg.CommentSpaces())
+	assert.Equal(t, "both", cfg.Comment.Tag)
+
+	assert.True(t, cfg.Skipped("my-org", "anything"))
+	assert.True(t, cfg.Skipped("actions", "checkout"))
+	assert.False(t, cfg.Skipped("actions", "cache"))
+
+	// An exact key wins over a pattern.
+	policy, ok := cfg.PolicyFor("actions", "setup-go")
+	assert.True(t, ok)
+	assert.Equal(t, "major", policy)
+	policy, ok = cfg.PolicyFor("actions", "cache")
+	assert.True(t, ok)
+	assert.Equal(t, "patch", policy)
+	_, ok = cfg.PolicyFor("docker", "login-action")
+	assert.False(t, ok)
+}
+
+func TestParse_Empty(t *testing.T) {
+	cfg, err := config.Parse("actlock.yml", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, cfg.CommentSpaces())
+
+	// A nil config behaves like an empty one.
+	var none *config.Config
+	assert.False(t, none.Skipped("actions", "checkout"))
+	assert.Equal(t, []string{"a.yml"}, none.SelectFiles(".", []string{"a.yml"}))
+}
+
+func TestParse_ValidationErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want []string
+	}{
+		{
+			name: "unknown_top_level_key",
+			yaml: "files: {}\npin_docker: true\n",
+			want: []string{`actlock.yml:2:1: pin_docker: unknown key "pin_docker"`},
+		},
+		{
+			name: "unknown_policy",
+			yaml: "update:\n  policy: newest\n",
+			want: []string{
+				`actlock.yml:2:11: update.policy: "newest" is not one of tracked, patch, minor, major, latest`,
+			},
+		},
+		{
+			name: "bad_action_key_and_policy",
+			yaml: "update:\n  actions:\n    checkout: major\n    actions/cache: 4\n",
+			want: []string{
+				`actlock.yml:3:5: update.actions.checkout: "checkout" must have the form owner/repo`,
+				`actlock.yml:4:20: update.actions.actions/cache: expected a string, got the number 4`,
+			},
+		},
+		{
+			name: "wrong_types",
+			yaml: "skip: actions/checkout\ncomment:\n  spaces: 12\npin-docker: yes\nfiles:\n  include: [\"\"]\n",
+			want: []string{
+				`actlock.yml:1:7: skip: expected a list, got "actions/checkout"`,
+				`actlock.yml:3:11: comment.spaces: must be at most 8`,
+				`actlock.yml:4:13: pin-docker: expected true or false, got "yes"`,
+				`actlock.yml:6:13: files.include[0]: must not be empty`,
+			},
+		},
+		{
+			name: "not_a_mapping",
+			yaml: "- actions/checkout\n",
+			want: []string{`actlock.yml:1:1: (root): expected a mapping, got a list`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := config.Parse("actlock.yml", []byte(tt.yaml))
+			require.Error(t, err)
+
+			var got []string
+			for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+				var verr *config.ValidationError
+				require.True(t, errors.As(e, &verr))
+				got = append(got, verr.Error())
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSelectFiles(t *testing.T) {
+	cfg, err := config.Parse("actlock.yml", []byte(validConfig))
+	require.NoError(t, err)
+
+	root := "repo"
+	files := []string{
+		filepath.Join(root, ".github", "workflows", "ci.yml"),
+		filepath.Join(root, ".github", "workflows", "generated-release.yml"),
+		filepath.Join(root, "tools", "action.yml"),
+	}
+	assert.Equal(t, files[:1], cfg.SelectFiles(root, files))
+}
+
+func TestFindAndLoad(t *testing.T) {
+	root := t.TempDir()
+	assert.Empty(t, config.Find(root))
+
+	require.NoError(t, os.MkdirAll(filepath.Join(root, ".github"), 0o750))
+	path := filepath.Join(root, ".github", "actlock.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("pin-docker: true\n"), 0o600))
+
+	assert.Equal(t, path, config.Find(root))
+	cfg, err := config.Load(path)
+	require.NoError(t, err)
+	assert.True(t, cfg.PinDocker)
+}
@@ -0,0 +1,247 @@
+// SPDX-License-Identifier: MIT
+
+package config
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Schema is the JSON schema of the configuration file, for use by editors and other tools.
+// The same schema drives the validation done by Parse, so the two can't drift apart.
+//
+//go:embed schema.json
+var Schema []byte
+
+// ValidationError describes a configuration value that doesn't match the schema.
+type ValidationError struct {
+	File    string // The configuration file name
+	Line    int    // 1-based line of the offending key or value
+	Column  int    // 1-based column of the offending key or value
+	Path    string // Dotted path to the offending key (e.g., "update.actions.actions/checkout")
+	Message string // What is wrong
+}
+
+// Error formats the error as "file:line:column: path: message".
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s", e.File, e.Line, e.Column, e.Path, e.Message)
+}
+
+// schema is the subset of JSON Schema used by schema.json.
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Properties           map[string]*schema `json:"properties"`
+	AdditionalProperties *additional        `json:"additionalProperties"`
+	PropertyNames        *schema            `json:"propertyNames"`
+	Items                *schema            `json:"items"`
+	Enum                 []string           `json:"enum"`
+	Minimum              *int               `json:"minimum"`
+	Maximum              *int               `json:"maximum"`
+	MinLength            int                `json:"minLength"`
+	Pattern              string             `json:"pattern"`
+	Defs                 map[string]*schema `json:"$defs"`
+
+	pattern *regexp.Regexp // Pattern, compiled
+}
+
+// additional is the value of additionalProperties: either false or a schema.
+type additional struct {
+	forbidden bool
+	schema    *schema
+}
+
+// UnmarshalJSON accepts a boolean or a schema.
+func (a *additional) UnmarshalJSON(data []byte) error {
+	var allowed bool
+	if err := json.Unmarshal(data, &allowed); err == nil {
+		a.forbidden = !allowed
+		return nil
+	}
+	return json.Unmarshal(data, &a.schema)
+}
+
+// rootSchema is schema.json, parsed once.
+var rootSchema = mustParseSchema(Schema)
+
+// mustParseSchema parses the embedded schema and compiles its patterns. The schema is
+// part of the binary, so any error is a programming mistake.
+func mustParseSchema(data []byte) *schema {
+	var s schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		panic(fmt.Sprintf("invalid embedded config schema: %v", err))
+	}
+	compilePatterns(&s)
+	return &s
+}
+
+// compilePatterns compiles the pattern of every schema reachable from s.
+func compilePatterns(s *schema) {
+	if s == nil {
+		return
+	}
+	if s.Pattern != "" {
+		s.pattern = regexp.MustCompile(s.Pattern)
+	}
+	for _, child := range s.Properties {
+		compilePatterns(child)
+	}
+	for _, child := range s.Defs {
+		compilePatterns(child)
+	}
+	compilePatterns(s.PropertyNames)
+	compilePatterns(s.Items)
+	if s.AdditionalProperties != nil {
+		compilePatterns(s.AdditionalProperties.schema)
+	}
+}
+
+// validator checks a YAML document against the schema, collecting every error.
+type validator struct {
+	file   string
+	root   *schema
+	errors []error
+}
+
+// resolve follows a "#/$defs/name" reference.
+func (v *validator) resolve(s *schema) *schema {
+	if name, ok := strings.CutPrefix(s.Ref, "#/$defs/"); ok {
+		if def := v.root.Defs[name]; def != nil {
+			return def
+		}
+	}
+	return s
+}
+
+// fail records an error located at node.
+func (v *validator) fail(node *yaml.Node, path, format string, args ...any) {
+	if path == "" {
+		path = "(root)"
+	}
+	v.errors = append(v.errors, &ValidationError{
+		File:    v.file,
+		Line:    node.Line,
+		Column:  node.Column,
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// validate checks node, found at path, against s.
+func (v *validator) validate(node *yaml.Node, s *schema, path string) {
+	s = v.resolve(s)
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	switch s.Type {
+	case "object":
+		if node.Kind != yaml.MappingNode {
+			v.fail(node, path, "expected a mapping, got %s", describe(node))
+			return
+		}
+		v.validateMapping(node, s, path)
+		return
+	case "array":
+		if node.Kind != yaml.SequenceNode {
+			v.fail(node, path, "expected a list, got %s", describe(node))
+			return
+		}
+		for i, item := range node.Content {
+			if s.Items != nil {
+				v.validate(item, s.Items, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+		return
+	case "string", "integer", "boolean":
+		if node.Kind != yaml.ScalarNode || node.ShortTag() != scalarTags[s.Type] {
+			v.fail(node, path, "expected %s, got %s", articles[s.Type], describe(node))
+			return
+		}
+	}
+
+	if len(s.Enum) > 0 && !slices.Contains(s.Enum, node.Value) {
+		v.fail(node, path, "%q is not one of %s", node.Value, strings.Join(s.Enum, ", "))
+	}
+	if s.MinLength > 0 && len(node.Value) < s.MinLength {
+		v.fail(node, path, "must not be empty")
+	}
+	// The only pattern in the schema is the owner/repo one.
+	if s.pattern != nil && !s.pattern.MatchString(node.Value) {
+		v.fail(node, path, "%q must have the form owner/repo", node.Value)
+	}
+	n, _ := strconv.Atoi(node.Value)
+	if s.Minimum != nil && n < *s.Minimum {
+		v.fail(node, path, "must be at least %d", *s.Minimum)
+	}
+	if s.Maximum != nil && n > *s.Maximum {
+		v.fail(node, path, "must be at most %d", *s.Maximum)
+	}
+}
+
+// validateMapping checks the keys and values of a mapping node against an object schema.
+func (v *validator) validateMapping(node *yaml.Node, s *schema, path string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		key := keyNode.Value
+		childPath := key
+		if path != "" {
+			childPath = path + "." + key
+		}
+
+		if s.PropertyNames != nil {
+			v.validate(keyNode, s.PropertyNames, childPath)
+		}
+		if child, ok := s.Properties[key]; ok {
+			v.validate(valueNode, child, childPath)
+			continue
+		}
+		switch {
+		case s.AdditionalProperties == nil:
+		case s.AdditionalProperties.forbidden:
+			v.fail(keyNode, childPath, "unknown key %q", key)
+		case s.AdditionalProperties.schema != nil:
+			v.validate(valueNode, s.AdditionalProperties.schema, childPath)
+		}
+	}
+}
+
+// scalarTags maps schema types to the YAML tags of matching scalars.
+var scalarTags = map[string]string{
+	"string":  "!!str",
+	"integer": "!!int",
+	"boolean": "!!bool",
+}
+
+// articles maps schema types to how they're named in error messages.
+var articles = map[string]string{
+	"string":  "a string",
+	"integer": "an integer",
+	"boolean": "true or false",
+}
+
+// describe names the kind of a YAML node for error messages.
+func describe(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	}
+	switch node.ShortTag() {
+	case "!!int", "!!float":
+		return "the number " + node.Value
+	case "!!bool":
+		return "the boolean " + node.Value
+	case "!!null":
+		return "nothing"
+	}
+	return fmt.Sprintf("%q", node.Value)
+}
@@ -0,0 +1,197 @@
+// SPDX-License-Identifier: MIT
+package githubclient
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v82/github"
+	"golang.org/x/oauth2"
+)
+
+// The environment variables a GitHub App is configured with.
+const (
+	appIDEnv             = "ACTLOCK_APP_ID"
+	appInstallationIDEnv = "ACTLOCK_APP_INSTALLATION_ID"
+	appPrivateKeyEnv     = "ACTLOCK_APP_PRIVATE_KEY"
+	appPrivateKeyFileEnv = "ACTLOCK_APP_PRIVATE_KEY_FILE"
+)
+
+const (
+	// appJWTLifetime is how long the JWT that requests an installation token is valid.
+	// GitHub refuses JWTs that expire more than 10 minutes after they were issued.
+	appJWTLifetime = 9 * time.Minute
+	// appJWTClockSkew backdates the JWT, in case the local clock is ahead of GitHub's.
+	appJWTClockSkew = time.Minute
+	// appTokenTimeout bounds the request for an installation token. oauth2 doesn't pass
+	// a context to Token, so the request can't use the context of the one it authenticates.
+	appTokenTimeout = 30 * time.Second
+)
+
+// AppConfig identifies a GitHub App installation to authenticate as. Its installation
+// tokens expire after an hour and have a rate limit of their own, which grows with
+// the number of repositories the app is installed on.
+type AppConfig struct {
+	AppID          int64           // The app's ID, from its settings page
+	InstallationID int64           // The ID of the app's installation on the organization or user
+	PrivateKey     *rsa.PrivateKey // One of the app's private keys, which signs the JWT
+}
+
+// AppConfigFromEnv reads the GitHub App configuration from ACTLOCK_APP_ID,
+// ACTLOCK_APP_INSTALLATION_ID, and either ACTLOCK_APP_PRIVATE_KEY (the PEM-encoded
+// key itself) or ACTLOCK_APP_PRIVATE_KEY_FILE (the path of the .pem file).
+//
+// Returns: The configuration, nil if no app is configured, and an error if the
+// configuration is incomplete or invalid.
+func AppConfigFromEnv() (*AppConfig, error) {
+	rawID := os.Getenv(appIDEnv)
+	rawInstallation := os.Getenv(appInstallationIDEnv)
+	pemKey := os.Getenv(appPrivateKeyEnv)
+	keyFile := os.Getenv(appPrivateKeyFileEnv)
+	if rawID == "" && rawInstallation == "" && pemKey == "" && keyFile == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	if rawID == "" || rawInstallation == "" || (pemKey == "" && keyFile == "") {
+		return nil, fmt.Errorf(
+			"incomplete GitHub App configuration: %s, %s, and %s or %s must all be set",
+			appIDEnv, appInstallationIDEnv, appPrivateKeyEnv, appPrivateKeyFileEnv,
+		)
+	}
+	appID, err := strconv.ParseInt(rawID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: %w", appIDEnv, rawID, err)
+	}
+	installationID, err := strconv.ParseInt(rawInstallation, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: %w", appInstallationIDEnv, rawInstallation, err)
+	}
+
+	data := []byte(pemKey)
+	if pemKey == "" {
+		data, err = os.ReadFile(keyFile) //nolint:gosec
+		if err != nil {
+			return nil, fmt.Errorf("could not read the GitHub App private key: %w", err)
+		}
+	}
+	key, err := ParseAppPrivateKey(data)
+	if err != nil {
+		return nil, err
+	}
+	return &AppConfig{AppID: appID, InstallationID: installationID, PrivateKey: key}, nil
+}
+
+// ParseAppPrivateKey parses a GitHub App private key, as downloaded from the app's
+// settings (PKCS #1) or converted to PKCS #8.
+//
+// - data: The PEM-encoded key.
+// Returns: The RSA private key, and an error if the data isn't a PEM-encoded RSA key.
+func ParseAppPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid GitHub App private key: no PEM data found")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GitHub App private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("invalid GitHub App private key: not an RSA key")
+	}
+	return key, nil
+}
+
+// appTokenSource is an oauth2.TokenSource of installation tokens. Each token is
+// requested with a freshly signed JWT; New wraps it in oauth2.ReuseTokenSource, so a
+// new token is only requested once the current one is about to expire.
+type appTokenSource struct {
+	api    *github.Client // Unauthenticated client for the host's API, which the JWT is added to
+	config AppConfig
+	now    func() time.Time
+}
+
+// NewAppTokenSource returns a token source of installation tokens for a GitHub App.
+// It requests a new token on every call: give it to WithTokenSource, which reuses each
+// token until it is about to expire, or wrap it in oauth2.ReuseTokenSource.
+//
+// - api: A client for the host's API. It must not cache responses, since each token
+// request must reach GitHub.
+// - config: The app installation to authenticate as.
+// Returns: The token source.
+func NewAppTokenSource(api *github.Client, config AppConfig) oauth2.TokenSource {
+	return &appTokenSource{api: api, config: config, now: time.Now}
+}
+
+// Token requests a new installation token, giving GitHub appTokenTimeout to respond.
+//
+// Returns: The token with its expiry, and an error if the JWT can't be signed or
+// GitHub refuses it.
+func (s *appTokenSource) Token() (*oauth2.Token, error) {
+	jwt, err := signAppJWT(s.config.AppID, s.config.PrivateKey, s.now())
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), appTokenTimeout)
+	defer cancel()
+	token, _, err := s.api.WithAuthToken(jwt).Apps.CreateInstallationToken(
+		ctx, s.config.InstallationID, nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"could not get a token for installation %d of GitHub App %d: %w",
+			s.config.InstallationID, s.config.AppID, err,
+		)
+	}
+	return &oauth2.Token{
+		AccessToken: token.GetToken(),
+		TokenType:   "Bearer",
+		Expiry:      token.GetExpiresAt().Time,
+	}, nil
+}
+
+// signAppJWT signs the RS256 JWT a GitHub App authenticates with to request an
+// installation token.
+//
+// - appID: The app's ID, the issuer of the JWT.
+// - key: The app's private key.
+// - now: The current time.
+// Returns: The encoded JWT, and an error if it can't be signed.
+func signAppJWT(appID int64, key *rsa.PrivateKey, now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-appJWTClockSkew).Unix(),
+		"exp": now.Add(appJWTLifetime).Unix(),
+		"iss": strconv.FormatInt(appID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("could not sign the GitHub App JWT: %w", err)
+	}
+	return unsigned + "." + enc.EncodeToString(signature), nil
+}
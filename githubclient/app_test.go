@@ -0,0 +1,160 @@
+// SPDX-License-Identifier: MIT
+
+package githubclient_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/esacteksab/gh-actlock/githubclient"
+	"github.com/esacteksab/gh-actlock/utils"
+)
+
+// verifyAppJWT checks the RS256 signature and claims of a GitHub App JWT.
+func verifyAppJWT(t *testing.T, key *rsa.PublicKey, jwt string) {
+	t.Helper()
+	parts := strings.Split(jwt, ".")
+	require.Len(t, parts, 3)
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	require.NoError(t, rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature))
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	var claims struct {
+		IssuedAt  int64  `json:"iat"`
+		ExpiresAt int64  `json:"exp"`
+		Issuer    string `json:"iss"`
+	}
+	require.NoError(t, json.Unmarshal(payload, &claims))
+	assert.Equal(t, "12345", claims.Issuer)
+	assert.LessOrEqual(t, claims.ExpiresAt-claims.IssuedAt, int64(10*time.Minute/time.Second))
+	assert.Greater(t, claims.ExpiresAt, time.Now().Unix())
+}
+
+func TestNewClientForHost_GitHubApp(t *testing.T) {
+	utils.CreateLogger(false)
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("GH_ENTERPRISE_TOKEN", "enterprise-token") // The app takes precedence
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	keyFile := filepath.Join(t.TempDir(), "app.pem")
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	}), 0o600))
+	t.Setenv("ACTLOCK_APP_ID", "12345")
+	t.Setenv("ACTLOCK_APP_INSTALLATION_ID", "678")
+	t.Setenv("ACTLOCK_APP_PRIVATE_KEY_FILE", keyFile)
+
+	// Each installation token expires within oauth2's expiry margin, so every API
+	// request needs a new one.
+	var tokens atomic.Int32
+	var apiAuth []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/app/installations/678/access_tokens":
+			assert.Equal(t, http.MethodPost, r.Method)
+			jwt, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			require.True(t, ok)
+			verifyAppJWT(t, &key.PublicKey, jwt)
+			n := tokens.Add(1)
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(map[string]any{ //nolint:errchkjson
+				"token":      fmt.Sprintf("ghs_%d", n),
+				"expires_at": time.Now().Add(5 * time.Second).UTC().Format(time.RFC3339),
+			})
+		case "/api/v3/rate_limit":
+			apiAuth = append(apiAuth, r.Header.Get("Authorization"))
+			_ = json.NewEncoder(w).Encode(map[string]any{ //nolint:errchkjson
+				"resources": map[string]any{"core": map[string]int{"limit": 15000, "remaining": 14999}},
+			})
+		case "/api/v3/repos/internal/action":
+			apiAuth = append(apiAuth, r.Header.Get("Authorization"))
+			_ = json.NewEncoder(w).Encode(map[string]string{"default_branch": "trunk"}) //nolint:errchkjson
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := githubclient.NewClientForHost(context.Background(), srv.URL)
+	require.NoError(t, err)
+
+	branch, err := githubclient.NewResolver(client).GetDefaultBranch(context.Background(), "internal", "action")
+	require.NoError(t, err)
+	assert.Equal(t, "trunk", branch)
+
+	// The rate limit check and the lookup each used a fresh installation token.
+	assert.Equal(t, int32(2), tokens.Load())
+	assert.Equal(t, []string{"Bearer ghs_1", "Bearer ghs_2"}, apiAuth)
+}
+
+func TestAppConfigFromEnv(t *testing.T) {
+	t.Run("not configured", func(t *testing.T) {
+		t.Setenv("ACTLOCK_APP_ID", "")
+		t.Setenv("ACTLOCK_APP_INSTALLATION_ID", "")
+		t.Setenv("ACTLOCK_APP_PRIVATE_KEY", "")
+		t.Setenv("ACTLOCK_APP_PRIVATE_KEY_FILE", "")
+		config, err := githubclient.AppConfigFromEnv()
+		require.NoError(t, err)
+		assert.Nil(t, config)
+	})
+
+	t.Run("incomplete", func(t *testing.T) {
+		t.Setenv("ACTLOCK_APP_ID", "12345")
+		t.Setenv("ACTLOCK_APP_INSTALLATION_ID", "")
+		t.Setenv("ACTLOCK_APP_PRIVATE_KEY", "")
+		t.Setenv("ACTLOCK_APP_PRIVATE_KEY_FILE", "")
+		_, err := githubclient.AppConfigFromEnv()
+		require.ErrorContains(t, err, "incomplete GitHub App configuration")
+	})
+
+	t.Run("PKCS #8 key", func(t *testing.T) {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		require.NoError(t, err)
+		t.Setenv("ACTLOCK_APP_ID", "12345")
+		t.Setenv("ACTLOCK_APP_INSTALLATION_ID", "678")
+		t.Setenv("ACTLOCK_APP_PRIVATE_KEY", string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})))
+		t.Setenv("ACTLOCK_APP_PRIVATE_KEY_FILE", "")
+
+		config, err := githubclient.AppConfigFromEnv()
+		require.NoError(t, err)
+		assert.Equal(t, int64(12345), config.AppID)
+		assert.Equal(t, int64(678), config.InstallationID)
+		assert.True(t, key.Equal(config.PrivateKey))
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		t.Setenv("ACTLOCK_APP_ID", "12345")
+		t.Setenv("ACTLOCK_APP_INSTALLATION_ID", "678")
+		t.Setenv("ACTLOCK_APP_PRIVATE_KEY", "not a key")
+		t.Setenv("ACTLOCK_APP_PRIVATE_KEY_FILE", "")
+		_, err := githubclient.AppConfigFromEnv()
+		require.ErrorContains(t, err, "no PEM data found")
+	})
+}
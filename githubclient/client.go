@@ -5,51 +5,49 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 
+	"github.com/charmbracelet/log"
 	"github.com/google/go-github/v82/github"
 	"golang.org/x/oauth2"
 
 	"github.com/esacteksab/httpcache"
 	"github.com/esacteksab/httpcache/diskcache"
 
+	"github.com/esacteksab/gh-actlock/parser"
 	"github.com/esacteksab/gh-actlock/utils"
 )
 
 // SHALength is the standard length of a Git SHA-1 hash.
-const (
-	SHALength   = 40
-	authLimit   = 5000
-	unAuthLimit = 60
-)
-
-// isHexDigit checks if a byte is a valid hexadecimal digit (0-9, a-f, A-F).
 //
-// - b: The byte to check.
-// Returns: true if the byte is a valid hex digit, false otherwise.
-func isHexDigit(b byte) bool {
-	return (b >= '0' && b <= '9') || (b >= 'a' && b <= 'f') || (b >= 'A' && b <= 'F')
-}
+// Deprecated: Use parser.SHALength.
+const SHALength = parser.SHALength
 
 // IsHexString checks if a string consists entirely of valid hexadecimal digits.
-// This is used to determine if a string is likely a Git SHA.
+//
+// Deprecated: Use parser.IsHexString.
 //
 // - s: The string to check.
 // Returns: true if the string contains only hexadecimal characters, false otherwise.
 func IsHexString(s string) bool {
-	for i := 0; i < len(s); i++ {
-		if !isHexDigit(s[i]) {
-			return false
-		}
-	}
-	return true
+	return parser.IsHexString(s)
 }
 
+// The core rate limits of authenticated and unauthenticated requests.
+const (
+	authLimit   = 5000
+	unAuthLimit = 60
+)
+
 // CachingTransport wraps an http.RoundTripper to potentially add custom logic,
 // such as logging or metrics, around the transport (including the cache layer).
 type CachingTransport struct {
-	Transport http.RoundTripper // The underlying transport, which could be the cache transport or an authenticated transport.
+	Transport   http.RoundTripper // The underlying transport, which could be the cache transport or an authenticated transport.
+	TokenSource string            // Where the token came from (see FindCredential), empty when unauthenticated.
+	Logger      *log.Logger       // Where the client logs (see WithLogger), nil for the default logger.
 }
 
 // RoundTrip executes a single HTTP transaction, passing it to the wrapped Transport.
@@ -66,25 +64,87 @@ func (t *CachingTransport) RoundTrip(req *http.Request) (*http.Response, error)
 	return t.Transport.RoundTrip(req)
 }
 
-// NewClient initializes and returns a new GitHub API client.
-// It configures authentication (using GITHUB_TOKEN if available) and adds an HTTP cache layer.
+// NewClient initializes and returns a new GitHub API client for GitHub.com, or for a
+// stand-in for its API, such as a proxy or the fake server in githubtest. The client is
+// set up as by NewClientForHost, except that a stand-in is only ever sent the token it
+// is given: GitHub.com's credentials stay with GitHub.com.
+//
+// Deprecated: Use New with WithBaseURL, WithCache(HostCache(baseURL)), and
+// WithCredential, then ReportRateLimit.
 //
 // - ctx: The context for the client, allows for cancellation.
+// - baseURL: The root of the REST API, e.g. "http://127.0.0.1:8080/", or "" for GitHub.com's.
+// Responses from another API are cached apart from GitHub.com's, by the URL's host. Plain
+// http is only allowed for loopback hosts.
+// - credential: The token to authenticate with. When its Token is empty, a stand-in is
+// used unauthenticated, and GitHub.com with its credentials as found by NewClientForHost.
+// - opts: Options for New, such as WithLogger and WithMaxWait. The base URL, cache, and
+// credentials are NewClient's own.
 // Returns: An initialized *github.Client and an error if setup fails (e.g., cache directory creation).
-func NewClient(ctx context.Context) (*github.Client, error) {
-	// Get the user's cache directory (platform-specific).
-	// This is where we'll store cached HTTP responses to reduce API calls.
-	projectCacheDir, err := os.UserCacheDir()
+func NewClient(ctx context.Context, baseURL string, credential Credential, opts ...Option) (*github.Client, error) {
+	if baseURL == "" && credential.Token == "" {
+		return NewClientForHost(ctx, DefaultHost, opts...)
+	}
+	target, cacheHost := WithHost(DefaultHost), DefaultHost
+	if baseURL != "" {
+		target, cacheHost = WithBaseURL(baseURL), baseURL
+	}
+	cache, err := HostCache(cacheHost)
 	if err != nil {
-		// Return an error if the user cache directory cannot be determined.
-		return nil, fmt.Errorf("failed to get user cache directory: %w", err)
+		return nil, err
 	}
+	return newReportedClient(ctx, append(slices.Clip(opts), target, WithCache(cache), WithCredential(credential)))
+}
 
-	// Define the subdirectory name within the user cache directory for this application.
-	appCacheDirName := "gh-actlock"
-	// Construct the full path for the application's cache directory.
-	cachePath := filepath.Join(projectCacheDir, appCacheDirName)
+// NewClientForHost initializes and returns a new GitHub API client for GitHub.com or a
+// GitHub Enterprise Server instance. It configures authentication (see HostCredentials)
+// and adds an HTTP cache layer kept per host (see HostCache).
+//
+// Deprecated: Use New with WithHost, WithCache(HostCache(hostname)), and
+// HostCredentials, then ReportRateLimit.
+//
+// - ctx: The context for the client, allows for cancellation.
+// - hostname: The host to talk to, e.g. "github.com" or "ghe.example.com".
+// - opts: Options for New, such as WithLogger and WithMaxWait. The base URL, cache, and
+// credentials are NewClientForHost's own.
+// Returns: An initialized *github.Client and an error if setup fails (e.g., cache directory creation).
+func NewClientForHost(ctx context.Context, hostname string, opts ...Option) (*github.Client, error) {
+	cache, err := HostCache(hostname)
+	if err != nil {
+		return nil, err
+	}
+	credentials, err := HostCredentials(ctx, hostname, resolveOptions(opts).logger)
+	if err != nil {
+		return nil, err
+	}
+	return newReportedClient(ctx, append(slices.Clip(opts), WithHost(hostname), WithCache(cache), credentials))
+}
 
+// newReportedClient implements NewClient and NewClientForHost, which, unlike New, have
+// always checked the rate limit once the client is created.
+//
+// - ctx: The context for the rate limit check.
+// - opts: The options for New.
+// Returns: The client, and an error if New fails.
+func newReportedClient(ctx context.Context, opts []Option) (*github.Client, error) {
+	client, err := New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	ReportRateLimit(ctx, client)
+	return client, nil
+}
+
+// HostCache returns the disk cache the HTTP responses from a host are kept in, see
+// CacheDir, creating its directory if needed.
+//
+// - hostname: The host, or the root of a stand-in API, whose responses are cached.
+// Returns: The cache, and an error if its directory can't be determined or created.
+func HostCache(hostname string) (httpcache.Cache, error) {
+	cachePath, err := CacheDir(hostname)
+	if err != nil {
+		return nil, err
+	}
 	// Create the cache directory if it doesn't exist. 0o750 is the permission
 	// mode in octal notation: Owner: read/write/execute (7) Group: read/execute
 	// (5) Others: no access (0)
@@ -92,42 +152,113 @@ func NewClient(ctx context.Context) (*github.Client, error) {
 		// Return an error if the cache directory cannot be created.
 		return nil, fmt.Errorf("could not create cache directory '%s': %w", cachePath, err)
 	}
+	return diskcache.New(cachePath), nil
+}
 
-	// Initialize the disk cache using the specified path.
-	// This cache will store HTTP responses to reduce API calls.
-	cache := diskcache.New(cachePath)
-
-	// Get the GitHub token from the environment variable.
-	// Using an environment variable is more secure than hardcoding the token.
-	token := os.Getenv("GITHUB_TOKEN")
+// HostCredentials finds how a client authenticates to a host: as the GitHub App
+// installation configured in the environment (see AppConfigFromEnv), or else with the
+// host's token (see FindCredential).
+//
+// - ctx: The context for looking up the host's token.
+// - hostname: The host whose credentials are used.
+// - logger: Where to log, nil for the default logger.
+// Returns: The option authenticating the client, which leaves it unauthenticated when
+// no credentials were found, and an error if the host or the GitHub App configuration
+// is invalid.
+func HostCredentials(ctx context.Context, hostname string, logger *log.Logger) (Option, error) {
+	if logger == nil {
+		logger = defaultLogger()
+	}
+	api, err := apiURL(hostname)
+	if err != nil {
+		return nil, err
+	}
+	source, sourceName, err := findTokenSource(ctx, hostname, api, logger)
+	if err != nil {
+		return nil, err
+	}
+	if source == nil {
+		return func(*clientOptions) {}, nil
+	}
+	return func(o *clientOptions) {
+		o.tokenSource = source
+		o.tokenSourceName = sourceName
+	}, nil
+}
 
-	var httpClient *http.Client // Variable to hold the final configured HTTP client.
-	// Initialize an HTTP transport that uses the disk cache.
-	cacheTransport := httpcache.NewTransport(cache)
+// CacheDir returns the directory the HTTP responses from a host are cached in.
+//
+// - hostname: The host, as given to NewClientForHost.
+// Returns: <user cache dir>/gh-actlock/<host>, and an error if the user cache directory
+// can't be determined or the hostname is invalid.
+func CacheDir(hostname string) (string, error) {
+	host, err := hostURL(hostname)
+	if err != nil {
+		return "", err
+	}
 
-	if token != "" {
-		ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
-		authTransport := &oauth2.Transport{
-			Base:   cacheTransport,
-			Source: oauth2.ReuseTokenSource(nil, ts),
-		}
-		cachingTransport := &CachingTransport{Transport: authTransport}
-		httpClient = &http.Client{Transport: cachingTransport}
-	} else {
-		debugTransport := &CachingTransport{Transport: cacheTransport}
-		httpClient = &http.Client{Transport: debugTransport}
+	// Get the user's cache directory (platform-specific).
+	// This is where we'll store cached HTTP responses to reduce API calls.
+	projectCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		// Return an error if the user cache directory cannot be determined.
+		return "", fmt.Errorf("failed to get user cache directory: %w", err)
 	}
 
-	client := github.NewClient(httpClient)
+	// Define the subdirectory name within the user cache directory for this application.
+	appCacheDirName := "gh-actlock"
+	// Construct the full path for the host's cache directory within the application's.
+	return filepath.Join(projectCacheDir, appCacheDirName, hostCacheDir(host)), nil
+}
 
-	// After client creation, check and log the actual rate limit/auth status:
-	limitType := CheckRateLimit(ctx, client)
-	utils.LogRateLimitStatus(limitType)
+// findTokenSource chooses how the client authenticates to a host: as the GitHub App
+// installation configured in the environment (see AppConfigFromEnv), or else with the
+// host's token (see FindCredential).
+//
+// - ctx: The context for looking up the host's token.
+// - hostname: The host whose credentials are used.
+// - api: The root of the REST API.
+// - logger: Where to log.
+// Returns: The token source, nil when no credentials were found; a description of
+// where the token comes from; and an error if the GitHub App configuration is invalid.
+func findTokenSource(
+	ctx context.Context,
+	hostname string,
+	api *url.URL,
+	logger *log.Logger,
+) (oauth2.TokenSource, string, error) {
+	app, err := AppConfigFromEnv()
+	if err != nil {
+		return nil, "", err
+	}
+	if app != nil {
+		// Installation tokens are requested without the cache, so each one reaches GitHub.
+		source := fmt.Sprintf("GitHub App %d, installation %d", app.AppID, app.InstallationID)
+		return NewAppTokenSource(newAPIClient(nil, api), *app), source, nil
+	}
 
-	return client, nil
+	// Find the host's GitHub token in the environment or gh's own credentials.
+	// Using the environment or gh is more secure than hardcoding the token.
+	credential := FindCredential(ctx, hostname, logger)
+	if credential.Token == "" {
+		return nil, "", nil
+	}
+	return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: credential.Token}), credential.Source, nil
+}
+
+// newAPIClient creates a GitHub client for a REST API.
+//
+// - httpClient: The HTTP client requests are made with, nil for http.DefaultClient.
+// - api: The root of the REST API, see apiURL.
+// Returns: The client.
+func newAPIClient(httpClient *http.Client, api *url.URL) *github.Client {
+	client := github.NewClient(httpClient)
+	client.BaseURL = api
+	return client
 }
 
-// CheckRateLimit retrieves the current GitHub API rate limit status and logs it.
+// CheckRateLimit retrieves the current GitHub API rate limit status and logs it,
+// along with where the client's token came from.
 // This is useful for monitoring usage and diagnosing rate limit errors.
 //
 // - ctx: The context for the API call, allows for cancellation/timeouts.
@@ -135,13 +266,23 @@ func NewClient(ctx context.Context) (*github.Client, error) {
 //
 // Returns a string representing the state of authentication.
 func CheckRateLimit(ctx context.Context, client *github.Client) string {
+	logger := loggerFor(client)
+	if transport, ok := client.Client().Transport.(*CachingTransport); ok && transport.TokenSource != "" {
+		logger.Debugf("Using the GitHub token from %s", transport.TokenSource)
+	} else {
+		logger.Debugf("No GitHub token found in GH_TOKEN, GITHUB_TOKEN, or gh's credentials")
+	}
 	limits, resp, err := client.RateLimit.Get(ctx)
 	if err != nil {
-		PrintRateLimit(resp)
+		if resp == nil {
+			printRate(logger, nil)
+		} else {
+			printRate(logger, &resp.Rate)
+		}
 		return "unknown"
 	}
 	if limits != nil && limits.Core != nil {
-		printRate(limits.Core)
+		printRate(logger, limits.Core)
 		switch {
 		case limits.Core.Limit >= authLimit:
 			return "authenticated"
@@ -151,40 +292,62 @@ func CheckRateLimit(ctx context.Context, client *github.Client) string {
 			return "unknown"
 		}
 	}
-	utils.Logger.Debugf("Warning: Rate limit data not available in response.")
+	logger.Debugf("Warning: Rate limit data not available in response.")
 	return "unknown"
 }
 
+// ReportRateLimit checks the rate limit of a client created by New, as CheckRateLimit
+// does, and logs whether the client is authenticated. New makes no requests of its own,
+// so this is the one call that tells a program whether its credentials work.
+//
+// - ctx: The context for the API call.
+// - client: The client.
+// Returns: The state of authentication, see CheckRateLimit.
+func ReportRateLimit(ctx context.Context, client *github.Client) string {
+	sourceName := ""
+	if transport, ok := client.Client().Transport.(*CachingTransport); ok {
+		sourceName = transport.TokenSource
+	}
+	limitType := CheckRateLimit(ctx, client)
+	utils.LogRateLimitStatus(loggerFor(client), limitType, sourceName)
+	return limitType
+}
+
 // PrintRateLimit logs rate limit information extracted directly from a GitHub API Response.
 // This function is primarily used as a fallback if retrieving the full RateLimit struct fails.
 //
+// - logger: Where to log, nil for the default logger.
 // - resp: The *github.Response object from a GitHub API call.
-func PrintRateLimit(resp *github.Response) {
+func PrintRateLimit(logger *log.Logger, resp *github.Response) {
+	if logger == nil {
+		logger = defaultLogger()
+	}
 	// If the response object itself is nil, call printRate with a nil rate object.
 	if resp == nil {
-		printRate(nil) // printRate will log "Rate limit info unavailable."
+		printRate(logger, nil) // printRate will log "Rate limit info unavailable."
 		return
 	}
 	// If the response is not nil, pass the address of its Rate field to printRate.
 	// The github.Response.Rate field contains limit details from the response headers.
-	printRate(&resp.Rate)
+	printRate(logger, &resp.Rate)
 }
 
 // printRate logs the details of a specific rate limit struct.
 // It formats the remaining requests, total limit, and reset time.
 //
+// - logger: Where to log.
 // - rate: A pointer to the github.Rate struct containing limit details.
-func printRate(rate *github.Rate) {
+func printRate(logger *log.Logger, rate *github.Rate) {
 	// Check if the rate struct is nil (e.g., if called with a nil response).
 	if rate == nil {
-		utils.Logger.Debugf("Rate limit info unavailable.")
+		logger.Debugf("Rate limit info unavailable.")
 		return
 	}
 	// Format the reset time from UTC to the local timezone and a readable string.
 	// The rate.Reset field contains the Unix timestamp when the rate limit resets.
 	resetTime := rate.Reset.Time.Local().Format("15:04:05 MST")
 	// Log the rate limit details: remaining requests, total limit, and reset time.
-	utils.Logger.Debugf(
+	logger.Debugf(
 		"Rate Limit: %d/%d remaining | Resets @ %s",
 		rate.Remaining,
 		rate.Limit,
@@ -195,9 +358,9 @@ func printRate(rate *github.Rate) {
 	const authenticatedLimit = 5000 // Typical authenticated rate limit per hour.
 	const unauthenticatedLimit = 60 // Typical unauthenticated rate limit per hour.
 	if rate.Limit >= authenticatedLimit {
-		utils.Logger.Debugf("  Using authenticated rate limits.")
+		logger.Debugf("  Using authenticated rate limits.")
 	} else if rate.Limit <= unauthenticatedLimit {
-		utils.Logger.Debugf("  Using unauthenticated rate limits.")
+		logger.Debugf("  Using unauthenticated rate limits.")
 	}
 }
 
@@ -219,6 +382,21 @@ func GetLatestActionRef(
 	owner string,
 	repo string,
 ) (string, string, error) {
+	ref, sha, _, err := latestActionRef(ctx, client, owner, repo)
+	return ref, sha, err
+}
+
+// latestActionRef implements GetLatestActionRef, also reporting whether the ref came
+// from the latest release.
+//
+// Returns: The ref name, its commit SHA, KindRelease for a release or "" for a tag
+// taken from the tag list, and an error if both release and tag retrieval fail.
+func latestActionRef(
+	ctx context.Context,
+	client *github.Client,
+	owner string,
+	repo string,
+) (string, string, RefKind, error) {
 	// First try to get the latest release as it's usually more stable
 	// Releases are formally published versions, often with release notes and assets
 	release, _, err := client.Repositories.GetLatestRelease(ctx, owner, repo)
@@ -227,7 +405,7 @@ func GetLatestActionRef(
 		sha, err := ResolveRefToSHA(ctx, client, owner, repo, *release.TagName)
 		if err == nil {
 			// Return the release tag name and its corresponding commit SHA
-			return *release.TagName, sha, nil
+			return *release.TagName, sha, KindRelease, nil
 		}
 		// If we couldn't get the SHA for the release tag, continue to try regular tags
 		// This can happen if the release references a lightweight tag that doesn't exist as a full ref
@@ -242,12 +420,12 @@ func GetLatestActionRef(
 	// Retrieve the list of tags for the repository
 	tags, _, err := client.Repositories.ListTags(ctx, owner, repo, opt)
 	if err != nil {
-		return "", "", fmt.Errorf("error getting tags for %s/%s: %w", owner, repo, err)
+		return "", "", "", fmt.Errorf("error getting tags for %s/%s: %w", owner, repo, err)
 	}
 
 	// Check if any tags were found
 	if len(tags) == 0 {
-		return "", "", fmt.Errorf("no tags found for %s/%s", owner, repo)
+		return "", "", "", fmt.Errorf("no tags found for %s/%s", owner, repo)
 	}
 
 	// Use the first tag in the list, which is typically the most recent
@@ -257,9 +435,9 @@ func GetLatestActionRef(
 	// Validate that the tag contains all the data we need
 	// Tags should have a name and a commit SHA, but we check to be safe
 	if latestTag.Name == nil || latestTag.Commit == nil || latestTag.Commit.SHA == nil {
-		return "", "", fmt.Errorf("invalid tag data for %s/%s", owner, repo)
+		return "", "", "", fmt.Errorf("invalid tag data for %s/%s", owner, repo)
 	}
 
 	// Return the tag name and its corresponding commit SHA
-	return *latestTag.Name, *latestTag.Commit.SHA, nil
+	return *latestTag.Name, *latestTag.Commit.SHA, "", nil
 }
@@ -16,6 +16,7 @@ import (
 	"golang.org/x/oauth2"
 
 	"github.com/esacteksab/gh-actlock/githubclient" // Import the package under test
+	"github.com/esacteksab/gh-actlock/githubclient/githubtest"
 	"github.com/esacteksab/gh-actlock/utils"
 )
 
@@ -67,22 +68,26 @@ func captureLogOutput(fn func()) string {
 func TestNewClient_WithToken(t *testing.T) {
 	utils.CreateLogger(true)
 	t.Setenv("ACTLOCK_DEBUG", "true")
-	t.Setenv("GITHUB_TOKEN", "fake-test-token")
-	// os.Getenv("GITHUB_TOKEN")
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	srv := githubtest.NewServer()
+	defer srv.Close()
 
 	ctx := context.Background()
 	var client *github.Client
 	var err error
 
 	logMsgs := captureLogOutput(func() {
-		client, err = githubclient.NewClient(ctx)
+		client, err = githubclient.NewClient(ctx, srv.APIURL(),
+			githubclient.Credential{Token: "fake-test-token", Source: "ACTLOCK_API_TOKEN"},
+			githubclient.WithLogger(utils.Logger))
 	})
 
 	require.NoError(t, err)
 	require.NotNil(t, client)
+	assert.Equal(t, srv.APIURL(), client.BaseURL.String())
 
 	// Check stdout message
-	assert.Contains(t, logMsgs, "ℹ️  Could not determine GitHub API authentication status.")
+	assert.Contains(t, logMsgs, "🔧  Authenticated GitHub API access in effect (token from ACTLOCK_API_TOKEN).")
 
 	// Check transport type (simplified check)
 	// This requires knowledge of internal structure, might be brittle
@@ -97,14 +102,19 @@ func TestNewClient_WithToken(t *testing.T) {
 func TestNewClient_WithoutToken(t *testing.T) {
 	utils.CreateLogger(true)
 	t.Setenv("ACTLOCK_DEBUG", "true")
-	t.Setenv("GITHUB_TOKEN", "") // Ensure token is unset
+	// GitHub.com's token is never sent to another API.
+	t.Setenv("GITHUB_TOKEN", "github-com-token")
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	srv := githubtest.NewServer()
+	defer srv.Close()
 
 	ctx := context.Background()
 	var client *github.Client
 	var err error
 
 	logMsgs := captureLogOutput(func() {
-		client, err = githubclient.NewClient(ctx)
+		client, err = githubclient.NewClient(ctx, srv.APIURL(), githubclient.Credential{},
+			githubclient.WithLogger(utils.Logger))
 	})
 
 	require.NoError(t, err)
@@ -126,6 +136,23 @@ func TestNewClient_WithoutToken(t *testing.T) {
 	// You could add a check for httpcache.Transport if needed
 }
 
+func TestNewClient_WithoutCLILogger(t *testing.T) {
+	// Programs embedding the package never create utils.Logger.
+	saved := utils.Logger
+	utils.Logger = nil
+	defer func() { utils.Logger = saved }()
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	srv := githubtest.NewServer()
+	defer srv.Close()
+
+	ctx := context.Background()
+	_, err := githubclient.NewClient(ctx, srv.APIURL(), githubclient.Credential{Token: "token"})
+	require.NoError(t, err)
+	_, err = githubclient.NewOfflineClient(githubclient.DefaultHost, srv.APIURL())
+	require.NoError(t, err)
+	assert.NotPanics(t, func() { githubclient.PrintRateLimit(nil, nil) })
+}
+
 func TestPrintRate(t *testing.T) {
 	utils.CreateLogger(true)
 	// This output will only be displayed when debugging
@@ -190,7 +217,7 @@ func TestPrintRate(t *testing.T) {
 			} // If tt.rate is nil, resp stays nil
 
 			logOutput := captureLogOutput(func() {
-				githubclient.PrintRateLimit(resp) // Call the public function
+				githubclient.PrintRateLimit(utils.Logger, resp) // Call the public function
 			})
 
 			for _, expected := range tt.expectedLogs {
@@ -0,0 +1,48 @@
+// SPDX-License-Identifier: MIT
+package githubclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/go-github/v82/github"
+)
+
+// ErrFileNotFound is wrapped by GetFileContent's error when the file doesn't exist at the ref.
+var ErrFileNotFound = errors.New("file not found")
+
+// GetFileContent retrieves the contents of a file in a repository at a given ref
+// using the contents API.
+//
+// - ctx: The context for API calls, allows for cancellation/timeouts.
+// - client: The initialized GitHub client for making API requests.
+// - owner: The owner (user or organization) of the GitHub repository.
+// - repo: The name of the GitHub repository.
+// - path: The path of the file in the repository, e.g. "action.yml".
+// - ref: The commit SHA, tag, or branch to read the file at.
+// Returns: The file contents, and an error (wrapping ErrFileNotFound if there is no such file).
+func GetFileContent(
+	ctx context.Context,
+	client *github.Client,
+	owner, repo, path, ref string,
+) ([]byte, error) {
+	file, _, resp, err := client.Repositories.GetContents(
+		ctx, owner, repo, path, &github.RepositoryContentGetOptions{Ref: ref})
+	if err != nil {
+		if isNotFoundError(err, resp) {
+			return nil, fmt.Errorf("%s/%s/%s@%s: %w", owner, repo, path, ref, ErrFileNotFound)
+		}
+		return nil, fmt.Errorf("error getting %s from %s/%s@%s: %w", path, owner, repo, ref, err)
+	}
+	// A directory listing has no file content.
+	if file == nil {
+		return nil, fmt.Errorf("%s/%s/%s@%s is a directory: %w", owner, repo, path, ref, ErrFileNotFound)
+	}
+
+	content, err := file.GetContent()
+	if err != nil {
+		return nil, fmt.Errorf("error decoding %s from %s/%s@%s: %w", path, owner, repo, ref, err)
+	}
+	return []byte(content), nil
+}
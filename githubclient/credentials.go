@@ -0,0 +1,152 @@
+// SPDX-License-Identifier: MIT
+package githubclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"gopkg.in/yaml.v3"
+)
+
+// ghAuthTokenTimeout bounds how long 'gh auth token' may take, so that a gh that prompts
+// or hangs can't stall creating the client.
+const ghAuthTokenTimeout = 5 * time.Second
+
+// Credential is a token for a GitHub host and where it was found.
+type Credential struct {
+	Token  string // The token, empty if none was found
+	Source string // Where the token came from, e.g. "GH_TOKEN" or "gh auth token"
+}
+
+// FindCredential looks for a token for a host, in the order the gh CLI uses:
+// 1. GH_TOKEN, then GITHUB_TOKEN for GitHub.com; GH_ENTERPRISE_TOKEN, then
+// GITHUB_ENTERPRISE_TOKEN for a GitHub Enterprise Server instance, so a
+// GitHub.com token is never sent to another host.
+// 2. The token 'gh auth login' stored for the host in gh's hosts.yml.
+// 3. The output of 'gh auth token --hostname <host>', for tokens gh keeps in the
+// system keyring instead.
+//
+// - ctx: The context 'gh auth token' runs in; it is also given ghAuthTokenTimeout at most.
+// - hostname: The hostname (or URL, see hostURL) the token is for.
+// - logger: Where to log why gh's credentials couldn't be read, nil for the default logger.
+// Returns: The credential, with an empty Token if none was found.
+func FindCredential(ctx context.Context, hostname string, logger *log.Logger) Credential {
+	if logger == nil {
+		logger = defaultLogger()
+	}
+	vars := []string{"GH_TOKEN", "GITHUB_TOKEN"}
+	if IsEnterprise(hostname) {
+		vars = []string{"GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"}
+	}
+	for _, name := range vars {
+		if token := os.Getenv(name); token != "" {
+			return Credential{Token: token, Source: name}
+		}
+	}
+
+	// gh knows hosts by name, without a scheme or path.
+	host := hostname
+	if u, err := hostURL(hostname); err == nil {
+		host = u.Host
+	}
+
+	path := ghHostsFile()
+	token, err := hostsFileToken(path, host)
+	if err != nil {
+		logger.Debugf("Could not read gh credentials from %s: %v", path, err)
+	}
+	if token != "" {
+		return Credential{Token: token, Source: path}
+	}
+
+	if token := ghAuthToken(ctx, host, logger); token != "" {
+		return Credential{Token: token, Source: "gh auth token"}
+	}
+	return Credential{}
+}
+
+// ghHostsFile returns the path of the file gh stores its per-host settings in.
+//
+// Returns: The path of hosts.yml, which may not exist.
+func ghHostsFile() string {
+	if dir := os.Getenv("GH_CONFIG_DIR"); dir != "" {
+		return filepath.Join(dir, "hosts.yml")
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "gh", "hosts.yml")
+	}
+	if dir := os.Getenv("AppData"); runtime.GOOS == "windows" && dir != "" {
+		return filepath.Join(dir, "GitHub CLI", "hosts.yml")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "gh", "hosts.yml")
+}
+
+// hostsFileToken reads the token stored for a host in gh's hosts.yml.
+//
+// - path: The path of hosts.yml.
+// - host: The hostname, e.g. "github.com".
+// Returns: The token, empty if the file or host has none, and an error if the file can't be read or parsed.
+func hostsFileToken(path, host string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	var hosts map[string]struct {
+		OAuthToken string `yaml:"oauth_token"`
+	}
+	if err := yaml.Unmarshal(data, &hosts); err != nil {
+		return "", fmt.Errorf("invalid gh hosts file: %w", err)
+	}
+	for name, settings := range hosts {
+		if strings.EqualFold(name, host) {
+			return settings.OAuthToken, nil
+		}
+	}
+	return "", nil
+}
+
+// ghAuthToken asks the gh CLI for its token for a host. It is quiet when gh isn't
+// installed or isn't logged in to the host, and gives up on a gh that takes longer
+// than ghAuthTokenTimeout.
+//
+// - ctx: The context gh runs in.
+// - host: The hostname, e.g. "github.com".
+// - logger: Where to log why gh had no token.
+// Returns: The token, or an empty string if gh has none.
+func ghAuthToken(ctx context.Context, host string, logger *log.Logger) string {
+	gh, err := exec.LookPath("gh")
+	if err != nil {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(ctx, ghAuthTokenTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, gh, "auth", "token", "--hostname", host) //nolint:gosec
+	// Children gh started may keep its output open after it was killed; don't wait on them.
+	cmd.WaitDelay = time.Second
+	out, err := cmd.Output()
+	if err != nil {
+		logger.Debugf("'gh auth token' found no token for %s: %v", host, err)
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,89 @@
+// SPDX-License-Identifier: MIT
+
+package githubclient_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/esacteksab/gh-actlock/githubclient"
+	"github.com/esacteksab/gh-actlock/utils"
+)
+
+func TestFindCredential(t *testing.T) {
+	utils.CreateLogger(false)
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake gh is a shell script")
+	}
+
+	configDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(configDir, "hosts.yml"), []byte(
+		"github.com:\n"+
+			"    user: octocat\n"+
+			"    oauth_token: gho_from_hosts_file\n"+
+			"    git_protocol: https\n"), 0o600))
+	t.Setenv("GH_CONFIG_DIR", configDir)
+
+	// A fake gh that only knows a token for the enterprise host.
+	binDir := t.TempDir()
+	script := "#!/bin/sh\n" +
+		"[ \"$4\" = ghe.example.com ] && echo gho_from_keyring && exit 0\n" +
+		"exit 1\n"
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "gh"), []byte(script), 0o700)) //nolint:gosec
+	t.Setenv("PATH", binDir)
+
+	for _, name := range []string{"GH_TOKEN", "GITHUB_TOKEN", "GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"} {
+		t.Setenv(name, "")
+	}
+
+	// gh's stored tokens are used when the environment has none.
+	assert.Equal(t,
+		githubclient.Credential{Token: "gho_from_hosts_file", Source: filepath.Join(configDir, "hosts.yml")},
+		githubclient.FindCredential(context.Background(), "github.com", nil))
+	assert.Equal(t,
+		githubclient.Credential{Token: "gho_from_keyring", Source: "gh auth token"},
+		githubclient.FindCredential(context.Background(), "ghe.example.com", nil))
+	assert.Equal(t, githubclient.Credential{}, githubclient.FindCredential(context.Background(), "other.example.com", nil))
+
+	// The environment wins, GH_TOKEN over GITHUB_TOKEN, and is only used for its own host.
+	t.Setenv("GITHUB_TOKEN", "github-token")
+	assert.Equal(t, githubclient.Credential{Token: "github-token", Source: "GITHUB_TOKEN"},
+		githubclient.FindCredential(context.Background(), "github.com", nil))
+	t.Setenv("GH_TOKEN", "gh-token")
+	assert.Equal(t, githubclient.Credential{Token: "gh-token", Source: "GH_TOKEN"},
+		githubclient.FindCredential(context.Background(), "github.com", nil))
+	assert.Equal(t, "gho_from_keyring", githubclient.FindCredential(context.Background(), "ghe.example.com", nil).Token)
+
+	t.Setenv("GITHUB_ENTERPRISE_TOKEN", "enterprise-token")
+	assert.Equal(t, githubclient.Credential{Token: "enterprise-token", Source: "GITHUB_ENTERPRISE_TOKEN"},
+		githubclient.FindCredential(context.Background(), "ghe.example.com", nil))
+}
+
+func TestFindCredential_GHTimeout(t *testing.T) {
+	utils.CreateLogger(false)
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake gh is a shell script")
+	}
+	t.Setenv("GH_CONFIG_DIR", t.TempDir())
+	for _, name := range []string{"GH_TOKEN", "GITHUB_TOKEN"} {
+		t.Setenv(name, "")
+	}
+
+	// A fake gh that waits for input that never comes, like one prompting for a login.
+	binDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "gh"), []byte("#!/bin/sh\nexec sleep 60\n"), 0o700)) //nolint:gosec
+	t.Setenv("PATH", binDir)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	assert.Equal(t, githubclient.Credential{}, githubclient.FindCredential(ctx, "github.com", nil))
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
@@ -0,0 +1,439 @@
+// SPDX-License-Identifier: MIT
+
+// Package githubtest provides an in-process stand-in for the GitHub REST API, serving
+// just enough of it to resolve, update, and verify action references in tests: repos,
+// refs, annotated tags, commits, releases, tags, branches, comparisons, and the rate limit.
+//
+// Point githubclient.NewClient at APIURL, or set ACTLOCK_API_URL to it for the CLI.
+// The GraphQL API isn't served, so clients fall back to the REST API.
+package githubtest
+
+import (
+	"crypto/sha1" //nolint:gosec
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rogpeppe/go-internal/txtar"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	authLimit   = 5000
+	unAuthLimit = 60
+	// defaultPerPage is the page size of list endpoints when per_page isn't given.
+	defaultPerPage = 30
+)
+
+// Repo is a repository served by the fake API. Every commit a branch, tag, or release
+// points at must be one of its Commits or ForkCommits.
+type Repo struct {
+	// DefaultBranch is the repository's default branch, "main" if empty.
+	DefaultBranch string `yaml:"default_branch"`
+	// Commits is the repository's history, oldest first: each commit's parent is the
+	// one before it.
+	Commits []string `yaml:"commits"`
+	// ForkCommits exist in the repository's fork network only: they can be fetched
+	// through the repository, but no branch or tag reaches them (impostor commits).
+	ForkCommits []string `yaml:"fork_commits"`
+	// Branches maps branch names to their head commit. Without an entry for it, the
+	// default branch's head is the newest commit.
+	Branches map[string]string `yaml:"branches"`
+	// Tags maps lightweight tag names to the commit they point at.
+	Tags map[string]string `yaml:"tags"`
+	// AnnotatedTags maps annotated tag names to the commit their tag object points at.
+	AnnotatedTags map[string]string `yaml:"annotated_tags"`
+	// Releases lists the tags that have a release, latest first.
+	Releases []string `yaml:"releases"`
+}
+
+// Server is a fake GitHub REST API. Its APIURL can be given to githubclient.NewClient.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	repos    map[string]*Repo // Lowercased "owner/repo" -> repository
+	requests int              // Requests served, including those that failed
+}
+
+// NewServer starts a fake GitHub API with no repositories. Call Close when done.
+//
+// Returns: The started *Server.
+func NewServer() *Server {
+	s := &Server{repos: make(map[string]*Repo)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /rate_limit", s.handleRateLimit)
+	mux.HandleFunc("GET /repos/{owner}/{repo}", s.handleRepo)
+	mux.HandleFunc("GET /repos/{owner}/{repo}/git/ref/{ref...}", s.handleRef)
+	mux.HandleFunc("GET /repos/{owner}/{repo}/git/tags/{sha}", s.handleTag)
+	mux.HandleFunc("GET /repos/{owner}/{repo}/git/commits/{sha}", s.handleCommit)
+	mux.HandleFunc("GET /repos/{owner}/{repo}/releases/latest", s.handleLatestRelease)
+	mux.HandleFunc("GET /repos/{owner}/{repo}/tags", s.handleTags)
+	mux.HandleFunc("GET /repos/{owner}/{repo}/branches", s.handleBranches)
+	mux.HandleFunc("GET /repos/{owner}/{repo}/compare/{basehead}", s.handleCompare)
+	mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+		writeError(w, http.StatusNotFound, "Not Found")
+	})
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests++
+		s.mu.Unlock()
+		mux.ServeHTTP(w, r)
+	}))
+	return s
+}
+
+// APIURL returns the root of the fake API, for githubclient.NewClient.
+//
+// Returns: The server's URL, with a trailing slash.
+func (s *Server) APIURL() string {
+	return s.URL + "/"
+}
+
+// Requests returns how many requests the server has received.
+//
+// Returns: The number of requests.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// AddRepo serves a repository, replacing any repository of the same name.
+//
+// - name: The repository's "owner/repo".
+// - repo: The repository.
+// Returns: An error if a branch, tag, or release points at a commit the repository doesn't have.
+func (s *Server) AddRepo(name string, repo Repo) error {
+	if repo.DefaultBranch == "" {
+		repo.DefaultBranch = "main"
+	}
+	branches := make(map[string]string, len(repo.Branches)+1)
+	for branch, sha := range repo.Branches {
+		branches[branch] = sha
+	}
+	if _, ok := branches[repo.DefaultBranch]; !ok && len(repo.Commits) > 0 {
+		branches[repo.DefaultBranch] = repo.Commits[len(repo.Commits)-1]
+	}
+	repo.Branches = branches
+
+	for _, refs := range []map[string]string{repo.Branches, repo.Tags, repo.AnnotatedTags} {
+		for ref, sha := range refs {
+			if !repo.hasCommit(sha) {
+				return fmt.Errorf("%s: %s points at unknown commit %s", name, ref, sha)
+			}
+		}
+	}
+	for _, tag := range repo.Releases {
+		if _, ok := repo.tagCommit(tag); !ok {
+			return fmt.Errorf("%s: release %s has no tag", name, tag)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.repos[strings.ToLower(name)] = &repo
+	return nil
+}
+
+// Seed serves the repositories in a txtar archive. Each file is named after a
+// repository, e.g. "actions/checkout", and holds its Repo as YAML.
+//
+// - archive: The archive.
+// Returns: An error if a file isn't a valid Repo.
+func (s *Server) Seed(archive *txtar.Archive) error {
+	for _, file := range archive.Files {
+		var repo Repo
+		if err := yaml.Unmarshal(file.Data, &repo); err != nil {
+			return fmt.Errorf("invalid repository %s: %w", file.Name, err)
+		}
+		if err := s.AddRepo(file.Name, repo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SeedFile serves the repositories in a txtar file, see Seed.
+//
+// - path: The path of the file.
+// Returns: An error if the file can't be read or holds an invalid Repo.
+func (s *Server) SeedFile(path string) error {
+	data, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		return err
+	}
+	return s.Seed(txtar.Parse(data))
+}
+
+// TagObjectSHA returns the SHA of the tag object of an annotated tag. It is made up from
+// the tag's name and commit, so it stays the same from run to run.
+//
+// - tag: The tag's name.
+// - commit: The commit the tag points at.
+// Returns: The tag object's SHA.
+func TagObjectSHA(tag, commit string) string {
+	sum := sha1.Sum([]byte("tag " + tag + "\x00" + commit)) //nolint:gosec
+	return hex.EncodeToString(sum[:])
+}
+
+// hasCommit reports whether a commit can be fetched through the repository.
+func (r *Repo) hasCommit(sha string) bool {
+	return slices.Contains(r.Commits, sha) || slices.Contains(r.ForkCommits, sha)
+}
+
+// tagCommit returns the commit a lightweight or annotated tag points at.
+func (r *Repo) tagCommit(tag string) (string, bool) {
+	if sha, ok := r.Tags[tag]; ok {
+		return sha, true
+	}
+	sha, ok := r.AnnotatedTags[tag]
+	return sha, ok
+}
+
+// resolve finds the commit a branch, tag, or commit SHA names, the way the compare
+// endpoint does.
+func (r *Repo) resolve(ref string) (string, bool) {
+	if sha, ok := r.Branches[ref]; ok {
+		return sha, true
+	}
+	if sha, ok := r.tagCommit(ref); ok {
+		return sha, true
+	}
+	return ref, r.hasCommit(ref)
+}
+
+// repo looks up the repository a request is for, answering 404 if there is none.
+func (s *Server) repo(w http.ResponseWriter, r *http.Request) (*Repo, bool) {
+	s.mu.Lock()
+	repo, ok := s.repos[strings.ToLower(r.PathValue("owner")+"/"+r.PathValue("repo"))]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not Found")
+	}
+	return repo, ok
+}
+
+// handleRateLimit serves /rate_limit, with GitHub's limits for authenticated and
+// anonymous requests. Any token is accepted.
+func (s *Server) handleRateLimit(w http.ResponseWriter, r *http.Request) {
+	limit := unAuthLimit
+	if r.Header.Get("Authorization") != "" {
+		limit = authLimit
+	}
+	rate := map[string]any{
+		"limit":     limit,
+		"remaining": limit - 1,
+		"reset":     time.Now().Add(time.Hour).Unix(),
+	}
+	writeJSON(w, map[string]any{"resources": map[string]any{"core": rate}, "rate": rate})
+}
+
+// handleRepo serves /repos/{owner}/{repo}.
+func (s *Server) handleRepo(w http.ResponseWriter, r *http.Request) {
+	repo, ok := s.repo(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, map[string]any{
+		"name":           r.PathValue("repo"),
+		"full_name":      r.PathValue("owner") + "/" + r.PathValue("repo"),
+		"default_branch": repo.DefaultBranch,
+	})
+}
+
+// handleRef serves /repos/{owner}/{repo}/git/ref/{tags,heads}/{name}.
+func (s *Server) handleRef(w http.ResponseWriter, r *http.Request) {
+	repo, ok := s.repo(w, r)
+	if !ok {
+		return
+	}
+	ref := r.PathValue("ref")
+	object := map[string]string{"type": "commit"}
+	switch kind, name, _ := strings.Cut(ref, "/"); {
+	case kind == "heads" && repo.Branches[name] != "":
+		object["sha"] = repo.Branches[name]
+	case kind == "tags" && repo.Tags[name] != "":
+		object["sha"] = repo.Tags[name]
+	case kind == "tags" && repo.AnnotatedTags[name] != "":
+		object["type"] = "tag"
+		object["sha"] = TagObjectSHA(name, repo.AnnotatedTags[name])
+	default:
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	writeJSON(w, map[string]any{"ref": "refs/" + ref, "object": object})
+}
+
+// handleTag serves /repos/{owner}/{repo}/git/tags/{sha}, the tag objects of annotated tags.
+func (s *Server) handleTag(w http.ResponseWriter, r *http.Request) {
+	repo, ok := s.repo(w, r)
+	if !ok {
+		return
+	}
+	sha := r.PathValue("sha")
+	for tag, commit := range repo.AnnotatedTags {
+		if TagObjectSHA(tag, commit) == sha {
+			writeJSON(w, map[string]any{
+				"sha":    sha,
+				"tag":    tag,
+				"object": map[string]string{"type": "commit", "sha": commit},
+			})
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "Not Found")
+}
+
+// handleCommit serves /repos/{owner}/{repo}/git/commits/{sha}.
+func (s *Server) handleCommit(w http.ResponseWriter, r *http.Request) {
+	repo, ok := s.repo(w, r)
+	if !ok {
+		return
+	}
+	sha := r.PathValue("sha")
+	if !repo.hasCommit(sha) {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	writeJSON(w, map[string]any{"sha": sha})
+}
+
+// handleLatestRelease serves /repos/{owner}/{repo}/releases/latest.
+func (s *Server) handleLatestRelease(w http.ResponseWriter, r *http.Request) {
+	repo, ok := s.repo(w, r)
+	if !ok {
+		return
+	}
+	if len(repo.Releases) == 0 {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	writeJSON(w, map[string]any{"tag_name": repo.Releases[0], "name": repo.Releases[0]})
+}
+
+// handleTags serves /repos/{owner}/{repo}/tags, newest commit first, as GitHub does.
+func (s *Server) handleTags(w http.ResponseWriter, r *http.Request) {
+	repo, ok := s.repo(w, r)
+	if !ok {
+		return
+	}
+	tags := make(map[string]string, len(repo.Tags)+len(repo.AnnotatedTags))
+	for _, refs := range []map[string]string{repo.Tags, repo.AnnotatedTags} {
+		for tag, sha := range refs {
+			tags[tag] = sha
+		}
+	}
+	names := make([]string, 0, len(tags))
+	for tag := range tags {
+		names = append(names, tag)
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		if c := slices.Index(repo.Commits, tags[b]) - slices.Index(repo.Commits, tags[a]); c != 0 {
+			return c
+		}
+		return strings.Compare(b, a)
+	})
+
+	list := make([]map[string]any, len(names))
+	for i, tag := range names {
+		list[i] = map[string]any{"name": tag, "commit": map[string]string{"sha": tags[tag]}}
+	}
+	writePage(w, r, list)
+}
+
+// handleBranches serves /repos/{owner}/{repo}/branches, sorted by name.
+func (s *Server) handleBranches(w http.ResponseWriter, r *http.Request) {
+	repo, ok := s.repo(w, r)
+	if !ok {
+		return
+	}
+	names := make([]string, 0, len(repo.Branches))
+	for branch := range repo.Branches {
+		names = append(names, branch)
+	}
+	slices.Sort(names)
+
+	list := make([]map[string]any, len(names))
+	for i, branch := range names {
+		list[i] = map[string]any{"name": branch, "commit": map[string]string{"sha": repo.Branches[branch]}}
+	}
+	writePage(w, r, list)
+}
+
+// handleCompare serves /repos/{owner}/{repo}/compare/{base}...{head}. The status says
+// where head is relative to base: "behind" when base's history contains it.
+func (s *Server) handleCompare(w http.ResponseWriter, r *http.Request) {
+	repo, ok := s.repo(w, r)
+	if !ok {
+		return
+	}
+	baseRef, headRef, found := strings.Cut(r.PathValue("basehead"), "...")
+	base, baseOK := repo.resolve(baseRef)
+	head, headOK := repo.resolve(headRef)
+	if !found || !baseOK || !headOK {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	baseIndex, headIndex := slices.Index(repo.Commits, base), slices.Index(repo.Commits, head)
+	var status string
+	switch {
+	case base == head:
+		status = "identical"
+	case baseIndex < 0 || headIndex < 0:
+		// At least one of them is a fork's commit.
+		status = "diverged"
+	case headIndex < baseIndex:
+		status = "behind"
+	default:
+		status = "ahead"
+	}
+	writeJSON(w, map[string]any{"status": status})
+}
+
+// writePage writes the page of a list a request asks for, with the Link header
+// go-github follows to the next page.
+func writePage(w http.ResponseWriter, r *http.Request, list []map[string]any) {
+	perPage, err := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if err != nil || perPage <= 0 {
+		perPage = defaultPerPage
+	}
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+
+	start := min((page-1)*perPage, len(list))
+	end := min(start+perPage, len(list))
+	if end < len(list) {
+		next := *r.URL
+		query := url.Values{"page": {strconv.Itoa(page + 1)}, "per_page": {strconv.Itoa(perPage)}}
+		next.RawQuery = query.Encode()
+		w.Header().Set("Link", fmt.Sprintf(`<http://%s%s>; rel="next"`, r.Host, next.RequestURI()))
+	}
+	writeJSON(w, list[start:end])
+}
+
+// writeJSON writes a successful JSON response, cacheable for a minute as GitHub's are.
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, max-age=60")
+	_ = json.NewEncoder(w).Encode(v) //nolint:errchkjson
+}
+
+// writeError writes an error response the way GitHub does.
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"message": message}) //nolint:errchkjson
+}
@@ -0,0 +1,263 @@
+// SPDX-License-Identifier: MIT
+package githubclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v82/github"
+
+	"github.com/esacteksab/gh-actlock/parser"
+)
+
+// graphQLBatchSize is how many refs are resolved per GraphQL query. Each ref costs up
+// to three fields, so this keeps queries well below GitHub's node limits.
+const graphQLBatchSize = 50
+
+// maxTagNesting is how many annotated tags pointing at annotated tags are peeled
+// server-side. A tag nested deeper is left to the REST API.
+const maxTagNesting = 3
+
+// RepoRef names a ref of a repository.
+type RepoRef struct {
+	Owner string // The owner of the repository
+	Repo  string // The name of the repository
+	Ref   string // The ref, e.g. "v4", "main", or a commit SHA
+}
+
+// gqlObject is a Git object in a GraphQL response. Annotated tags have a target.
+type gqlObject struct {
+	Typename string     `json:"__typename"`
+	OID      string     `json:"oid"`
+	Target   *gqlObject `json:"target"`
+}
+
+// gqlRef is a ref in a GraphQL response.
+type gqlRef struct {
+	Target *gqlObject `json:"target"`
+}
+
+// gqlResponse is the body of a GraphQL response: repository aliases, then field aliases.
+type gqlResponse struct {
+	Data   map[string]map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// ResolveBatch resolves many refs with a handful of GraphQL queries instead of one to
+// three REST calls each, and remembers the results, so that Resolve answers them
+// without an API call. Refs the queries don't resolve, because the GraphQL API isn't
+// available (e.g. without a token) or because the ref wasn't found, are left for
+// Resolve to look up with the REST API as usual.
+//
+// - ctx: The context for the API calls.
+// - refs: The refs to resolve.
+// Returns: The number of refs resolved.
+func (r *Resolver) ResolveBatch(ctx context.Context, refs []RepoRef) int {
+	resolved, err := ResolveRefsGraphQL(ctx, r.client, refs)
+	if err != nil {
+		loggerFor(r.client).Debugf("Resolving refs with the REST API, as the GraphQL API failed: %v", err)
+	}
+	for ref, res := range resolved {
+		r.refs.do(ref.Owner+"/"+ref.Repo+"@"+ref.Ref, func() (Resolution, error) {
+			return res, nil
+		})
+	}
+	loggerFor(r.client).Debugf("Resolved %d of %d ref(s) with the GraphQL API", len(resolved), len(refs))
+	return len(resolved)
+}
+
+// ResolveRefsGraphQL resolves refs the way ResolveRef does, checking for a commit SHA,
+// then a tag, then a branch, but for many repositories per GraphQL query. Annotated
+// tags are peeled to their commit by the server.
+//
+// - ctx: The context for the API calls.
+// - client: The initialized GitHub client.
+// - refs: The refs to resolve.
+// Returns: The resolutions of the refs that were found, and an error if a query failed.
+// Refs resolved by earlier queries are returned along with the error.
+func ResolveRefsGraphQL(ctx context.Context, client *github.Client, refs []RepoRef) (map[RepoRef]Resolution, error) {
+	resolved := make(map[RepoRef]Resolution, len(refs))
+	for start := 0; start < len(refs); start += graphQLBatchSize {
+		batch := refs[start:min(start+graphQLBatchSize, len(refs))]
+		if err := resolveGraphQLBatch(ctx, client, batch, resolved); err != nil {
+			return resolved, err
+		}
+	}
+	return resolved, nil
+}
+
+// resolveGraphQLBatch resolves one batch of refs with a single query.
+//
+// - ctx: The context for the API call.
+// - client: The initialized GitHub client.
+// - batch: The refs to resolve.
+// - resolved: Receives the resolutions of the refs that were found.
+// Returns: An error if the query failed.
+func resolveGraphQLBatch(
+	ctx context.Context,
+	client *github.Client,
+	batch []RepoRef,
+	resolved map[RepoRef]Resolution,
+) error {
+	query, aliases := buildRefsQuery(batch)
+	req, err := client.NewRequest(http.MethodPost, graphQLURL(client.BaseURL), map[string]string{"query": query})
+	if err != nil {
+		return err
+	}
+	var resp gqlResponse
+	if _, err := client.Do(ctx, req, &resp); err != nil {
+		return fmt.Errorf("GraphQL query failed: %w", err)
+	}
+	// A missing repository is reported as an error next to the data of the others.
+	for _, e := range resp.Errors {
+		loggerFor(client).Debugf("GraphQL: %s", e.Message)
+	}
+	if resp.Data == nil {
+		if len(resp.Errors) > 0 {
+			return fmt.Errorf("GraphQL query failed: %s", resp.Errors[0].Message)
+		}
+		return errors.New("GraphQL query returned no data")
+	}
+
+	for i, ref := range batch {
+		repo := resp.Data[aliases[i].repo]
+		if repo == nil {
+			continue
+		}
+		if res, ok := refFromGraphQL(repo, aliases[i].field, ref.Ref); ok {
+			resolved[ref] = res
+		}
+	}
+	return nil
+}
+
+// refAlias locates a ref's fields in a query: the repository's alias, and the suffix
+// shared by the ref's commit ("c"), tag ("t"), and branch ("h") fields.
+type refAlias struct {
+	repo  string
+	field string
+}
+
+// buildRefsQuery builds the query that resolves a batch of refs. Each repository is
+// queried once, with three fields per ref: the object with that SHA, if the ref looks
+// like one, and the tag and the branch with that name.
+//
+// - batch: The refs to resolve.
+// Returns: The query, and the aliases of each ref, in the order of the batch.
+func buildRefsQuery(batch []RepoRef) (string, []refAlias) {
+	var repos []string // "owner/repo", in the order they were first seen
+	fields := make(map[string][]string)
+	repoAlias := make(map[string]string)
+	aliases := make([]refAlias, len(batch))
+
+	for i, ref := range batch {
+		name := ref.Owner + "/" + ref.Repo
+		if _, ok := repoAlias[name]; !ok {
+			repoAlias[name] = "r" + strconv.Itoa(len(repos))
+			repos = append(repos, name)
+			fields[name] = []string{fmt.Sprintf("%s: repository(owner: %s, name: %s) {",
+				repoAlias[name], graphQLString(ref.Owner), graphQLString(ref.Repo))}
+		}
+		field := strconv.Itoa(i)
+		aliases[i] = refAlias{repo: repoAlias[name], field: field}
+
+		if len(ref.Ref) == parser.SHALength && parser.IsHexString(ref.Ref) {
+			fields[name] = append(fields[name],
+				fmt.Sprintf("c%s: object(oid: %s) { __typename oid }", field, graphQLString(ref.Ref)))
+		}
+		fields[name] = append(fields[name],
+			fmt.Sprintf("t%s: ref(qualifiedName: %s) { %s }", field, graphQLString("refs/tags/"+ref.Ref), peeledTarget()),
+			fmt.Sprintf("h%s: ref(qualifiedName: %s) { target { __typename oid } }",
+				field, graphQLString("refs/heads/"+ref.Ref)),
+		)
+	}
+
+	var query strings.Builder
+	query.WriteString("query {\n")
+	for _, name := range repos {
+		query.WriteString("  " + strings.Join(fields[name], "\n    ") + "\n  }\n")
+	}
+	query.WriteString("}\n")
+	return query.String(), aliases
+}
+
+// peeledTarget returns the selection of a tag ref's target, following annotated tags
+// up to maxTagNesting deep.
+//
+// Returns: The selection.
+func peeledTarget() string {
+	selection := "target { __typename oid }"
+	for range maxTagNesting {
+		selection = "target { __typename oid ... on Tag { " + selection + " } }"
+	}
+	return selection
+}
+
+// refFromGraphQL reads a ref's resolution from its repository's fields, in the order
+// ResolveRef checks them.
+//
+// - repo: The repository's fields in the response.
+// - field: The suffix of the ref's fields.
+// - ref: The ref.
+// Returns: The resolution, and false if the ref wasn't found.
+func refFromGraphQL(repo map[string]json.RawMessage, field, ref string) (Resolution, bool) {
+	var commit *gqlObject
+	if raw, ok := repo["c"+field]; ok && json.Unmarshal(raw, &commit) == nil &&
+		commit != nil && commit.Typename == "Commit" {
+		return Resolution{Ref: ref, SHA: commit.OID, Kind: KindCommit}, true
+	}
+
+	var tag *gqlRef
+	if raw, ok := repo["t"+field]; ok && json.Unmarshal(raw, &tag) == nil && tag != nil && tag.Target != nil {
+		kind := KindLightweightTag
+		target := tag.Target
+		for target != nil && target.Typename == "Tag" {
+			kind = KindAnnotatedTag
+			target = target.Target
+		}
+		if target != nil && target.Typename == "Commit" {
+			return Resolution{Ref: ref, SHA: target.OID, Kind: kind}, true
+		}
+		// Nested too deep, or not pointing at a commit: leave it to the REST API.
+		return Resolution{}, false
+	}
+
+	var branch *gqlRef
+	if raw, ok := repo["h"+field]; ok && json.Unmarshal(raw, &branch) == nil &&
+		branch != nil && branch.Target != nil && branch.Target.Typename == "Commit" {
+		return Resolution{Ref: ref, SHA: branch.Target.OID, Kind: KindBranch}, true
+	}
+	return Resolution{}, false
+}
+
+// graphQLURL returns the GraphQL endpoint of the API a client talks to:
+// https://api.github.com/graphql for GitHub.com, and /api/graphql on a GitHub
+// Enterprise Server instance, whose REST API is under /api/v3.
+//
+// - base: The client's BaseURL.
+// Returns: The GraphQL endpoint.
+func graphQLURL(base *url.URL) string {
+	u := *base
+	if prefix, ok := strings.CutSuffix(u.Path, "/api/v3/"); ok {
+		u.Path = prefix + "/api/graphql"
+		return u.String()
+	}
+	return u.ResolveReference(&url.URL{Path: "graphql"}).String()
+}
+
+// graphQLString quotes a string for a GraphQL query. JSON string syntax is valid GraphQL.
+//
+// - s: The string.
+// Returns: The quoted string.
+func graphQLString(s string) string {
+	quoted, _ := json.Marshal(s) //nolint:errchkjson
+	return string(quoted)
+}
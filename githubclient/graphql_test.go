@@ -0,0 +1,257 @@
+// SPDX-License-Identifier: MIT
+
+package githubclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-github/v82/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/esacteksab/gh-actlock/githubclient"
+	"github.com/esacteksab/gh-actlock/utils"
+)
+
+// fakeObject is a Git object served by fakeGraphQL.
+type fakeObject struct {
+	typename string      // "Commit" or "Tag"
+	oid      string      // The object's SHA
+	target   *fakeObject // What an annotated tag points at
+}
+
+func (o *fakeObject) toJSON() map[string]any {
+	if o == nil {
+		return nil
+	}
+	obj := map[string]any{"__typename": o.typename, "oid": o.oid}
+	if o.target != nil {
+		obj["target"] = o.target.toJSON()
+	}
+	return obj
+}
+
+// fakeRepo is a repository served by fakeGraphQL.
+type fakeRepo struct {
+	refs    map[string]*fakeObject // Qualified ref name -> target
+	commits map[string]bool        // Commit SHAs
+}
+
+var (
+	repoField   = regexp.MustCompile(`(\w+): repository\(owner: "([^"]*)", name: "([^"]*)"\) \{`)
+	refField    = regexp.MustCompile(`(\w+): ref\(qualifiedName: "([^"]*)"\)`)
+	objectField = regexp.MustCompile(`(\w+): object\(oid: "([^"]*)"\)`)
+)
+
+// fakeGraphQL answers the queries ResolveRefsGraphQL makes from the given repositories,
+// the way GitHub does: a missing repository is null and reported in errors.
+func fakeGraphQL(t *testing.T, repos map[string]fakeRepo, w http.ResponseWriter, r *http.Request) {
+	t.Helper()
+	var body struct {
+		Query string `json:"query"`
+	}
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+	data := map[string]any{}
+	var errs []map[string]string
+	// Each repository's fields run until the next repository.
+	blocks := repoField.FindAllStringSubmatchIndex(body.Query, -1)
+	for i, block := range blocks {
+		end := len(body.Query)
+		if i+1 < len(blocks) {
+			end = blocks[i+1][0]
+		}
+		alias := body.Query[block[2]:block[3]]
+		name := body.Query[block[4]:block[5]] + "/" + body.Query[block[6]:block[7]]
+		fields := body.Query[block[1]:end]
+
+		repo, ok := repos[name]
+		if !ok {
+			data[alias] = nil
+			errs = append(errs, map[string]string{"message": "Could not resolve to a Repository with the name '" + name + "'."})
+			continue
+		}
+		result := map[string]any{}
+		for _, m := range refField.FindAllStringSubmatch(fields, -1) {
+			if target, ok := repo.refs[m[2]]; ok {
+				result[m[1]] = map[string]any{"target": target.toJSON()}
+			} else {
+				result[m[1]] = nil
+			}
+		}
+		for _, m := range objectField.FindAllStringSubmatch(fields, -1) {
+			if repo.commits[m[2]] {
+				result[m[1]] = map[string]any{"__typename": "Commit", "oid": m[2]}
+			} else {
+				result[m[1]] = nil
+			}
+		}
+		data[alias] = result
+	}
+
+	resp := map[string]any{"data": data}
+	if len(errs) > 0 {
+		resp["errors"] = errs
+	}
+	_ = json.NewEncoder(w).Encode(resp) //nolint:errchkjson
+}
+
+func TestResolver_ResolveBatch(t *testing.T) {
+	utils.CreateLogger(false)
+
+	commitSHA := strings.Repeat("c", 40)
+	repos := map[string]fakeRepo{
+		"actions/checkout": {
+			refs: map[string]*fakeObject{
+				// An annotated tag of an annotated tag, peeled by the server.
+				"refs/tags/v4": {typename: "Tag", oid: "tag-outer", target: &fakeObject{
+					typename: "Tag", oid: "tag-inner", target: &fakeObject{typename: "Commit", oid: "sha-v4"},
+				}},
+				"refs/tags/v3":    {typename: "Commit", oid: "sha-v3"},
+				"refs/heads/main": {typename: "Commit", oid: "sha-main"},
+				// A tag and a branch with the same name: the tag wins, as with the REST API.
+				"refs/tags/both":  {typename: "Commit", oid: "sha-tag"},
+				"refs/heads/both": {typename: "Commit", oid: "sha-branch"},
+			},
+			commits: map[string]bool{commitSHA: true},
+		},
+		"actions/setup-go": {
+			refs: map[string]*fakeObject{"refs/tags/v5": {typename: "Commit", oid: "sha-go-v5"}},
+		},
+	}
+
+	var queries, restRequests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/graphql" {
+			queries.Add(1)
+			fakeGraphQL(t, repos, w, r)
+			return
+		}
+		restRequests.Add(1)
+		// The REST API knows a tag GraphQL doesn't, so a fallback can be told apart.
+		if r.URL.Path == "/repos/actions/setup-go/git/ref/tags/v6" {
+			_ = json.NewEncoder(w).Encode(map[string]any{ //nolint:errchkjson
+				"ref":    "refs/tags/v6",
+				"object": map[string]string{"type": "commit", "sha": "sha-go-v6"},
+			})
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	client := github.NewClient(nil)
+	baseURL, err := url.Parse(srv.URL + "/")
+	require.NoError(t, err)
+	client.BaseURL = baseURL
+	resolver := githubclient.NewResolver(client)
+
+	refs := []githubclient.RepoRef{
+		{Owner: "actions", Repo: "checkout", Ref: "v4"},
+		{Owner: "actions", Repo: "checkout", Ref: "v3"},
+		{Owner: "actions", Repo: "checkout", Ref: "main"},
+		{Owner: "actions", Repo: "checkout", Ref: "both"},
+		{Owner: "actions", Repo: "checkout", Ref: commitSHA},
+		{Owner: "actions", Repo: "setup-go", Ref: "v5"},
+		{Owner: "actions", Repo: "setup-go", Ref: "v6"},
+		{Owner: "actions", Repo: "missing", Ref: "v1"},
+	}
+	assert.Equal(t, 6, resolver.ResolveBatch(context.Background(), refs))
+	assert.Equal(t, int32(1), queries.Load())
+	assert.Equal(t, int32(0), restRequests.Load())
+
+	tests := []struct {
+		ref  githubclient.RepoRef
+		want githubclient.Resolution
+	}{
+		{ref: refs[0], want: githubclient.Resolution{Ref: "v4", SHA: "sha-v4", Kind: githubclient.KindAnnotatedTag}},
+		{ref: refs[1], want: githubclient.Resolution{Ref: "v3", SHA: "sha-v3", Kind: githubclient.KindLightweightTag}},
+		{ref: refs[2], want: githubclient.Resolution{Ref: "main", SHA: "sha-main", Kind: githubclient.KindBranch}},
+		{ref: refs[3], want: githubclient.Resolution{Ref: "both", SHA: "sha-tag", Kind: githubclient.KindLightweightTag}},
+		{ref: refs[4], want: githubclient.Resolution{Ref: commitSHA, SHA: commitSHA, Kind: githubclient.KindCommit}},
+		{ref: refs[5], want: githubclient.Resolution{Ref: "v5", SHA: "sha-go-v5", Kind: githubclient.KindLightweightTag}},
+		// Not found by GraphQL, so resolved with the REST API.
+		{ref: refs[6], want: githubclient.Resolution{Ref: "v6", SHA: "sha-go-v6", Kind: githubclient.KindLightweightTag}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref.Repo+"@"+tt.ref.Ref, func(t *testing.T) {
+			res, err := resolver.Resolve(context.Background(), tt.ref.Owner, tt.ref.Repo, tt.ref.Ref)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, res)
+		})
+	}
+	// Only the ref GraphQL didn't resolve needed REST calls (its tag, found first).
+	assert.Equal(t, int32(1), restRequests.Load())
+}
+
+func TestResolveRefsGraphQL_Batches(t *testing.T) {
+	utils.CreateLogger(false)
+
+	refs := make([]githubclient.RepoRef, 120)
+	repo := fakeRepo{refs: map[string]*fakeObject{}}
+	for i := range refs {
+		name := "v" + strings.Repeat("1", i+1)
+		refs[i] = githubclient.RepoRef{Owner: "owner", Repo: "repo", Ref: name}
+		repo.refs["refs/tags/"+name] = &fakeObject{typename: "Commit", oid: "sha-" + name}
+	}
+
+	var queries atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries.Add(1)
+		fakeGraphQL(t, map[string]fakeRepo{"owner/repo": repo}, w, r)
+	}))
+	defer srv.Close()
+
+	client := github.NewClient(nil)
+	baseURL, err := url.Parse(srv.URL + "/")
+	require.NoError(t, err)
+	client.BaseURL = baseURL
+
+	resolved, err := githubclient.ResolveRefsGraphQL(context.Background(), client, refs)
+	require.NoError(t, err)
+	assert.Len(t, resolved, len(refs))
+	assert.Equal(t, "sha-v111", resolved[refs[2]].SHA)
+	// 120 refs take three queries of up to 50.
+	assert.Equal(t, int32(3), queries.Load())
+}
+
+func TestResolver_ResolveBatch_GraphQLUnavailable(t *testing.T) {
+	utils.CreateLogger(false)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/graphql":
+			// GraphQL needs a token.
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"message": "This endpoint requires you to be authenticated."}`))
+		case "/api/v3/repos/owner/repo/git/ref/tags/v1":
+			_ = json.NewEncoder(w).Encode(map[string]any{ //nolint:errchkjson
+				"ref":    "refs/tags/v1",
+				"object": map[string]string{"type": "commit", "sha": "sha-of-v1"},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	// An Enterprise Server's GraphQL API is under /api/graphql.
+	client, err := github.NewClient(nil).WithEnterpriseURLs(srv.URL, srv.URL)
+	require.NoError(t, err)
+	resolver := githubclient.NewResolver(client)
+
+	refs := []githubclient.RepoRef{{Owner: "owner", Repo: "repo", Ref: "v1"}, {Owner: "owner", Repo: "repo", Ref: "v2"}}
+	assert.Equal(t, 0, resolver.ResolveBatch(context.Background(), refs))
+
+	sha, err := resolver.ResolveRefToSHA(context.Background(), "owner", "repo", "v1")
+	require.NoError(t, err)
+	assert.Equal(t, "sha-of-v1", sha)
+}
@@ -0,0 +1,128 @@
+// SPDX-License-Identifier: MIT
+package githubclient
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// DefaultHost is the hostname of GitHub.com. Any other hostname is a GitHub Enterprise
+// Server instance, whose API is served under /api/v3 on the host itself.
+const DefaultHost = "github.com"
+
+// defaultAPIURL is the root of GitHub.com's REST API.
+const defaultAPIURL = "https://api.github.com/"
+
+// IsEnterprise reports whether a hostname refers to a GitHub Enterprise Server instance.
+//
+// - hostname: The hostname, as given to --hostname or GH_HOST.
+// Returns: true unless the hostname is empty or GitHub.com.
+func IsEnterprise(hostname string) bool {
+	return hostname != "" && !strings.EqualFold(hostname, DefaultHost)
+}
+
+// hostURL parses a hostname into the URL of the host. A bare hostname such as
+// "ghe.example.com" means https; a URL such as "http://127.0.0.1:8080" is used as it is,
+// which lets tests stand in for an instance with a local server.
+//
+// - hostname: The hostname or URL of the host.
+// Returns: The host's URL, and an error if the hostname is not valid or is plain http
+// to a host that isn't loopback (see checkScheme).
+func hostURL(hostname string) (*url.URL, error) {
+	raw := hostname
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid GitHub hostname %q", hostname)
+	}
+	if err := checkScheme(u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// checkScheme refuses plain http to any host but a loopback one, where it's only used
+// by local stand-ins for GitHub. Anywhere else, tokens would be sent in the clear.
+//
+// - u: The URL of the host or API.
+// Returns: An error if the URL is neither https nor http to a loopback host.
+func checkScheme(u *url.URL) error {
+	switch u.Scheme {
+	case "https":
+		return nil
+	case "http":
+		if isLoopback(u.Hostname()) {
+			return nil
+		}
+		return fmt.Errorf("refusing plain http to %s: use https, or http only for localhost", u.Host)
+	default:
+		return fmt.Errorf("unsupported scheme %q in %s: use https", u.Scheme, u)
+	}
+}
+
+// isLoopback reports whether a host is this machine, by name or by a loopback address.
+//
+// - host: The hostname or IP address, without a port.
+// Returns: true for "localhost" and loopback addresses such as 127.0.0.1 and ::1.
+func isLoopback(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// hostCacheDir returns the name of the cache subdirectory for a host, so cached
+// responses from different instances are kept apart.
+//
+// - u: The host's URL.
+// Returns: A directory name, with the port separator replaced.
+func hostCacheDir(u *url.URL) string {
+	return strings.ReplaceAll(strings.ToLower(u.Host), ":", "_")
+}
+
+// apiURL returns the root of a host's REST API: https://api.github.com/ for GitHub.com,
+// and the host's /api/v3/ for a GitHub Enterprise Server instance.
+//
+// - hostname: The hostname or URL of the host.
+// Returns: The API's URL, and an error if the hostname is not valid.
+func apiURL(hostname string) (*url.URL, error) {
+	if !IsEnterprise(hostname) {
+		return url.Parse(defaultAPIURL)
+	}
+	host, err := hostURL(hostname)
+	if err != nil {
+		return nil, err
+	}
+	api := *host
+	api.Path = strings.TrimSuffix(api.Path, "/")
+	if !strings.HasSuffix(api.Path, "/api/v3") {
+		api.Path += "/api/v3"
+	}
+	api.Path += "/"
+	return &api, nil
+}
+
+// parseBaseURL parses the root of a REST API given to NewClient, e.g.
+// "http://127.0.0.1:8080/". A trailing slash is added, as go-github requires.
+//
+// - baseURL: The API's URL.
+// Returns: The API's URL, and an error if it isn't an absolute URL or is plain http
+// to a host that isn't loopback (see checkScheme).
+func parseBaseURL(baseURL string) (*url.URL, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid GitHub API URL %q", baseURL)
+	}
+	if err := checkScheme(u); err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	return u, nil
+}
@@ -0,0 +1,93 @@
+// SPDX-License-Identifier: MIT
+
+package githubclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/esacteksab/gh-actlock/githubclient"
+	"github.com/esacteksab/gh-actlock/utils"
+)
+
+func TestNewClientForHost_Enterprise(t *testing.T) {
+	utils.CreateLogger(false)
+	cacheHome := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cacheHome)
+	t.Setenv("GITHUB_TOKEN", "github-com-token")
+	t.Setenv("GH_ENTERPRISE_TOKEN", "enterprise-token")
+
+	var auth []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/api/v3/rate_limit":
+			_ = json.NewEncoder(w).Encode(map[string]any{ //nolint:errchkjson
+				"resources": map[string]any{"core": map[string]int{"limit": 5000, "remaining": 4999}},
+			})
+		case "/api/v3/repos/internal/action":
+			_ = json.NewEncoder(w).Encode(map[string]string{"default_branch": "trunk"}) //nolint:errchkjson
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := githubclient.NewClientForHost(context.Background(), srv.URL)
+	require.NoError(t, err)
+	assert.Equal(t, srv.URL+"/api/v3/", client.BaseURL.String())
+
+	branch, err := githubclient.NewResolver(client).GetDefaultBranch(context.Background(), "internal", "action")
+	require.NoError(t, err)
+	assert.Equal(t, "trunk", branch)
+
+	// The enterprise token is sent to the instance, never the github.com one.
+	require.NotEmpty(t, auth)
+	for _, header := range auth {
+		assert.Equal(t, "Bearer enterprise-token", header)
+	}
+
+	// Responses are cached per host.
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	info, err := os.Stat(filepath.Join(cacheHome, "gh-actlock", strings.ReplaceAll(u.Host, ":", "_")))
+	require.NoError(t, err)
+	assert.True(t, info.IsDir())
+}
+
+func TestIsEnterprise(t *testing.T) {
+	assert.False(t, githubclient.IsEnterprise(""))
+	assert.False(t, githubclient.IsEnterprise("github.com"))
+	assert.False(t, githubclient.IsEnterprise("GitHub.com"))
+	assert.True(t, githubclient.IsEnterprise("ghe.example.com"))
+}
+
+func TestNewClient_PlainHTTP(t *testing.T) {
+	utils.CreateLogger(false)
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	ctx := context.Background()
+
+	// Tokens would cross the network in the clear.
+	_, err := githubclient.NewClient(ctx, "http://proxy.example.com/", githubclient.Credential{Token: "token"})
+	require.ErrorContains(t, err, "refusing plain http to proxy.example.com")
+	_, err = githubclient.NewClientForHost(ctx, "http://ghe.example.com")
+	require.ErrorContains(t, err, "refusing plain http to ghe.example.com")
+	_, err = githubclient.New(githubclient.WithBaseURL("ftp://proxy.example.com/"))
+	require.ErrorContains(t, err, "unsupported scheme")
+
+	// Loopback hosts are fine: they're local stand-ins for GitHub.
+	for _, host := range []string{"localhost", "127.0.0.1", "[::1]"} {
+		_, err := githubclient.NewOfflineClient(githubclient.DefaultHost, "http://"+host+":8080/")
+		require.NoError(t, err, host)
+	}
+}
@@ -0,0 +1,305 @@
+// SPDX-License-Identifier: MIT
+
+package githubclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/google/go-github/v82/github"
+)
+
+// Resolver wraps a GitHub client and remembers every lookup it makes for the rest of
+// the run, so a ref used by many workflows (e.g. actions/checkout@v4) is resolved
+// only once. It is safe for concurrent use: callers asking for the same lookup at the
+// same time wait for a single API call.
+type Resolver struct {
+	client    *github.Client
+	refs      memo[Resolution]              // "owner/repo@ref" -> resolution
+	latest    memo[Resolution]              // "owner/repo" -> latest release or tag
+	tags      memo[[]*github.RepositoryTag] // "owner/repo" -> every tag
+	tagIndex  memo[map[string][]string]     // "owner/repo" -> commit SHA -> tags pointing at it
+	branches  memo[string]                  // "owner/repo" -> default branch
+	heads     memo[[]*github.Branch]        // "owner/repo" -> every branch
+	files     memo[[]byte]                  // "owner/repo/path@ref" -> file contents
+	reachable memo[bool]                    // "owner/repo@sha" -> reachable from a branch or tag
+}
+
+// Resolution is a ref, the commit SHA it points to, and what kind of ref it is.
+type Resolution struct {
+	Ref  string  // The ref that was resolved, e.g. "v4"
+	SHA  string  // The full commit SHA
+	Kind RefKind // What the ref resolved through, empty if unknown
+}
+
+// NewResolver creates a Resolver with an empty memo.
+//
+// - client: The initialized GitHub client used for the lookups.
+// Returns: The new *Resolver.
+func NewResolver(client *github.Client) *Resolver {
+	return &Resolver{client: client}
+}
+
+// Client returns the GitHub client the resolver makes its lookups with.
+//
+// Returns: The wrapped *github.Client.
+func (r *Resolver) Client() *github.Client {
+	return r.client
+}
+
+// Resolve is the memoized form of the ResolveRef function.
+//
+// - ctx: The context for the API calls.
+// - owner: The owner of the GitHub repository.
+// - repo: The name of the GitHub repository.
+// - ref: The Git reference to resolve (e.g., "v4", "main", or a SHA).
+// Returns: The resolution, and an error if the ref can't be resolved.
+func (r *Resolver) Resolve(ctx context.Context, owner, repo, ref string) (Resolution, error) {
+	return r.refs.do(owner+"/"+repo+"@"+ref, func() (Resolution, error) {
+		sha, kind, err := ResolveRef(ctx, r.client, owner, repo, ref)
+		return Resolution{Ref: ref, SHA: sha, Kind: kind}, err
+	})
+}
+
+// ResolveRefToSHA is the memoized form of the ResolveRefToSHA function.
+//
+// - ctx: The context for the API calls.
+// - owner: The owner of the GitHub repository.
+// - repo: The name of the GitHub repository.
+// - ref: The Git reference to resolve (e.g., "v4", "main", or a SHA).
+// Returns: The full commit SHA, and an error if the ref can't be resolved.
+func (r *Resolver) ResolveRefToSHA(ctx context.Context, owner, repo, ref string) (string, error) {
+	res, err := r.Resolve(ctx, owner, repo, ref)
+	return res.SHA, err
+}
+
+// Latest is the memoized form of the GetLatestActionRef function. A ref taken from the
+// latest release has KindRelease; one taken from the tag list is looked up to learn its kind.
+//
+// - ctx: The context for the API calls.
+// - owner: The owner of the GitHub repository.
+// - repo: The name of the GitHub repository.
+// Returns: The resolution of the latest release or tag, and an error if neither can be found.
+func (r *Resolver) Latest(ctx context.Context, owner, repo string) (Resolution, error) {
+	return r.latest.do(owner+"/"+repo, func() (Resolution, error) {
+		ref, sha, kind, err := latestActionRef(ctx, r.client, owner, repo)
+		if err != nil {
+			return Resolution{}, err
+		}
+		return r.withKind(ctx, owner, repo, Resolution{Ref: ref, SHA: sha, Kind: kind}), nil
+	})
+}
+
+// GetLatestActionRef is the memoized form of the GetLatestActionRef function.
+//
+// - ctx: The context for the API calls.
+// - owner: The owner of the GitHub repository.
+// - repo: The name of the GitHub repository.
+// Returns: The latest release or tag name, its commit SHA, and an error if neither can be found.
+func (r *Resolver) GetLatestActionRef(ctx context.Context, owner, repo string) (string, string, error) {
+	res, err := r.Latest(ctx, owner, repo)
+	return res.Ref, res.SHA, err
+}
+
+// GetDefaultBranch retrieves the default branch of a repository.
+//
+// - ctx: The context for the API calls.
+// - owner: The owner of the GitHub repository.
+// - repo: The name of the GitHub repository.
+// Returns: The default branch name, and an error if it can't be determined.
+func (r *Resolver) GetDefaultBranch(ctx context.Context, owner, repo string) (string, error) {
+	return r.branches.do(owner+"/"+repo, func() (string, error) {
+		repoInfo, _, err := r.client.Repositories.Get(ctx, owner, repo)
+		if err != nil {
+			return "", fmt.Errorf(
+				"error getting repository info for %s/%s to find default branch: %w", owner, repo, err)
+		}
+		// DefaultBranch is a pointer and could be nil, or could point to an empty string
+		if repoInfo.GetDefaultBranch() == "" {
+			return "", fmt.Errorf("could not determine default branch for %s/%s", owner, repo)
+		}
+		return repoInfo.GetDefaultBranch(), nil
+	})
+}
+
+// GetFileContent is the memoized form of the GetFileContent function.
+//
+// - ctx: The context for the API calls.
+// - owner: The owner of the GitHub repository.
+// - repo: The name of the GitHub repository.
+// - path: The path of the file in the repository.
+// - ref: The commit SHA, tag, or branch to read the file at.
+// Returns: The file contents, and an error (wrapping ErrFileNotFound if there is no such file).
+func (r *Resolver) GetFileContent(ctx context.Context, owner, repo, path, ref string) ([]byte, error) {
+	return r.files.do(owner+"/"+repo+"/"+path+"@"+ref, func() ([]byte, error) {
+		return GetFileContent(ctx, r.client, owner, repo, path, ref)
+	})
+}
+
+// Update finds the ref update mode should move to under the given policy. It is the
+// memoized form of the GetUpdateRef function, also reporting the kind of the ref.
+//
+// - ctx: The context for API calls.
+// - owner: The owner of the GitHub repository.
+// - repo: The name of the GitHub repository.
+// - current: The ref currently tracked, may be empty for the tracked and latest policies.
+// - policy: The update policy to apply.
+// Returns: The resolution of the ref to update to, and an error if none can be found.
+func (r *Resolver) Update(
+	ctx context.Context,
+	owner, repo, current string,
+	policy UpdatePolicy,
+) (Resolution, error) {
+	switch {
+	case policy == UpdateLatest:
+		return r.Latest(ctx, owner, repo)
+	case policy == UpdateTracked && current == "":
+		// A SHA without a comment doesn't say what it tracks, so fall back to the latest release.
+		loggerFor(r.client).Debugf("No tracked ref for %s/%s, using the latest release", owner, repo)
+		return r.Latest(ctx, owner, repo)
+	case policy == UpdateTracked:
+		return r.Resolve(ctx, owner, repo, current)
+	}
+	ref, sha, err := r.GetLatestSemverRef(ctx, owner, repo, current, policy)
+	if err != nil {
+		return Resolution{}, err
+	}
+	return r.withKind(ctx, owner, repo, Resolution{Ref: ref, SHA: sha}), nil
+}
+
+// GetUpdateRef is the memoized form of the GetUpdateRef function.
+//
+// - ctx: The context for API calls.
+// - owner: The owner of the GitHub repository.
+// - repo: The name of the GitHub repository.
+// - current: The ref currently tracked, may be empty for the tracked and latest policies.
+// - policy: The update policy to apply.
+// Returns: The tag or release to update to, its commit SHA, and an error if none can be found.
+func (r *Resolver) GetUpdateRef(
+	ctx context.Context,
+	owner, repo, current string,
+	policy UpdatePolicy,
+) (string, string, error) {
+	res, err := r.Update(ctx, owner, repo, current, policy)
+	if err != nil {
+		return "", "", err
+	}
+	return res.Ref, res.SHA, nil
+}
+
+// withKind fills in the kind of a tag taken from the tag list by resolving it. The
+// kind stays empty if the tag can't be resolved to the same commit.
+//
+// - ctx: The context for API calls.
+// - owner: The owner of the GitHub repository.
+// - repo: The name of the GitHub repository.
+// - res: The resolution to complete.
+// Returns: The resolution, with Kind set when it could be determined.
+func (r *Resolver) withKind(ctx context.Context, owner, repo string, res Resolution) Resolution {
+	if res.Kind != "" {
+		return res
+	}
+	if tag, err := r.Resolve(ctx, owner, repo, res.Ref); err == nil && tag.SHA == res.SHA {
+		res.Kind = tag.Kind
+	}
+	return res
+}
+
+// GetLatestSemverRef is the memoized form of the GetLatestSemverRef function. The tags
+// of each repository are listed once and shared by every policy and current version.
+//
+// - ctx: The context for API calls.
+// - owner: The owner of the GitHub repository.
+// - repo: The name of the GitHub repository.
+// - current: The version currently in use (e.g., "v4" or "v4.1.0").
+// - policy: UpdatePatch, UpdateMinor, or UpdateMajor.
+// Returns: The selected tag name, its commit SHA, and an error if no tag matches.
+func (r *Resolver) GetLatestSemverRef(
+	ctx context.Context,
+	owner, repo, current string,
+	policy UpdatePolicy,
+) (string, string, error) {
+	if _, ok := parseVersion(current); !ok {
+		return "", "", fmt.Errorf(
+			"cannot apply %s update policy to %s/%s: '%s' is not a semantic version",
+			policy, owner, repo, current)
+	}
+
+	tags, err := r.tags.do(owner+"/"+repo, func() ([]*github.RepositoryTag, error) {
+		return listAllTags(ctx, r.client, owner, repo)
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	tag, err := selectSemverTag(tags, current, policy)
+	if err != nil {
+		return "", "", fmt.Errorf("%s/%s: %w", owner, repo, err)
+	}
+	return tag.GetName(), tag.GetCommit().GetSHA(), nil
+}
+
+// memo remembers the result of a lookup per key. Failures that can't change are
+// remembered too, so a ref that doesn't exist isn't looked up again for every reference
+// to it; any other failure, such as a rate limit or a server error, is retried by the
+// next caller (see permanentError).
+type memo[V any] struct {
+	mu    sync.Mutex
+	calls map[string]*memoCall[V]
+}
+
+// memoCall is a single memoized lookup.
+type memoCall[V any] struct {
+	done chan struct{} // Closed once val and err are set
+	val  V
+	err  error
+}
+
+// do returns the remembered result for key, calling fn to produce it the first time.
+// Concurrent callers with the same key wait for the first call to finish, and share
+// its result even if it failed; only later callers retry a transient failure.
+//
+// - key: The lookup key.
+// - fn: The lookup to perform.
+// Returns: The result of the lookup.
+func (m *memo[V]) do(key string, fn func() (V, error)) (V, error) {
+	m.mu.Lock()
+	if m.calls == nil {
+		m.calls = make(map[string]*memoCall[V])
+	}
+	if call, ok := m.calls[key]; ok {
+		m.mu.Unlock()
+		<-call.done
+		return call.val, call.err
+	}
+	call := &memoCall[V]{done: make(chan struct{})}
+	m.calls[key] = call
+	m.mu.Unlock()
+
+	call.val, call.err = fn()
+	if call.err != nil && !permanentError(call.err) {
+		m.mu.Lock()
+		delete(m.calls, key)
+		m.mu.Unlock()
+	}
+	close(call.done)
+	return call.val, call.err
+}
+
+// permanentError reports whether a failed lookup would fail the same way if repeated:
+// the ref, commit, or file doesn't exist, or isn't available offline.
+//
+// - err: The error of the lookup.
+// Returns: true if the error can be remembered.
+func permanentError(err error) bool {
+	if errors.Is(err, ErrRefNotFound) || errors.Is(err, ErrCommitNotFound) ||
+		errors.Is(err, ErrFileNotFound) || errors.Is(err, ErrOffline) {
+		return true
+	}
+	var errResp *github.ErrorResponse
+	return errors.As(err, &errResp) && errResp.Response != nil &&
+		errResp.Response.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,135 @@
+// SPDX-License-Identifier: MIT
+
+package githubclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-github/v82/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/esacteksab/gh-actlock/githubclient"
+	"github.com/esacteksab/gh-actlock/utils"
+)
+
+func TestResolver_Memoizes(t *testing.T) {
+	utils.CreateLogger(false)
+
+	var tagRequests, refRequests, repoRequests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if tag, ok := strings.CutPrefix(r.URL.Path, "/repos/owner/repo/git/ref/tags/"); ok {
+			refRequests.Add(1)
+			_ = json.NewEncoder(w).Encode(map[string]any{ //nolint:errchkjson
+				"ref":    "refs/tags/" + tag,
+				"object": map[string]string{"type": "commit", "sha": "sha-" + tag},
+			})
+			return
+		}
+		switch r.URL.Path {
+		case "/repos/owner/repo/tags":
+			tagRequests.Add(1)
+			_ = json.NewEncoder(w).Encode([]map[string]any{ //nolint:errchkjson
+				{"name": "v1.0.0", "commit": map[string]string{"sha": "sha-v1.0.0"}},
+				{"name": "v1.1.0", "commit": map[string]string{"sha": "sha-v1.1.0"}},
+				{"name": "v2.0.0", "commit": map[string]string{"sha": "sha-v2.0.0"}},
+			})
+		case "/repos/owner/repo":
+			repoRequests.Add(1)
+			_ = json.NewEncoder(w).Encode(map[string]string{"default_branch": "main"}) //nolint:errchkjson
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	client := github.NewClient(nil)
+	baseURL, err := url.Parse(srv.URL + "/")
+	require.NoError(t, err)
+	client.BaseURL = baseURL
+	resolver := githubclient.NewResolver(client)
+
+	// Concurrent lookups share one listing of the tags and one repository request.
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			res, err := resolver.Update(
+				context.Background(), "owner", "repo", "v1.0.0", githubclient.UpdateMinor)
+			assert.NoError(t, err)
+			assert.Equal(t, githubclient.Resolution{
+				Ref:  "v1.1.0",
+				SHA:  "sha-v1.1.0",
+				Kind: githubclient.KindLightweightTag,
+			}, res)
+
+			branch, err := resolver.GetDefaultBranch(context.Background(), "owner", "repo")
+			assert.NoError(t, err)
+			assert.Equal(t, "main", branch)
+		})
+	}
+	wg.Wait()
+
+	// A different policy reuses the tags already listed.
+	ref, _, err := resolver.GetUpdateRef(context.Background(), "owner", "repo", "v1.0.0", githubclient.UpdateMajor)
+	require.NoError(t, err)
+	assert.Equal(t, "v2.0.0", ref)
+
+	assert.Equal(t, int32(1), tagRequests.Load())
+	assert.Equal(t, int32(2), refRequests.Load()) // One per selected tag
+	assert.Equal(t, int32(1), repoRequests.Load())
+}
+
+func TestResolver_RetriesTransientErrors(t *testing.T) {
+	utils.CreateLogger(false)
+
+	var repoRequests, missingRequests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo":
+			// The first request hits an outage.
+			if repoRequests.Add(1) == 1 {
+				http.Error(w, "unavailable", http.StatusBadGateway)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{"default_branch": "main"}) //nolint:errchkjson
+		case "/repos/owner/missing":
+			missingRequests.Add(1)
+			http.NotFound(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	client := github.NewClient(nil)
+	baseURL, err := url.Parse(srv.URL + "/")
+	require.NoError(t, err)
+	client.BaseURL = baseURL
+	resolver := githubclient.NewResolver(client)
+	ctx := context.Background()
+
+	// A server error isn't remembered: the next lookup tries again.
+	_, err = resolver.GetDefaultBranch(ctx, "owner", "repo")
+	require.Error(t, err)
+	branch, err := resolver.GetDefaultBranch(ctx, "owner", "repo")
+	require.NoError(t, err)
+	assert.Equal(t, "main", branch)
+	_, err = resolver.GetDefaultBranch(ctx, "owner", "repo")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), repoRequests.Load())
+
+	// A repository that doesn't exist is only asked about once.
+	for range 2 {
+		_, err = resolver.GetDefaultBranch(ctx, "owner", "missing")
+		require.Error(t, err)
+	}
+	assert.Equal(t, int32(1), missingRequests.Load())
+}
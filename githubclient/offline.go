@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: MIT
+package githubclient
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/google/go-github/v82/github"
+
+	"github.com/esacteksab/httpcache"
+)
+
+// ErrOffline is returned for a request an offline client can't serve from the cache.
+var ErrOffline = errors.New("not in the offline cache")
+
+// offlineTransport serves requests from the HTTP cache, however old the cached
+// response is, and never opens a connection.
+type offlineTransport struct {
+	cache httpcache.Cache // The cache filled by clients from NewClientForHost
+}
+
+// RoundTrip returns the cached response to a request.
+//
+// - req: The HTTP request.
+// Returns: The cached response, or an error wrapping ErrOffline if there is none.
+func (t *offlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return nil, ErrOffline
+	}
+	// The response is used whatever its Vary headers say: it was cached for the same
+	// user, possibly with a token that has expired since.
+	resp, err := httpcache.CachedResponse(t.cache, req)
+	if err != nil || resp == nil {
+		return nil, ErrOffline
+	}
+	resp.Header.Set(httpcache.XFromCache, "1")
+	return resp, nil
+}
+
+// NewOfflineClient returns a GitHub API client for a host that answers every request
+// from the responses NewClientForHost (or NewClient, given a base URL) cached for it,
+// and fails with ErrOffline when a response isn't cached. It never opens a connection,
+// so it doesn't authenticate or check the rate limit either.
+//
+// Deprecated: Use New with WithHost or WithBaseURL, WithCache(HostCache(...)), and
+// WithOffline.
+//
+// - hostname: The host, e.g. "github.com" or "ghe.example.com".
+// - baseURL: The root of the REST API given to NewClient, or "" for the host's own API.
+// - opts: Options for New; only WithLogger applies, as nothing is sent.
+// Returns: The client, and an error if the cache directory can't be determined.
+func NewOfflineClient(hostname, baseURL string, opts ...Option) (*github.Client, error) {
+	// Responses from a base URL are cached by its host, see NewClient.
+	target, cacheHost := WithHost(hostname), hostname
+	if baseURL != "" {
+		target, cacheHost = WithBaseURL(baseURL), baseURL
+	}
+	cache, err := HostCache(cacheHost)
+	if err != nil {
+		return nil, err
+	}
+	return New(append(slices.Clip(opts), target, WithCache(cache), WithOffline())...)
+}
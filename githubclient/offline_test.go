@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: MIT
+
+package githubclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/esacteksab/gh-actlock/githubclient"
+	"github.com/esacteksab/gh-actlock/utils"
+)
+
+func TestNewOfflineClient(t *testing.T) {
+	utils.CreateLogger(false)
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("GH_ENTERPRISE_TOKEN", "enterprise-token")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/rate_limit":
+			_ = json.NewEncoder(w).Encode(map[string]any{ //nolint:errchkjson
+				"resources": map[string]any{"core": map[string]int{"limit": 5000, "remaining": 4999}},
+			})
+		case "/api/v3/repos/internal/action":
+			// Vary on the token, as GitHub does; offline lookups are made without one.
+			w.Header().Set("Vary", "Accept, Authorization")
+			w.Header().Set("Cache-Control", "private, max-age=60")
+			_ = json.NewEncoder(w).Encode(map[string]string{"default_branch": "trunk"}) //nolint:errchkjson
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	host := srv.URL
+
+	// Fill the cache while online.
+	online, err := githubclient.NewClientForHost(context.Background(), host)
+	require.NoError(t, err)
+	branch, err := githubclient.NewResolver(online).GetDefaultBranch(context.Background(), "internal", "action")
+	require.NoError(t, err)
+	assert.Equal(t, "trunk", branch)
+	srv.Close()
+
+	offline, err := githubclient.NewOfflineClient(host, "")
+	require.NoError(t, err)
+	resolver := githubclient.NewResolver(offline)
+
+	branch, err = resolver.GetDefaultBranch(context.Background(), "internal", "action")
+	require.NoError(t, err)
+	assert.Equal(t, "trunk", branch)
+
+	// A lookup that was never made fails, rather than reaching for the network.
+	_, err = resolver.Resolve(context.Background(), "internal", "action", "v1")
+	require.ErrorIs(t, err, githubclient.ErrOffline)
+	_, err = resolver.GetDefaultBranch(context.Background(), "internal", "other")
+	require.ErrorIs(t, err, githubclient.ErrOffline)
+}
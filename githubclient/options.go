@@ -0,0 +1,268 @@
+// SPDX-License-Identifier: MIT
+package githubclient
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/google/go-github/v82/github"
+	"golang.org/x/oauth2"
+
+	"github.com/esacteksab/httpcache"
+)
+
+// Option configures a client created by New.
+type Option func(*clientOptions)
+
+// clientOptions holds what the options given to New set.
+type clientOptions struct {
+	tokenSource     oauth2.TokenSource // Authenticates requests, nil for none
+	tokenSourceName string             // Where the token comes from, for the logs
+	baseURL         string             // The root of the REST API, "" for GitHub.com's
+	host            string             // The host whose API is used instead of baseURL, see WithHost
+	offline         bool               // Whether requests are only answered from the cache
+	cache           httpcache.Cache    // Caches responses, nil for none
+	transport       http.RoundTripper  // Sends the requests, nil for http.DefaultTransport
+	logger          *log.Logger        // Where the client logs, nil for the default logger
+	userAgent       string             // The User-Agent header, "" for go-github's
+	maxWait         time.Duration      // The total time rate limits are waited out for
+}
+
+// WithTokenSource authenticates the client's requests with the tokens of a source, e.g.
+// oauth2.StaticTokenSource for a personal access token, or NewAppTokenSource. Tokens are
+// reused until they expire. Without it, the client is unauthenticated.
+//
+// - source: The token source.
+// Returns: The option.
+func WithTokenSource(source oauth2.TokenSource) Option {
+	return func(o *clientOptions) {
+		o.tokenSource = source
+		if o.tokenSourceName == "" {
+			o.tokenSourceName = "the given token source"
+		}
+	}
+}
+
+// withTokenSourceName says where the token of WithTokenSource comes from, e.g.
+// "GITHUB_TOKEN", for the logs.
+//
+// - name: Where the token comes from.
+// Returns: The option.
+func withTokenSourceName(name string) Option {
+	return func(o *clientOptions) {
+		o.tokenSourceName = name
+	}
+}
+
+// WithBaseURL points the client at another REST API than GitHub.com's, such as a GitHub
+// Enterprise Server instance's ("https://ghe.example.com/api/v3/") or the fake server
+// in githubtest.
+//
+// - baseURL: The root of the REST API.
+// Returns: The option.
+func WithBaseURL(baseURL string) Option {
+	return func(o *clientOptions) {
+		o.baseURL = baseURL
+		o.host = ""
+	}
+}
+
+// WithHost points the client at the REST API of a host: api.github.com for
+// "github.com", and https://<host>/api/v3/ for a GitHub Enterprise Server instance.
+//
+// - hostname: The host, e.g. "github.com" or "ghe.example.com".
+// Returns: The option.
+func WithHost(hostname string) Option {
+	return func(o *clientOptions) {
+		o.host = hostname
+	}
+}
+
+// WithCredential authenticates the client's requests with a token found by, e.g.,
+// FindCredential. A credential without a token leaves the client unauthenticated.
+//
+// - credential: The token, and where it comes from for the logs.
+// Returns: The option.
+func WithCredential(credential Credential) Option {
+	return func(o *clientOptions) {
+		if credential.Token == "" {
+			return
+		}
+		o.tokenSource = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: credential.Token})
+		o.tokenSourceName = credential.Source
+	}
+}
+
+// WithOffline makes the client answer every request from the responses in its cache
+// (see WithCache), however old, and fail with ErrOffline when a response isn't cached.
+// It never opens a connection, so the token source, transport, and rate limit waits
+// don't apply.
+//
+// Returns: The option.
+func WithOffline() Option {
+	return func(o *clientOptions) {
+		o.offline = true
+	}
+}
+
+// WithCache caches the client's responses, honoring GitHub's caching headers, e.g. in a
+// diskcache.Cache or an httpcache.MemoryCache. Without it, nothing is cached.
+//
+// - cache: The cache, nil for none.
+// Returns: The option.
+func WithCache(cache httpcache.Cache) Option {
+	return func(o *clientOptions) {
+		o.cache = cache
+	}
+}
+
+// WithTransport sends the client's requests with a transport of its own, e.g. one
+// going through a proxy. Authentication, caching, and rate limit waits are layered on
+// top of it.
+//
+// - transport: The transport, nil for http.DefaultTransport.
+// Returns: The option.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *clientOptions) {
+		o.transport = transport
+	}
+}
+
+// WithLogger makes the client, and the lookups made with it, log to a logger of its
+// own instead of the package's default one.
+//
+// - logger: The logger.
+// Returns: The option.
+func WithLogger(logger *log.Logger) Option {
+	return func(o *clientOptions) {
+		o.logger = logger
+	}
+}
+
+// WithUserAgent sets the User-Agent header of the client's requests, so that GitHub
+// can tell the program making them apart.
+//
+// - userAgent: The User-Agent, e.g. "my-tool/1.0".
+// Returns: The option.
+func WithUserAgent(userAgent string) Option {
+	return func(o *clientOptions) {
+		o.userAgent = userAgent
+	}
+}
+
+// WithMaxWait sets how long the client waits out rate limits, in total, before giving
+// up and returning GitHub's rate limit error. Zero never waits. Without it, the client
+// waits for up to DefaultMaxWait.
+//
+// - maxWait: The total time requests may be paused for.
+// Returns: The option.
+func WithMaxWait(maxWait time.Duration) Option {
+	return func(o *clientOptions) {
+		o.maxWait = maxWait
+	}
+}
+
+// New creates a GitHub API client configured by options alone. It reads no
+// environment variables, credentials, or directories, and makes no requests, which
+// makes it the constructor for programs using actlock as a library. Without options,
+// the client talks to GitHub.com unauthenticated and caches nothing.
+//
+// The command finds the options with HostCache and HostCredentials, and checks the
+// rate limit with ReportRateLimit once the client is created.
+//
+// - opts: The options, see WithHost, WithBaseURL, WithTokenSource, WithCredential,
+// WithCache, WithOffline, WithTransport, WithLogger, WithUserAgent, and WithMaxWait.
+// Returns: The client, and an error if the base URL or host is invalid, or if the
+// client is offline without a cache.
+func New(opts ...Option) (*github.Client, error) {
+	o := resolveOptions(opts)
+	var api *url.URL
+	var err error
+	if o.host != "" {
+		api, err = apiURL(o.host)
+	} else {
+		api, err = parseBaseURL(o.baseURL)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if api.String() != defaultAPIURL {
+		o.logger.Debugf("Using the GitHub API at %s", api)
+	}
+
+	if o.offline {
+		if o.cache == nil {
+			return nil, errors.New("an offline client needs a cache to answer from")
+		}
+		transport := &offlineTransport{cache: o.cache}
+		return newAPIClient(&http.Client{Transport: &CachingTransport{Transport: transport, Logger: o.logger}}, api), nil
+	}
+
+	// Requests that miss the cache wait out rate limits rather than failing.
+	rateLimitTransport := NewRateLimitTransport(o.transport, o.maxWait)
+	rateLimitTransport.Logger = o.logger
+	var transport http.RoundTripper = rateLimitTransport
+	if o.cache != nil {
+		cacheTransport := httpcache.NewTransport(o.cache)
+		cacheTransport.Transport = transport
+		transport = cacheTransport
+	}
+	sourceName := ""
+	if o.tokenSource != nil {
+		transport = &oauth2.Transport{
+			Base:   transport,
+			Source: oauth2.ReuseTokenSource(nil, o.tokenSource),
+		}
+		sourceName = o.tokenSourceName
+	}
+	httpClient := &http.Client{
+		Transport: &CachingTransport{Transport: transport, TokenSource: sourceName, Logger: o.logger},
+	}
+
+	client := newAPIClient(httpClient, api)
+	if o.userAgent != "" {
+		client.UserAgent = o.userAgent
+	}
+	// The transport waits out rate limits, so go-github mustn't refuse requests on its own.
+	client.DisableRateLimitCheck = true
+	return client, nil
+}
+
+// resolveOptions applies options to the defaults New starts from.
+//
+// - opts: The options.
+// Returns: The options set, with the default logger if none was given.
+func resolveOptions(opts []Option) clientOptions {
+	o := clientOptions{baseURL: defaultAPIURL, maxWait: DefaultMaxWait}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.logger == nil {
+		o.logger = defaultLogger()
+	}
+	return o
+}
+
+// defaultLogger returns the logger used when none is given: charmbracelet/log's
+// default logger.
+//
+// Returns: The logger.
+func defaultLogger() *log.Logger {
+	return log.Default()
+}
+
+// loggerFor returns the logger a client logs to, see WithLogger.
+//
+// - client: The client.
+// Returns: The logger given to New, or the default logger.
+func loggerFor(client *github.Client) *log.Logger {
+	if client != nil {
+		if transport, ok := client.Client().Transport.(*CachingTransport); ok && transport.Logger != nil {
+			return transport.Logger
+		}
+	}
+	return defaultLogger()
+}
@@ -0,0 +1,112 @@
+// SPDX-License-Identifier: MIT
+
+package githubclient_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/charmbracelet/log"
+	"github.com/esacteksab/httpcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+
+	"github.com/esacteksab/gh-actlock/githubclient"
+	"github.com/esacteksab/gh-actlock/githubclient/githubtest"
+)
+
+// headerRecorder is a transport that remembers the headers of the last request it sent.
+type headerRecorder struct {
+	header http.Header
+}
+
+func (h *headerRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	h.header = req.Header.Clone()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestNew_Options(t *testing.T) {
+	// New reads no environment: the token in it must not be sent.
+	t.Setenv("GITHUB_TOKEN", "token-from-the-environment")
+
+	srv := githubtest.NewServer()
+	defer srv.Close()
+	require.NoError(t, srv.AddRepo("actions/checkout", githubtest.Repo{
+		Commits: []string{"11d5960a326750d5838078e36cf38b85af677262"},
+	}))
+
+	var logs bytes.Buffer
+	recorder := &headerRecorder{}
+	client, err := githubclient.New(
+		githubclient.WithBaseURL(srv.APIURL()),
+		githubclient.WithTokenSource(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "given-token"})),
+		githubclient.WithCache(httpcache.NewMemoryCache()),
+		githubclient.WithTransport(recorder),
+		githubclient.WithLogger(log.New(&logs)),
+		githubclient.WithUserAgent("my-tool/1.0"),
+	)
+	require.NoError(t, err)
+	require.Zero(t, srv.Requests(), "New makes no requests")
+
+	assert.Equal(t, "authenticated", githubclient.ReportRateLimit(context.Background(), client))
+	assert.Contains(t, logs.String(), "(token from the given token source)")
+	assert.Equal(t, "Bearer given-token", recorder.header.Get("Authorization"))
+	assert.Equal(t, "my-tool/1.0", recorder.header.Get("User-Agent"))
+
+	// The rate limit check is the only request made so far.
+	require.Equal(t, 1, srv.Requests())
+	for range 2 {
+		repo, _, err := client.Repositories.Get(context.Background(), "actions", "checkout")
+		require.NoError(t, err)
+		assert.Equal(t, "main", repo.GetDefaultBranch())
+	}
+	assert.Equal(t, 2, srv.Requests(), "the second lookup should be served from the cache")
+}
+
+func TestNew_Defaults(t *testing.T) {
+	srv := githubtest.NewServer()
+	defer srv.Close()
+
+	var logs bytes.Buffer
+	recorder := &headerRecorder{}
+	client, err := githubclient.New(
+		githubclient.WithBaseURL(srv.APIURL()),
+		githubclient.WithTransport(recorder),
+		githubclient.WithLogger(log.New(&logs)),
+	)
+	require.NoError(t, err)
+	githubclient.ReportRateLimit(context.Background(), client)
+
+	assert.Empty(t, recorder.header.Get("Authorization"), "no token source means no authentication")
+	assert.Contains(t, recorder.header.Get("User-Agent"), "go-github")
+	assert.Contains(t, logs.String(), "Unauthenticated")
+}
+
+func TestNew_InvalidBaseURL(t *testing.T) {
+	_, err := githubclient.New(githubclient.WithBaseURL("://not a url"))
+	require.Error(t, err)
+}
+
+func TestNew_Host(t *testing.T) {
+	client, err := githubclient.New(githubclient.WithHost("ghe.example.com"))
+	require.NoError(t, err)
+	assert.Equal(t, "https://ghe.example.com/api/v3/", client.BaseURL.String())
+
+	// The last of WithHost and WithBaseURL wins.
+	client, err = githubclient.New(githubclient.WithHost("ghe.example.com"), githubclient.WithBaseURL("https://proxy.example.com/"))
+	require.NoError(t, err)
+	assert.Equal(t, "https://proxy.example.com/", client.BaseURL.String())
+}
+
+func TestNew_Offline(t *testing.T) {
+	_, err := githubclient.New(githubclient.WithOffline())
+	require.ErrorContains(t, err, "needs a cache")
+
+	client, err := githubclient.New(githubclient.WithOffline(), githubclient.WithCache(httpcache.NewMemoryCache()))
+	require.NoError(t, err)
+	_, err = githubclient.NewResolver(client).GetDefaultBranch(context.Background(), "actions", "checkout")
+	require.ErrorIs(t, err, githubclient.ErrOffline)
+}
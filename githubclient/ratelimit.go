@@ -0,0 +1,237 @@
+// SPDX-License-Identifier: MIT
+package githubclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/google/go-github/v82/github"
+)
+
+// DefaultMaxWait is how long a run waits out rate limits, in total, unless told otherwise.
+const DefaultMaxWait = 5 * time.Minute
+
+const (
+	// secondaryLimitBackoff is how long to wait after a secondary rate limit that doesn't
+	// say when to retry. GitHub asks for at least a minute, doubled on every retry.
+	secondaryLimitBackoff = time.Minute
+	// maxRateLimitRetries caps the retries of a single request.
+	maxRateLimitRetries = 5
+	// resetBuffer is added to the primary rate limit's reset time, for clock skew.
+	resetBuffer = time.Second
+)
+
+// RateLimitTransport waits out GitHub's rate limits instead of failing. When a response
+// says a primary rate limit is exhausted (X-RateLimit-Remaining: 0) or a secondary one
+// was hit (Retry-After, or a 403/429 mentioning it), every request made through the
+// transport is paused until the limit should have lifted, plus some jitter so that
+// concurrent requests don't all retry at once, and the request is retried.
+//
+// The pauses share a budget: once waiting would exceed MaxWait in total, the rate
+// limited response is returned as it is, for go-github to turn into an error.
+type RateLimitTransport struct {
+	Transport http.RoundTripper // The underlying transport; http.DefaultTransport if nil
+	MaxWait   time.Duration     // The total time requests may be paused for
+	Logger    *log.Logger       // Where waits are logged; the default logger if nil
+
+	mu       sync.Mutex
+	resumeAt time.Time     // Requests are paused until then
+	waited   time.Duration // How long requests have been paused for so far
+
+	now   func() time.Time                                 // The clock, replaced in tests
+	sleep func(ctx context.Context, d time.Duration) error // Waits, replaced in tests
+}
+
+// NewRateLimitTransport returns a RateLimitTransport.
+//
+// - transport: The underlying transport, nil for http.DefaultTransport.
+// - maxWait: The total time requests may be paused for.
+// Returns: The transport.
+func NewRateLimitTransport(transport http.RoundTripper, maxWait time.Duration) *RateLimitTransport {
+	return &RateLimitTransport{Transport: transport, MaxWait: maxWait, now: time.Now, sleep: sleepContext}
+}
+
+// RoundTrip sends a request, retrying it when it was rejected by a rate limit and the
+// wait fits in the budget.
+//
+// - req: The HTTP request to execute.
+// Returns: The HTTP response and an error, if any.
+func (t *RateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := t.waitForResume(req.Context()); err != nil {
+			return nil, err
+		}
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+
+		wait, limit := t.rateLimitDelay(resp, attempt)
+		if limit == "" {
+			return resp, nil
+		}
+		// A request whose body can't be sent again can't be retried.
+		retryable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+		if !retryable || attempt >= maxRateLimitRetries || !t.pause(wait) {
+			t.logger().Errorf("❌  GitHub %s rate limit reached for %s; not waiting any longer", limit, req.URL.Path)
+			return resp, nil
+		}
+		t.logger().Printf("⏳  GitHub %s rate limit reached; waiting %s before retrying", limit, wait.Round(time.Second))
+
+		// Drain the body so the connection can be reused.
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// logger returns where the transport logs.
+//
+// Returns: The transport's Logger, or the default logger.
+func (t *RateLimitTransport) logger() *log.Logger {
+	if t.Logger != nil {
+		return t.Logger
+	}
+	return defaultLogger()
+}
+
+// rateLimitDelay works out whether a response was rejected by a rate limit, and how
+// long to wait before retrying.
+//
+// - resp: The response. Its body is read, and replaced, only for a 403 that may be a secondary limit.
+// - attempt: How many times the request was retried already.
+// Returns: The wait, with jitter, and "primary" or "secondary", or "" if the response wasn't rate limited.
+func (t *RateLimitTransport) rateLimitDelay(resp *http.Response, attempt int) (time.Duration, string) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, ""
+	}
+
+	// A secondary limit may say how many seconds to wait.
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs >= 0 {
+		return withJitter(time.Duration(secs) * time.Second), "secondary"
+	}
+
+	// A primary limit says when it resets.
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			wait := max(time.Unix(reset, 0).Sub(t.now()), 0) + resetBuffer
+			return withJitter(wait), "primary"
+		}
+	}
+
+	// Otherwise, only the message tells a secondary limit from a permission error.
+	if resp.StatusCode == http.StatusForbidden {
+		body, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		if err != nil || !strings.Contains(strings.ToLower(string(body)), "secondary rate limit") {
+			return 0, ""
+		}
+	}
+	return withJitter(secondaryLimitBackoff << attempt), "secondary"
+}
+
+// pause extends the pause of every request to cover a wait, if it fits in the budget.
+//
+// - wait: How long from now requests must wait.
+// Returns: false if the wait would exceed MaxWait.
+func (t *RateLimitTransport) pause(wait time.Duration) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	until := now.Add(wait)
+	from := t.resumeAt
+	if from.Before(now) {
+		from = now
+	}
+	if !until.After(from) {
+		// Another request is already pausing for longer.
+		return true
+	}
+	added := until.Sub(from)
+	if t.waited+added > t.MaxWait {
+		return false
+	}
+	t.waited += added
+	t.resumeAt = until
+	return true
+}
+
+// waitForResume waits until requests are no longer paused.
+//
+// - ctx: The request's context, which ends the wait early.
+// Returns: The context's error if it ended first.
+func (t *RateLimitTransport) waitForResume(ctx context.Context) error {
+	t.mu.Lock()
+	wait := t.resumeAt.Sub(t.now())
+	t.mu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+	return t.sleep(ctx, wait)
+}
+
+// withJitter adds up to a quarter of a wait, and at least a little, to it.
+//
+// - d: The wait.
+// Returns: The wait with jitter.
+func withJitter(d time.Duration) time.Duration {
+	return d + rand.N(d/4+100*time.Millisecond) //nolint:gosec,mnd
+}
+
+// sleepContext sleeps for a duration or until the context ends.
+//
+// - ctx: The context.
+// - d: How long to sleep.
+// Returns: The context's error if it ended first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// IsRateLimitError reports whether an API call failed because of a rate limit.
+//
+// - err: The error returned by the call.
+// Returns: true for primary and secondary rate limit errors.
+func IsRateLimitError(err error) bool {
+	var rateErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	var respErr *github.ErrorResponse
+	switch {
+	case errors.As(err, &rateErr), errors.As(err, &abuseErr):
+		return true
+	case errors.As(err, &respErr) && respErr.Response != nil:
+		// go-github only recognizes a secondary limit by its documentation URL.
+		return respErr.Response.StatusCode == http.StatusTooManyRequests ||
+			strings.Contains(strings.ToLower(respErr.Message), "secondary rate limit")
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+// SPDX-License-Identifier: MIT
+
+package githubclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v82/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/esacteksab/gh-actlock/githubclient"
+	"github.com/esacteksab/gh-actlock/utils"
+)
+
+func TestRateLimitTransport(t *testing.T) {
+	utils.CreateLogger(false)
+
+	// limited writes a rate limited response.
+	type limited func(w http.ResponseWriter)
+	secondaryRetryAfter := func(w http.ResponseWriter) {
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"message": "You have exceeded a secondary rate limit.",` +
+			`"documentation_url": "https://docs.github.com/rest/overview/rate-limits-for-the-rest-api#about-secondary-rate-limits"}`))
+	}
+	secondaryNoHeader := func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"message": "You have exceeded a secondary rate limit."}`))
+	}
+	tooManyRequests := func(w http.ResponseWriter) {
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}
+	primaryIn := func(d time.Duration) limited {
+		return func(w http.ResponseWriter) {
+			w.Header().Set("X-RateLimit-Limit", "5000")
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(d).Unix(), 10))
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message": "API rate limit exceeded"}`))
+		}
+	}
+	forbidden := func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"message": "Resource not accessible by integration"}`))
+	}
+
+	tests := []struct {
+		name         string
+		limit        limited
+		maxWait      time.Duration
+		wantRequests int32
+		wantErr      bool
+		wantLimitErr bool
+	}{
+		{name: "secondary with Retry-After", limit: secondaryRetryAfter, maxWait: time.Minute, wantRequests: 2},
+		{name: "too many requests", limit: tooManyRequests, maxWait: time.Minute, wantRequests: 2},
+		{name: "primary reset", limit: primaryIn(0), maxWait: time.Minute, wantRequests: 2},
+		// Waiting for the reset would exceed the budget, so the error is returned at once.
+		{name: "primary over budget", limit: primaryIn(time.Hour), maxWait: time.Minute, wantRequests: 1,
+			wantErr: true, wantLimitErr: true},
+		// Without a Retry-After, a secondary limit is waited out for at least a minute.
+		{name: "secondary over budget", limit: secondaryNoHeader, maxWait: time.Second, wantRequests: 1,
+			wantErr: true, wantLimitErr: true},
+		{name: "no waiting", limit: secondaryRetryAfter, maxWait: 0, wantRequests: 1,
+			wantErr: true, wantLimitErr: true},
+		// A 403 that isn't a rate limit is returned as it is.
+		{name: "forbidden", limit: forbidden, maxWait: time.Minute, wantRequests: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				if requests.Add(1) == 1 {
+					tt.limit(w)
+					return
+				}
+				_ = json.NewEncoder(w).Encode(map[string]string{"default_branch": "main"}) //nolint:errchkjson
+			}))
+			defer srv.Close()
+
+			client := github.NewClient(&http.Client{Transport: githubclient.NewRateLimitTransport(nil, tt.maxWait)})
+			client.DisableRateLimitCheck = true
+			baseURL, err := url.Parse(srv.URL + "/")
+			require.NoError(t, err)
+			client.BaseURL = baseURL
+
+			repo, _, err := client.Repositories.Get(context.Background(), "owner", "repo")
+			assert.Equal(t, tt.wantRequests, requests.Load())
+			if !tt.wantErr {
+				require.NoError(t, err)
+				assert.Equal(t, "main", repo.GetDefaultBranch())
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, tt.wantLimitErr, githubclient.IsRateLimitError(err))
+		})
+	}
+}
@@ -0,0 +1,180 @@
+// SPDX-License-Identifier: MIT
+package githubclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/google/go-github/v82/github"
+)
+
+// ErrImpostorCommit is wrapped by the error returned for a commit that exists in the
+// repository's fork network but can't be reached from any of its branches or tags.
+var ErrImpostorCommit = errors.New("commit is not reachable from any branch or tag")
+
+// ErrCommitNotFound is wrapped by the error returned for a commit that doesn't exist in
+// the repository at all, such as a mistyped SHA.
+var ErrCommitNotFound = errors.New("commit does not exist in the repository")
+
+// ErrReachabilityUndetermined is wrapped by the error returned for a commit that none of
+// the branches and tags it was compared with contain, while others were left unchecked.
+var ErrReachabilityUndetermined = errors.New("commit could not be checked against every branch and tag")
+
+// branchesPerPage is the page size used when listing branches.
+const branchesPerPage = 100
+
+// maxHeadComparisons caps how many other branches and tags a commit that isn't in the
+// default branch is compared with, so that repositories with hundreds of tags cost a
+// bounded number of calls per pinned commit.
+const maxHeadComparisons = 10
+
+// CommitReachable reports whether a commit is reachable from at least one branch or tag
+// of a repository. GitHub serves any commit in a fork network through every repository
+// in it, so a commit that exists (see verifyCommitSHA) may still have been pushed to
+// a fork only. Pinning such an "impostor" commit runs code the repository never contained.
+//
+// - ctx: The context for API calls, allows for cancellation/timeouts.
+// - client: The initialized GitHub client for making API requests.
+// - owner: The owner (user or organization) of the GitHub repository.
+// - repo: The name of the GitHub repository.
+// - sha: The full commit SHA to check.
+// Returns: Whether the commit is reachable, and an error wrapping ErrCommitNotFound if
+// it doesn't exist, or another error if it couldn't be determined.
+func CommitReachable(ctx context.Context, client *github.Client, owner, repo, sha string) (bool, error) {
+	return NewResolver(client).Reachable(ctx, owner, repo, sha)
+}
+
+// Reachable is the memoized form of the CommitReachable function. The commit is first
+// looked up, so that one that doesn't exist isn't mistaken for an impostor. It is then
+// compared with the default branch, which contains nearly every pinned commit. Only if
+// that doesn't contain it is it matched against the heads of the other branches and the
+// tags, and compared with up to maxHeadComparisons of them. A commit that none of those
+// contain while others are left unchecked is reported as undetermined (see
+// ErrReachabilityUndetermined), not as an impostor.
+//
+// - ctx: The context for API calls.
+// - owner: The owner of the GitHub repository.
+// - repo: The name of the GitHub repository.
+// - sha: The full commit SHA to check.
+// Returns: Whether the commit is reachable, and an error wrapping ErrCommitNotFound if
+// it doesn't exist, ErrReachabilityUndetermined if it wasn't compared with every branch
+// and tag, or another error if the lookups failed.
+func (r *Resolver) Reachable(ctx context.Context, owner, repo, sha string) (bool, error) {
+	return r.reachable.do(owner+"/"+repo+"@"+sha, func() (bool, error) {
+		if _, exists, err := verifyCommitSHA(ctx, r.client, owner, repo, sha); err != nil {
+			return false, err
+		} else if !exists {
+			return false, fmt.Errorf("%s/%s@%s: %w", owner, repo, sha, ErrCommitNotFound)
+		}
+
+		defaultBranch, err := r.GetDefaultBranch(ctx, owner, repo)
+		if err != nil {
+			return false, err
+		}
+		if ok, err := r.contains(ctx, owner, repo, defaultBranch, sha); err != nil || ok {
+			return ok, err
+		}
+
+		// A tag pointing straight at the commit needs no comparison.
+		if names, err := r.TagsAt(ctx, owner, repo, sha); err != nil || len(names) > 0 {
+			return len(names) > 0, err
+		}
+		branches, err := r.heads.do(owner+"/"+repo, func() ([]*github.Branch, error) {
+			return listAllBranches(ctx, r.client, owner, repo)
+		})
+		if err != nil {
+			return false, err
+		}
+		tags, err := r.tags.do(owner+"/"+repo, func() ([]*github.RepositoryTag, error) {
+			return listAllTags(ctx, r.client, owner, repo)
+		})
+		if err != nil {
+			return false, err
+		}
+
+		// Branches and tags often share a commit, so each commit is compared only once.
+		var heads []string
+		seen := map[string]bool{}
+		for _, branch := range branches {
+			if head := branch.GetCommit().GetSHA(); branch.GetName() != defaultBranch && head != "" && !seen[head] {
+				heads = append(heads, head)
+				seen[head] = true
+			}
+		}
+		for _, tag := range tags {
+			if head := tag.GetCommit().GetSHA(); head != "" && !seen[head] {
+				heads = append(heads, head)
+				seen[head] = true
+			}
+		}
+		if slices.Contains(heads, sha) {
+			return true, nil
+		}
+		for i, head := range heads {
+			if i == maxHeadComparisons {
+				return false, fmt.Errorf(
+					"%s/%s@%s is not in the default branch or the first %d other branches and tags, and the other %d weren't checked: %w",
+					owner, repo, sha, maxHeadComparisons, len(heads)-maxHeadComparisons, ErrReachabilityUndetermined)
+			}
+			if ok, err := r.contains(ctx, owner, repo, head, sha); err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	})
+}
+
+// contains reports whether a commit is an ancestor of (or the same as) a base commit.
+//
+// - ctx: The context for API calls.
+// - owner: The owner of the GitHub repository.
+// - repo: The name of the GitHub repository.
+// - base: The branch name or commit SHA that may contain the commit.
+// - sha: The commit SHA to look for.
+// Returns: Whether base contains the commit, and an error if the comparison failed.
+func (r *Resolver) contains(ctx context.Context, owner, repo, base, sha string) (bool, error) {
+	comparison, resp, err := r.client.Repositories.CompareCommits(
+		ctx, owner, repo, base, sha, &github.ListOptions{PerPage: 1})
+	if err != nil {
+		// A commit from outside the fork network can't be compared at all.
+		if isNotFoundError(err, resp) {
+			return false, nil
+		}
+		return false, fmt.Errorf("error comparing %s with %s in %s/%s: %w", sha, base, owner, repo, err)
+	}
+	// "behind" means the commit is in base's history; "ahead" or "diverged" mean it isn't.
+	switch comparison.GetStatus() {
+	case "identical", "behind":
+		return true, nil
+	}
+	return false, nil
+}
+
+// listAllBranches fetches every branch of a repository, following pagination.
+//
+// - ctx: The context for API calls.
+// - client: The initialized GitHub client.
+// - owner: The owner of the GitHub repository.
+// - repo: The name of the GitHub repository.
+// Returns: All branches, and an error if any page can't be fetched.
+func listAllBranches(
+	ctx context.Context,
+	client *github.Client,
+	owner, repo string,
+) ([]*github.Branch, error) {
+	var all []*github.Branch
+	opt := &github.BranchListOptions{ListOptions: github.ListOptions{PerPage: branchesPerPage}}
+	for {
+		branches, resp, err := client.Repositories.ListBranches(ctx, owner, repo, opt)
+		if err != nil {
+			return nil, fmt.Errorf("error getting branches for %s/%s: %w", owner, repo, err)
+		}
+		all = append(all, branches...)
+		if resp == nil || resp.NextPage == 0 {
+			return all, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
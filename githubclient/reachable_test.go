@@ -0,0 +1,169 @@
+// SPDX-License-Identifier: MIT
+
+package githubclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-github/v82/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/esacteksab/gh-actlock/githubclient"
+	"github.com/esacteksab/gh-actlock/utils"
+)
+
+// reachableSHA returns a fake full commit SHA for a name, so test tables stay readable.
+func reachableSHA(name string) string {
+	return fmt.Sprintf("%040x", []byte(name))[:40]
+}
+
+func TestResolver_Reachable(t *testing.T) {
+	utils.CreateLogger(false)
+
+	// History: "on-main" is in main, "on-release" only in the release branch,
+	// "on-tag" only in the v0.9.0 tag, and "from-fork" was pushed to a fork.
+	// "unknown" doesn't exist at all; every other commit exists in the fork network.
+	sha := reachableSHA
+	contains := map[string][]string{
+		sha("main-head"):    {sha("main-head"), sha("on-main")},
+		sha("release-head"): {sha("release-head"), sha("on-release")},
+		sha("tag-head"):     {sha("tag-head"), sha("on-tag")},
+	}
+	var compareRequests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if spec, ok := strings.CutPrefix(r.URL.Path, "/repos/owner/repo/compare/"); ok {
+			compareRequests.Add(1)
+			base, head, _ := strings.Cut(spec, "...")
+			if base == "main" {
+				base = sha("main-head")
+			}
+			status := "diverged"
+			if base == head {
+				status = "identical"
+			}
+			for _, c := range contains[base] {
+				if c == head && status != "identical" {
+					status = "behind"
+				}
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{"status": status}) //nolint:errchkjson
+			return
+		}
+		if commit, ok := strings.CutPrefix(r.URL.Path, "/repos/owner/repo/git/commits/"); ok {
+			if commit == sha("unknown") {
+				http.NotFound(w, r)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{"sha": commit}) //nolint:errchkjson
+			return
+		}
+		switch r.URL.Path {
+		case "/repos/owner/repo":
+			_ = json.NewEncoder(w).Encode(map[string]string{"default_branch": "main"}) //nolint:errchkjson
+		case "/repos/owner/repo/branches":
+			_ = json.NewEncoder(w).Encode([]map[string]any{ //nolint:errchkjson
+				{"name": "main", "commit": map[string]string{"sha": sha("main-head")}},
+				{"name": "release", "commit": map[string]string{"sha": sha("release-head")}},
+			})
+		case "/repos/owner/repo/tags":
+			_ = json.NewEncoder(w).Encode([]map[string]any{ //nolint:errchkjson
+				{"name": "v1.0.0", "commit": map[string]string{"sha": sha("release-head")}},
+				{"name": "v0.9.0", "commit": map[string]string{"sha": sha("tag-head")}},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	client := github.NewClient(nil)
+	baseURL, err := url.Parse(srv.URL + "/")
+	require.NoError(t, err)
+	client.BaseURL = baseURL
+	resolver := githubclient.NewResolver(client)
+
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "on-main", want: true},
+		{name: "release-head", want: true},
+		{name: "on-release", want: true},
+		{name: "on-tag", want: true},
+		{name: "from-fork", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := resolver.Reachable(context.Background(), "owner", "repo", sha(tt.name))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, ok)
+		})
+	}
+
+	// A commit that doesn't exist is not an impostor, but a broken pin.
+	_, err = resolver.Reachable(context.Background(), "owner", "repo", sha("unknown"))
+	require.ErrorIs(t, err, githubclient.ErrCommitNotFound)
+
+	// The result is remembered, and the release branch and v1.0.0 tag share a commit
+	// that is compared only once: main, release-head, and tag-head.
+	before := compareRequests.Load()
+	ok, err := resolver.Reachable(context.Background(), "owner", "repo", sha("from-fork"))
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, before, compareRequests.Load())
+}
+
+func TestResolver_Reachable_CapsComparisons(t *testing.T) {
+	utils.CreateLogger(false)
+
+	// Thirty tags, each at its own commit, none of which contains the pinned commit.
+	var tags []map[string]any
+	for i := range 30 {
+		tags = append(tags, map[string]any{
+			"name":   fmt.Sprintf("v1.0.%d", i),
+			"commit": map[string]string{"sha": reachableSHA(fmt.Sprintf("tag-%d", i))},
+		})
+	}
+	var compareRequests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/repos/owner/repo/compare/"):
+			compareRequests.Add(1)
+			_ = json.NewEncoder(w).Encode(map[string]string{"status": "diverged"}) //nolint:errchkjson
+		case strings.HasPrefix(r.URL.Path, "/repos/owner/repo/git/commits/"):
+			_ = json.NewEncoder(w).Encode(map[string]string{"sha": "exists"}) //nolint:errchkjson
+		case r.URL.Path == "/repos/owner/repo":
+			_ = json.NewEncoder(w).Encode(map[string]string{"default_branch": "main"}) //nolint:errchkjson
+		case r.URL.Path == "/repos/owner/repo/branches":
+			_ = json.NewEncoder(w).Encode([]map[string]any{}) //nolint:errchkjson
+		case r.URL.Path == "/repos/owner/repo/tags":
+			_ = json.NewEncoder(w).Encode(tags) //nolint:errchkjson
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	client := github.NewClient(nil)
+	baseURL, err := url.Parse(srv.URL + "/")
+	require.NoError(t, err)
+	client.BaseURL = baseURL
+
+	// With tags left unchecked, the commit may still be in one of them: it's not an impostor.
+	ok, err := githubclient.NewResolver(client).Reachable(
+		context.Background(), "owner", "repo", reachableSHA("old-release"))
+	require.ErrorIs(t, err, githubclient.ErrReachabilityUndetermined)
+	require.NotErrorIs(t, err, githubclient.ErrImpostorCommit)
+	assert.False(t, ok)
+	// The default branch, plus ten of the tags.
+	assert.Equal(t, int32(11), compareRequests.Load())
+}
@@ -9,10 +9,44 @@ import (
 
 	"github.com/google/go-github/v82/github"
 
-	"github.com/esacteksab/gh-actlock/utils"
+	"github.com/esacteksab/gh-actlock/parser"
 )
 
+// RefKind describes what a ref resolved through, as recorded in the lockfile.
+type RefKind string
+
+const (
+	KindCommit         RefKind = "commit"          // The ref is itself a commit SHA
+	KindLightweightTag RefKind = "lightweight-tag" // A tag pointing directly at a commit
+	KindAnnotatedTag   RefKind = "annotated-tag"   // A tag pointing at a tag object
+	KindBranch         RefKind = "branch"          // The head of a branch
+	KindRelease        RefKind = "release"         // The tag of the repository's latest release
+)
+
+// ErrRefNotFound is wrapped by the error returned for a ref that is neither a commit
+// SHA, a tag, nor a branch of the repository.
+var ErrRefNotFound = errors.New("not found as a tag or branch")
+
 // ResolveRefToSHA attempts to find the commit SHA for a given Git ref (tag, branch, or potential SHA).
+// See ResolveRef for the order refs are checked in.
+//
+// - ctx: The context for the API calls, allows for cancellation/timeouts.
+// - client: The initialized GitHub client for making API requests.
+// - owner: The owner (user or organization) of the GitHub repository (e.g., "actions").
+// - repo: The name of the GitHub repository (e.g., "checkout").
+// - ref: The Git reference string to resolve (e.g., "v4", "main", "abcdef").
+// Returns: The full 40-character SHA-1 hash as a string if resolved, or an empty string and an error if not found or a critical error occurs.
+func ResolveRefToSHA(
+	ctx context.Context,
+	client *github.Client,
+	owner, repo, ref string,
+) (string, error) {
+	sha, _, err := ResolveRef(ctx, client, owner, repo, ref)
+	return sha, err
+}
+
+// ResolveRef attempts to find the commit SHA for a given Git ref (tag, branch, or potential SHA),
+// and reports what kind of ref it resolved through.
 // It checks in the order:
 // 1. If the ref itself is a valid, existing commit SHA.
 // 2. If the ref matches an existing Git tag (handling lightweight and annotated tags).
@@ -23,19 +57,20 @@ import (
 // - owner: The owner (user or organization) of the GitHub repository (e.g., "actions").
 // - repo: The name of the GitHub repository (e.g., "checkout").
 // - ref: The Git reference string to resolve (e.g., "v4", "main", "abcdef").
-// Returns: The full 40-character SHA-1 hash as a string if resolved, or an empty string and an error if not found or a critical error occurs.
-func ResolveRefToSHA(
+// Returns: The full 40-character SHA-1 hash and the kind of ref, or an error if not found or a critical error occurs.
+func ResolveRef(
 	ctx context.Context,
 	client *github.Client,
 	owner, repo, ref string,
-) (string, error) {
+) (string, RefKind, error) {
 	// Basic validation of input parameters.
 	if client == nil {
-		return "", errors.New("github client is nil")
+		return "", "", errors.New("github client is nil")
 	}
 	if owner == "" || repo == "" || ref == "" {
-		return "", errors.New("owner, repo, and ref must not be empty")
+		return "", "", errors.New("owner, repo, and ref must not be empty")
 	}
+	logger := loggerFor(client)
 
 	// 1. First, check if the provided 'ref' string is already a valid commit SHA.
 	// This avoids unnecessary API calls if the reference is already a commit hash.
@@ -43,7 +78,7 @@ func ResolveRefToSHA(
 	if sha, isCommit, err := verifyCommitSHA(ctx, client, owner, repo, ref); err != nil {
 		// Log non-critical errors during verification (e.g. network issues during check, but not 404).
 		// This doesn't stop the process - we'll continue to check tags/branches.
-		utils.Logger.Errorf(
+		logger.Errorf(
 			"Warning: Error verifying potential SHA '%s' for %s/%s: %v. Proceeding to check tags/branches.",
 			ref,
 			owner,
@@ -54,17 +89,22 @@ func ResolveRefToSHA(
 		// We'll continue to check tags/branches just in case.
 	} else if isCommit {
 		// If verifyCommitSHA confirmed this is a valid commit SHA that exists in the repo.
-		utils.Logger.Debugf("Ref '%s' is already a valid commit SHA.", ref) // Optional verbose log
-		return sha, nil                                                     // Return the verified SHA.
+		logger.Debugf("Ref '%s' is already a valid commit SHA.", ref) // Optional verbose log
+		return sha, KindCommit, nil                                   // Return the verified SHA.
 	}
 
 	// 2. If it wasn't a verified commit SHA, try resolving it as a Git tag.
 	// resolveTagToSHA returns the resolved SHA, a boolean indicating if a tag was found,
 	// the associated HTTP response, and an error.
-	if sha, found, resp, err := resolveTagToSHA(ctx, client, owner, repo, ref); err != nil {
+	// A lookup that couldn't be made, offline or because of a rate limit, doesn't mean
+	// the ref doesn't exist.
+	var lookupErr error
+	if sha, kind, found, resp, err := resolveTagToSHA(ctx, client, owner, repo, ref); err != nil {
 		// Log errors unless it's a simple "not found" (HTTP 404 from the initial GetRef call), which is expected when checking.
-		if !isNotFoundError(err, resp) { // Use the resp returned by resolveTagToSHA
-			utils.Logger.Errorf(
+		if errors.Is(err, ErrOffline) || IsRateLimitError(err) {
+			lookupErr = err
+		} else if !isNotFoundError(err, resp) { // Use the resp returned by resolveTagToSHA
+			logger.Errorf(
 				"Warning: Error checking tag '%s' for %s/%s: %v",
 				ref,
 				owner,
@@ -75,8 +115,8 @@ func ResolveRefToSHA(
 		// Continue even if there was an error checking the tag, unless it's critical and returned found=true with an error
 	} else if found {
 		// If a tag with this name was found and resolved to a SHA.
-		utils.Logger.Debugf("  Resolved ref '%s' via tag to SHA: %s", ref, sha[:8]) // Log resolved SHA (truncated)
-		return sha, nil                                                             // Return the resolved SHA.
+		logger.Debugf("  Resolved ref '%s' via tag to SHA: %s", ref, sha[:8]) // Log resolved SHA (truncated)
+		return sha, kind, nil                                                 // Return the resolved SHA.
 	}
 
 	// 3. If it wasn't a tag, try resolving it as a branch.
@@ -84,8 +124,10 @@ func ResolveRefToSHA(
 	// the associated HTTP response, and an error.
 	if sha, found, resp, err := resolveBranchToSHA(ctx, client, owner, repo, ref); err != nil {
 		// Log errors unless it's a simple "not found" (HTTP 404), which is expected when checking.
-		if !isNotFoundError(err, resp) { // Use the resp returned by resolveBranchToSHA
-			utils.Logger.Errorf(
+		if errors.Is(err, ErrOffline) || IsRateLimitError(err) {
+			lookupErr = err
+		} else if !isNotFoundError(err, resp) { // Use the resp returned by resolveBranchToSHA
+			logger.Errorf(
 				"Warning: Error checking branch '%s' for %s/%s: %v",
 				ref,
 				owner,
@@ -96,13 +138,16 @@ func ResolveRefToSHA(
 		// Continue even if there was an error checking the branch
 	} else if found {
 		// If a branch with this name was found and resolved to a SHA.
-		utils.Logger.Debugf("  Resolved ref '%s' via branch to SHA: %s", ref, sha[:8]) // Log resolved SHA (truncated)
-		return sha, nil                                                                // Return the resolved SHA.
+		logger.Debugf("  Resolved ref '%s' via branch to SHA: %s", ref, sha[:8]) // Log resolved SHA (truncated)
+		return sha, KindBranch, nil                                              // Return the resolved SHA.
 	}
 
 	// 4. If we've tried all options (commit SHA check, tag lookup, branch lookup)
 	// and nothing matched or resolved successfully, return a "not found" error.
-	return "", fmt.Errorf("reference '%s' not found as a tag or branch in %s/%s", ref, owner, repo)
+	if lookupErr != nil {
+		return "", "", fmt.Errorf("reference '%s' of %s/%s can't be resolved: %w", ref, owner, repo, lookupErr)
+	}
+	return "", "", fmt.Errorf("reference '%s' %w in %s/%s", ref, ErrRefNotFound, owner, repo)
 }
 
 // verifyCommitSHA checks if a given string 'ref' is formatted like a SHA-1 and
@@ -123,7 +168,7 @@ func verifyCommitSHA(
 	owner, repo, ref string,
 ) (string, bool, error) {
 	// A valid SHA must be exactly 40 characters long and contain only hexadecimal digits.
-	if len(ref) != SHALength || !IsHexString(ref) {
+	if len(ref) != parser.SHALength || !parser.IsHexString(ref) {
 		// If the format is wrong, it's definitely not a full SHA. No API call needed.
 		return "", false, nil
 	}
@@ -172,6 +217,7 @@ func verifyCommitSHA(
 //
 // Returns:
 //   - sha: The commit SHA the tag ultimately points to.
+//   - kind: KindLightweightTag or KindAnnotatedTag.
 //   - found: A boolean indicating if a tag with the given name was found.
 //   - resp: The GitHub API response object from the last successful or failed call.
 //   - err: An error if a critical API call failed during resolution (excluding initial 404).
@@ -179,7 +225,7 @@ func resolveTagToSHA(
 	ctx context.Context,
 	client *github.Client,
 	owner, repo, ref string,
-) (sha string, found bool, resp *github.Response, err error) {
+) (sha string, kind RefKind, found bool, resp *github.Response, err error) {
 	// GitHub API uses "refs/tags/" prefix for tag references.
 	refPath := "refs/tags/" + ref
 
@@ -190,10 +236,10 @@ func resolveTagToSHA(
 		// If the GetRef call failed:
 		// If it's a 404 error, the tag simply doesn't exist. This is not a critical error for the overall process.
 		if isNotFoundError(errRef, respRef) {
-			return "", false, respRef, nil // Tag not found by GetRef, return found=false.
+			return "", "", false, respRef, nil // Tag not found by GetRef, return found=false.
 		}
 		// For any other error (network, auth, rate limit), return the error.
-		return "", false, respRef, fmt.Errorf(
+		return "", "", false, respRef, fmt.Errorf(
 			"failed to get tag ref '%s' (%s) for %s/%s: %w",
 			ref,
 			refPath,
@@ -205,7 +251,7 @@ func resolveTagToSHA(
 
 	// If gitRef is nil but no error occurred, it's an unexpected state.
 	if gitRef == nil || gitRef.Object == nil || gitRef.Object.SHA == nil {
-		return "", false, respRef, fmt.Errorf(
+		return "", "", false, respRef, fmt.Errorf(
 			"tag ref '%s' (%s) for %s/%s found but returned unexpected nil object/SHA",
 			ref,
 			refPath,
@@ -218,20 +264,20 @@ func resolveTagToSHA(
 	switch *gitRef.Object.Type {
 	case "commit":
 		// This is a lightweight tag. It points directly to a commit.
-		utils.Logger.Debugf(
+		loggerFor(client).Debugf(
 			"  Tag '%s' (%s) is lightweight, pointing directly to commit %s",
 			ref,
 			refPath,
 			(*gitRef.Object.SHA)[:8],
 		)
 		// The SHA we need is the one in the reference's object.
-		return *gitRef.Object.SHA, true, respRef, nil // Return the commit SHA, and the response from GetRef.
+		return *gitRef.Object.SHA, KindLightweightTag, true, respRef, nil // Return the commit SHA, and the response from GetRef.
 
 	case "tag":
 		// This is an annotated tag. It points to a Git Tag object.
 		// We need to fetch the Tag object to find the commit it points to.
 		tagObjectSHA := *gitRef.Object.SHA
-		utils.Logger.Debugf(
+		loggerFor(client).Debugf(
 			"  Tag '%s' (%s) is annotated, pointing to tag object %s. Fetching tag object...",
 			ref,
 			refPath,
@@ -243,7 +289,7 @@ func resolveTagToSHA(
 		if errTag != nil {
 			// If GetTag fails, this *is* a critical error because the tag object should exist if GetRef said so.
 			// If it's a 404 here, it indicates an inconsistency or a cache issue.
-			return "", false, respTag, fmt.Errorf(
+			return "", "", false, respTag, fmt.Errorf(
 				"failed to get tag object '%s' for annotated tag '%s': %w",
 				tagObjectSHA,
 				ref,
@@ -253,7 +299,7 @@ func resolveTagToSHA(
 
 		// If gitTag is nil but no error occurred, it's an unexpected state.
 		if gitTag == nil || gitTag.Object == nil || gitTag.Object.SHA == nil {
-			return "", false, respTag, fmt.Errorf(
+			return "", "", false, respTag, fmt.Errorf(
 				"tag object '%s' for annotated tag '%s' found but returned unexpected nil object/SHA",
 				tagObjectSHA,
 				ref,
@@ -265,7 +311,7 @@ func resolveTagToSHA(
 		if *gitTag.Object.Type != "commit" {
 			// The tag object points to something unexpected (e.g., another tag object, or a tree/blob).
 			// This is not a standard action/workflow tag structure.
-			return "", false, respTag, fmt.Errorf(
+			return "", "", false, respTag, fmt.Errorf(
 				"tag object '%s' for annotated tag '%s' points to object type '%s', expected 'commit'",
 				tagObjectSHA,
 				ref,
@@ -275,18 +321,18 @@ func resolveTagToSHA(
 
 		// The SHA in the Tag object's Object field is the commit SHA that the tag points to.
 		commitSHA := *gitTag.Object.SHA
-		utils.Logger.Debugf(
+		loggerFor(client).Debugf(
 			"  Annotated tag object %s points to commit SHA: %s",
 			tagObjectSHA[:8],
 			commitSHA[:8],
 		)
 		// Return the commit SHA and the response from the GetTag call (as it was the last successful/relevant one).
-		return commitSHA, true, respTag, nil
+		return commitSHA, KindAnnotatedTag, true, respTag, nil
 
 	default:
 		// The Git Reference object points to something other than a commit or a tag object (e.g., a tree or blob).
 		// This is not a standard tag structure.
-		return "", false, respRef, fmt.Errorf(
+		return "", "", false, respRef, fmt.Errorf(
 			"tag ref '%s' (%s) for %s/%s points to unexpected object type '%s'",
 			ref,
 			refPath,
@@ -365,7 +411,7 @@ func resolveBranchToSHA(
 
 	// The SHA in the reference object's Object field is the commit SHA at the head of the branch.
 	commitSHA := *gitRef.Object.SHA
-	utils.Logger.Debugf(
+	loggerFor(client).Debugf(
 		"  Resolved branch '%s' (%s) to commit SHA: %s",
 		ref,
 		refPath,
@@ -0,0 +1,265 @@
+// SPDX-License-Identifier: MIT
+package githubclient
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v82/github"
+)
+
+// UpdatePolicy controls how far update mode may move a reference.
+type UpdatePolicy string
+
+// Update policies accepted by --policy. Tracked follows the current ref; patch, minor
+// and major are relative to the current version; latest uses the repository's latest release.
+const (
+	UpdateTracked UpdatePolicy = "tracked" // Re-resolve the current ref (e.g., a moving v4 tag or main branch)
+	UpdatePatch   UpdatePolicy = "patch"   // Stay within the current major.minor version
+	UpdateMinor   UpdatePolicy = "minor"   // Stay within the current major version
+	UpdateMajor   UpdatePolicy = "major"   // Move to the highest version, never downgrading
+	UpdateLatest  UpdatePolicy = "latest"  // Move to the latest release, falling back to the newest tag
+)
+
+// tagsPerPage is the maximum page size the GitHub API allows when listing tags.
+const tagsPerPage = 100
+
+// ParseUpdatePolicy validates a --policy value.
+//
+// - s: The policy name.
+// Returns: The UpdatePolicy, and an error if the name is not a known policy.
+func ParseUpdatePolicy(s string) (UpdatePolicy, error) {
+	switch p := UpdatePolicy(s); p {
+	case UpdateTracked, UpdatePatch, UpdateMinor, UpdateMajor, UpdateLatest:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown update policy %q: expected tracked, patch, minor, major, or latest", s)
+}
+
+// GetUpdateRef retrieves the reference update mode should move to under the given policy,
+// and its corresponding commit SHA.
+//
+// - ctx: The context for API calls, allows for cancellation/timeouts.
+// - client: The initialized GitHub client for making API requests.
+// - owner: The owner (user or organization) of the GitHub repository.
+// - repo: The name of the GitHub repository.
+// - current: The ref currently tracked (e.g., "v4.1.0"), may be empty for the tracked and latest policies.
+// - policy: The update policy to apply.
+// Returns:
+//   - string: The name of the tag or release to update to
+//   - string: The full SHA hash corresponding to that reference
+//   - error: An error if no suitable reference can be found
+func GetUpdateRef(
+	ctx context.Context,
+	client *github.Client,
+	owner, repo, current string,
+	policy UpdatePolicy,
+) (string, string, error) {
+	return NewResolver(client).GetUpdateRef(ctx, owner, repo, current, policy)
+}
+
+// GetLatestSemverRef lists every tag of a repository and returns the highest semantic
+// version allowed by the policy relative to the current version, with its commit SHA.
+// Pre-releases are only considered when the current version is itself a pre-release.
+//
+// - ctx: The context for API calls, allows for cancellation/timeouts.
+// - client: The initialized GitHub client for making API requests.
+// - owner: The owner (user or organization) of the GitHub repository.
+// - repo: The name of the GitHub repository.
+// - current: The version currently in use (e.g., "v4" or "v4.1.0").
+// - policy: UpdatePatch, UpdateMinor, or UpdateMajor.
+// Returns:
+//   - string: The name of the selected tag
+//   - string: The full SHA hash of the commit the tag points to
+//   - error: An error if current isn't a semantic version or no tag matches
+func GetLatestSemverRef(
+	ctx context.Context,
+	client *github.Client,
+	owner, repo, current string,
+	policy UpdatePolicy,
+) (string, string, error) {
+	return NewResolver(client).GetLatestSemverRef(ctx, owner, repo, current, policy)
+}
+
+// listAllTags retrieves every tag of a repository, following pagination.
+//
+// - ctx: The context for API calls.
+// - client: The initialized GitHub client.
+// - owner: The owner of the GitHub repository.
+// - repo: The name of the GitHub repository.
+// Returns: All tags, and an error if any page cannot be retrieved.
+func listAllTags(
+	ctx context.Context,
+	client *github.Client,
+	owner, repo string,
+) ([]*github.RepositoryTag, error) {
+	var all []*github.RepositoryTag
+	opt := &github.ListOptions{PerPage: tagsPerPage}
+	for {
+		tags, resp, err := client.Repositories.ListTags(ctx, owner, repo, opt)
+		if err != nil {
+			return nil, fmt.Errorf("error getting tags for %s/%s: %w", owner, repo, err)
+		}
+		all = append(all, tags...)
+		if resp == nil || resp.NextPage == 0 {
+			return all, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
+
+// selectSemverTag picks the highest tag the policy allows. Tags lower than the current
+// version are never chosen, so updating can't downgrade. Parts the current version
+// doesn't spell out match any tag: a patch update from "v4" stays within v4, like a
+// minor one, rather than within v4.0.
+//
+// - tags: The repository's tags.
+// - current: The version currently in use.
+// - policy: UpdatePatch, UpdateMinor, or UpdateMajor.
+// Returns: The selected tag, and an error if no tag matches.
+func selectSemverTag(
+	tags []*github.RepositoryTag,
+	current string,
+	policy UpdatePolicy,
+) (*github.RepositoryTag, error) {
+	cur, ok := parseVersion(current)
+	if !ok {
+		return nil, fmt.Errorf("'%s' is not a semantic version", current)
+	}
+
+	var best *github.RepositoryTag
+	var bestVersion version
+	for _, tag := range tags {
+		if tag.GetName() == "" || tag.GetCommit().GetSHA() == "" {
+			continue
+		}
+		v, ok := parseVersion(tag.GetName())
+		if !ok || (v.pre != "" && cur.pre == "") {
+			continue
+		}
+		switch policy {
+		case UpdatePatch:
+			if v.major != cur.major || (cur.parts > 1 && v.minor != cur.minor) {
+				continue
+			}
+		case UpdateMinor:
+			if v.major != cur.major {
+				continue
+			}
+		case UpdateMajor:
+		default:
+			return nil, fmt.Errorf("update policy %q does not select by version", policy)
+		}
+		if v.compare(cur) < 0 {
+			continue
+		}
+		if best == nil || v.compare(bestVersion) > 0 {
+			best, bestVersion = tag, v
+		}
+	}
+
+	if best == nil {
+		return nil, fmt.Errorf("no tag found for a %s update from %s", policy, current)
+	}
+	return best, nil
+}
+
+// version is a parsed semantic version tag such as "v4", "v4.1" or "v4.1.0-rc.1".
+type version struct {
+	major, minor, patch int
+	parts               int    // How many of major.minor.patch were written
+	pre                 string // Pre-release identifier, without the '-'
+}
+
+// parseVersion parses a version tag. A leading 'v' is optional, minor and patch may be
+// omitted (as in the moving "v4" tags many actions publish), and build metadata is ignored.
+//
+// - s: The tag name.
+// Returns: The parsed version, and whether s is a semantic version.
+func parseVersion(s string) (version, bool) {
+	var v version
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "v"), "V")
+	s, _, _ = strings.Cut(s, "+")
+	s, v.pre, _ = strings.Cut(s, "-")
+
+	fields := strings.Split(s, ".")
+	if len(fields) > 3 { //nolint:mnd
+		return version{}, false
+	}
+	nums := []*int{&v.major, &v.minor, &v.patch}
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || strings.HasPrefix(field, "+") {
+			return version{}, false
+		}
+		*nums[i] = n
+	}
+	v.parts = len(fields)
+	return v, true
+}
+
+// compare orders versions by major, minor and patch. Between equal numbers a release
+// sorts above a pre-release, pre-releases are ordered as semver §11 says (see
+// comparePre), and a more specific tag (v4.0.0) sorts above a shorter one (v4).
+//
+// - o: The version to compare against.
+// Returns: -1, 0 or +1 as v is lower than, equal to or higher than o.
+func (v version) compare(o version) int {
+	for _, d := range []int{v.major - o.major, v.minor - o.minor, v.patch - o.patch} {
+		if d != 0 {
+			return sign(d)
+		}
+	}
+	switch {
+	case v.pre == "" && o.pre != "":
+		return 1
+	case v.pre != "" && o.pre == "":
+		return -1
+	case v.pre != o.pre:
+		return comparePre(v.pre, o.pre)
+	}
+	return sign(v.parts - o.parts)
+}
+
+// comparePre orders pre-release identifiers such as "rc.2" and "rc.10" field by field:
+// numeric fields numerically and below alphanumeric ones, which compare in ASCII order.
+// When every field of one is a prefix of the other, the one with fewer fields is lower.
+//
+// - a: A pre-release identifier, without the '-'.
+// - b: The identifier to compare against.
+// Returns: -1, 0 or +1 as a is lower than, equal to or higher than b.
+func comparePre(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := range min(len(as), len(bs)) {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				return sign(an - bn)
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return sign(len(as) - len(bs))
+}
+
+// sign returns -1, 0 or +1 according to the sign of n.
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
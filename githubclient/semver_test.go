@@ -0,0 +1,134 @@
+// SPDX-License-Identifier: MIT
+
+package githubclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-github/v82/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/esacteksab/gh-actlock/githubclient"
+)
+
+// newTagServer serves the given tag names from /repos/owner/repo/tags, one tag per
+// page so pagination is exercised. Each tag's commit SHA is "sha-<name>".
+func newTagServer(t *testing.T, names ...string) *github.Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/tags" {
+			http.NotFound(w, r)
+			return
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		page = max(page, 1)
+		if page < len(names) {
+			w.Header().Set("Link", fmt.Sprintf(`<%s?page=%d>; rel="next"`, r.URL.Path, page+1))
+		}
+		var tags []map[string]any
+		if page <= len(names) {
+			name := names[page-1]
+			tags = append(tags, map[string]any{"name": name, "commit": map[string]string{"sha": "sha-" + name}})
+		}
+		_ = json.NewEncoder(w).Encode(tags) //nolint:errchkjson
+	}))
+	t.Cleanup(srv.Close)
+
+	client := github.NewClient(nil)
+	baseURL, err := url.Parse(srv.URL + "/")
+	require.NoError(t, err)
+	client.BaseURL = baseURL
+	return client
+}
+
+func TestGetLatestSemverRef(t *testing.T) {
+	client := newTagServer(t,
+		"v2.0.0", "v3", "v3.0.0", "v3.1.0", "v3.1.4", "v3.2.0", "v4.0.0-rc.1", "v4.0.0", "v4.1.0", "latest", "v5.0.0-beta")
+
+	tests := []struct {
+		name    string
+		current string
+		policy  githubclient.UpdatePolicy
+		want    string
+	}{
+		{name: "patch", current: "v3.1.0", policy: githubclient.UpdatePatch, want: "v3.1.4"},
+		// A moving major tag says nothing of the minor version, so patch can't mean v3.0.x.
+		{name: "patch_from_major_tag", current: "v3", policy: githubclient.UpdatePatch, want: "v3.2.0"},
+		{name: "patch_from_minor_tag", current: "v3.1", policy: githubclient.UpdatePatch, want: "v3.1.4"},
+		{name: "minor", current: "v3.1.0", policy: githubclient.UpdateMinor, want: "v3.2.0"},
+		{name: "minor_from_major_tag", current: "v3", policy: githubclient.UpdateMinor, want: "v3.2.0"},
+		{name: "major", current: "v3.1.0", policy: githubclient.UpdateMajor, want: "v4.1.0"},
+		{name: "already_highest", current: "v4.1.0", policy: githubclient.UpdateMajor, want: "v4.1.0"},
+		{name: "prerelease", current: "v4.0.0-rc.1", policy: githubclient.UpdateMajor, want: "v5.0.0-beta"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, sha, err := githubclient.GetLatestSemverRef(
+				context.Background(), client, "owner", "repo", tt.current, tt.policy)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, ref)
+			assert.Equal(t, "sha-"+tt.want, sha)
+		})
+	}
+}
+
+func TestGetLatestSemverRef_PreReleaseOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		tags    []string
+		current string
+		want    string
+	}{
+		{name: "numeric", tags: []string{"v1.0.0-rc.2", "v1.0.0-rc.10", "v1.0.0-rc.9"}, current: "v1.0.0-rc.1", want: "v1.0.0-rc.10"},
+		{name: "numeric_below_alphanumeric", tags: []string{"v1.0.0-rc.1", "v1.0.0-rc.beta"}, current: "v1.0.0-rc.0", want: "v1.0.0-rc.beta"},
+		{name: "alphanumeric", tags: []string{"v1.0.0-beta"}, current: "v1.0.0-alpha", want: "v1.0.0-beta"},
+		{name: "more_fields", tags: []string{"v1.0.0-alpha.1"}, current: "v1.0.0-alpha", want: "v1.0.0-alpha.1"},
+		{name: "release_above_prerelease", tags: []string{"v1.0.0-rc.10", "v1.0.0"}, current: "v1.0.0-rc.2", want: "v1.0.0"},
+		// A lower pre-release is never chosen, however its fields sort as text.
+		{name: "no_downgrade", tags: []string{"v1.0.0-rc.2"}, current: "v1.0.0-rc.10", want: "v1.0.0-rc.10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTagServer(t, append(tt.tags, tt.current)...)
+			ref, _, err := githubclient.GetLatestSemverRef(
+				context.Background(), client, "owner", "repo", tt.current, githubclient.UpdatePatch)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, ref)
+		})
+	}
+}
+
+func TestGetLatestSemverRef_Errors(t *testing.T) {
+	client := newTagServer(t, "v1.0.0", "v2.0.0")
+
+	// A branch has no version to update from.
+	_, _, err := githubclient.GetLatestSemverRef(
+		context.Background(), client, "owner", "repo", "main", githubclient.UpdateMinor)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not a semantic version")
+
+	// Updates never go backwards, even when the current version has no tag.
+	_, _, err = githubclient.GetLatestSemverRef(
+		context.Background(), client, "owner", "repo", "v3.0.0", githubclient.UpdateMajor)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no tag found")
+}
+
+func TestParseUpdatePolicy(t *testing.T) {
+	for _, name := range []string{"tracked", "patch", "minor", "major", "latest"} {
+		policy, err := githubclient.ParseUpdatePolicy(name)
+		require.NoError(t, err)
+		assert.Equal(t, githubclient.UpdatePolicy(name), policy)
+	}
+
+	_, err := githubclient.ParseUpdatePolicy("newest")
+	assert.Error(t, err)
+}
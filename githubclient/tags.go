@@ -0,0 +1,96 @@
+// SPDX-License-Identifier: MIT
+package githubclient
+
+import (
+	"context"
+
+	"github.com/google/go-github/v82/github"
+)
+
+// MostSpecificTag finds the most specific tag pointing at a commit. Moving tags such as
+// "v4" are ambiguous once they move on, so a pin is better described by "v4.2.2".
+//
+// - ctx: The context for API calls, allows for cancellation/timeouts.
+// - client: The initialized GitHub client for making API requests.
+// - owner: The owner (user or organization) of the GitHub repository.
+// - repo: The name of the GitHub repository.
+// - sha: The full commit SHA.
+// Returns: The tag name, empty if no tag points at the commit, and an error if the tags can't be listed.
+func MostSpecificTag(ctx context.Context, client *github.Client, owner, repo, sha string) (string, error) {
+	return NewResolver(client).MostSpecificTag(ctx, owner, repo, sha)
+}
+
+// MostSpecificTag is the memoized form of the MostSpecificTag function. Among the semantic
+// versions pointing at the commit, a release wins over a pre-release, then the tag with
+// the most of major.minor.patch written, then the highest version. Without a semantic
+// version, the first tag in the repository's tag list is used.
+//
+// - ctx: The context for API calls.
+// - owner: The owner of the GitHub repository.
+// - repo: The name of the GitHub repository.
+// - sha: The full commit SHA.
+// Returns: The tag name, empty if no tag points at the commit, and an error if the tags can't be listed.
+func (r *Resolver) MostSpecificTag(ctx context.Context, owner, repo, sha string) (string, error) {
+	names, err := r.TagsAt(ctx, owner, repo, sha)
+	if err != nil || len(names) == 0 {
+		return "", err
+	}
+
+	best, bestVersion := "", version{}
+	for _, name := range names {
+		v, ok := parseVersion(name)
+		if ok && (best == "" || moreSpecific(v, bestVersion)) {
+			best, bestVersion = name, v
+		}
+	}
+	if best == "" {
+		return names[0], nil
+	}
+	return best, nil
+}
+
+// TagsAt lists the tags that point at a commit. The tag list gives the commit an
+// annotated tag points at rather than the tag object, the same commit resolveTagToSHA
+// peels it to, so the index of commits to tags needs one listing per repository.
+//
+// - ctx: The context for API calls.
+// - owner: The owner of the GitHub repository.
+// - repo: The name of the GitHub repository.
+// - sha: The full commit SHA.
+// Returns: The names of the tags, in the order GitHub lists them, and an error if the tags can't be listed.
+func (r *Resolver) TagsAt(ctx context.Context, owner, repo, sha string) ([]string, error) {
+	index, err := r.tagIndex.do(owner+"/"+repo, func() (map[string][]string, error) {
+		tags, err := r.tags.do(owner+"/"+repo, func() ([]*github.RepositoryTag, error) {
+			return listAllTags(ctx, r.client, owner, repo)
+		})
+		if err != nil {
+			return nil, err
+		}
+		index := make(map[string][]string)
+		for _, tag := range tags {
+			if commit := tag.GetCommit().GetSHA(); commit != "" && tag.GetName() != "" {
+				index[commit] = append(index[commit], tag.GetName())
+			}
+		}
+		return index, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return index[sha], nil
+}
+
+// moreSpecific reports whether version v describes a commit better than version o.
+//
+// - v: The candidate version.
+// - o: The best version so far.
+// Returns: true if v should replace o.
+func moreSpecific(v, o version) bool {
+	switch {
+	case (v.pre == "") != (o.pre == ""):
+		return v.pre == ""
+	case v.parts != o.parts:
+		return v.parts > o.parts
+	}
+	return v.compare(o) > 0
+}
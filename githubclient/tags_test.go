@@ -0,0 +1,67 @@
+// SPDX-License-Identifier: MIT
+
+package githubclient_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-github/v82/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/esacteksab/gh-actlock/githubclient"
+)
+
+func TestResolver_MostSpecificTag(t *testing.T) {
+	var tagRequests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo/tags" {
+			http.NotFound(w, r)
+			return
+		}
+		tagRequests.Add(1)
+		// The tag list gives the commit of annotated tags, not the tag object.
+		_ = json.NewEncoder(w).Encode([]map[string]any{ //nolint:errchkjson
+			{"name": "v4", "commit": map[string]string{"sha": "sha-a"}},
+			{"name": "v4.2.2", "commit": map[string]string{"sha": "sha-a"}},
+			{"name": "v4.2", "commit": map[string]string{"sha": "sha-a"}},
+			{"name": "v5.0.0-rc.1", "commit": map[string]string{"sha": "sha-a"}},
+			{"name": "v4.2.1", "commit": map[string]string{"sha": "sha-b"}},
+			{"name": "nightly", "commit": map[string]string{"sha": "sha-c"}},
+			{"name": "stable", "commit": map[string]string{"sha": "sha-c"}},
+		})
+	}))
+	defer srv.Close()
+
+	client := github.NewClient(nil)
+	baseURL, err := url.Parse(srv.URL + "/")
+	require.NoError(t, err)
+	client.BaseURL = baseURL
+	resolver := githubclient.NewResolver(client)
+
+	tests := []struct {
+		sha  string
+		want string
+	}{
+		{sha: "sha-a", want: "v4.2.2"},  // A release beats a pre-release, and v4.2.2 beats v4.2 and v4
+		{sha: "sha-b", want: "v4.2.1"},  // The only tag
+		{sha: "sha-c", want: "nightly"}, // No semantic version: the first listed tag
+		{sha: "sha-d", want: ""},        // Not tagged
+	}
+	for _, tt := range tests {
+		t.Run(tt.sha, func(t *testing.T) {
+			tag, err := resolver.MostSpecificTag(context.Background(), "owner", "repo", tt.sha)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, tag)
+		})
+	}
+
+	// The index is built from a single listing of the tags.
+	assert.Equal(t, int32(1), tagRequests.Load())
+}
@@ -0,0 +1,166 @@
+// SPDX-License-Identifier: MIT
+
+// Package lockfile reads and writes actlock.lock, which records the commit every
+// pinned action and reusable workflow reference was resolved to.
+package lockfile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the name of the lockfile, kept in the .github directory.
+const FileName = "actlock.lock"
+
+// Version is the version of the lockfile format written by this package.
+const Version = 1
+
+// header is written at the top of every lockfile.
+const header = "# Generated by actlock. Do not edit by hand; run 'gh actlock' instead.\n"
+
+// Lockfile is the contents of an actlock.lock file.
+type Lockfile struct {
+	Version int     `yaml:"version"` // Format version
+	Actions []Entry `yaml:"actions"` // One entry per distinct owner/repo[/path], ref and SHA
+}
+
+// Entry records what a reference was resolved to.
+type Entry struct {
+	Uses     string    `yaml:"uses"`               // owner/repo[/path], as written after 'uses:'
+	Ref      string    `yaml:"ref,omitempty"`      // The human ref, e.g. "v4"; empty if the pin has no comment
+	SHA      string    `yaml:"sha"`                // The commit SHA the reference is pinned to
+	Type     string    `yaml:"type,omitempty"`     // What the ref resolved through, e.g. "annotated-tag"
+	Verified bool      `yaml:"verified,omitempty"` // Whether the SHA is known to be on a branch or tag of the repo
+	Resolved time.Time `yaml:"resolved"`           // When the ref was resolved
+}
+
+// Path returns where the lockfile of a project lives.
+//
+// - root: The project root directory.
+// Returns: <root>/.github/actlock.lock.
+func Path(root string) string {
+	return filepath.Join(root, ".github", FileName)
+}
+
+// Load reads a lockfile.
+//
+// - filePath: The path of the lockfile.
+// Returns: The parsed Lockfile, and an error (wrapping fs.ErrNotExist if there is no such file) if it can't be read.
+func Load(filePath string) (*Lockfile, error) {
+	data, err := os.ReadFile(filePath) //nolint:gosec
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("lockfile %s not found: %w", filePath, err)
+		}
+		return nil, fmt.Errorf("error reading lockfile %s: %w", filePath, err)
+	}
+
+	lock := &Lockfile{}
+	if err := yaml.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("error parsing lockfile %s: %w", filePath, err)
+	}
+	if lock.Version > Version {
+		return nil, fmt.Errorf("lockfile %s has version %d, this actlock supports up to %d",
+			filePath, lock.Version, Version)
+	}
+	return lock, nil
+}
+
+// Add records an entry, replacing any entry for the same reference and SHA.
+//
+// - entry: The entry to record.
+func (l *Lockfile) Add(entry Entry) {
+	i := slices.IndexFunc(l.Actions, func(e Entry) bool {
+		return e.Uses == entry.Uses && e.Ref == entry.Ref && e.SHA == entry.SHA
+	})
+	if i >= 0 {
+		l.Actions[i] = entry
+		return
+	}
+	l.Actions = append(l.Actions, entry)
+}
+
+// Find returns the entry for a reference pinned to a SHA.
+//
+// - uses: The owner/repo[/path] of the reference.
+// - ref: The ref it tracks, from its inline comment, may be empty.
+// - sha: The commit SHA it is pinned to.
+// Returns: The entry, and whether there is one.
+func (l *Lockfile) Find(uses, ref, sha string) (Entry, bool) {
+	if l == nil {
+		return Entry{}, false
+	}
+	for _, e := range l.Actions {
+		if e.Uses == uses && e.Ref == ref && e.SHA == sha {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Lookup returns every entry for a reference, whatever SHA it was resolved to.
+//
+// - uses: The owner/repo[/path] of the reference.
+// - ref: The ref it tracks, may be empty.
+// Returns: The matching entries.
+func (l *Lockfile) Lookup(uses, ref string) []Entry {
+	if l == nil {
+		return nil
+	}
+	var found []Entry
+	for _, e := range l.Actions {
+		if e.Uses == uses && e.Ref == ref {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+// Write sorts the entries and writes the lockfile, creating its directory if needed.
+//
+// - filePath: The path to write to.
+// Returns: An error if the file can't be written.
+func (l *Lockfile) Write(filePath string) error {
+	l.Version = Version
+	slices.SortFunc(l.Actions, cmpEntries)
+
+	var buf bytes.Buffer
+	buf.WriteString(header)
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2) //nolint:mnd
+	if err := enc.Encode(l); err != nil {
+		return fmt.Errorf("error encoding lockfile %s: %w", filePath, err)
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("error encoding lockfile %s: %w", filePath, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o750); err != nil { //nolint:mnd
+		return fmt.Errorf("error creating directory for lockfile %s: %w", filePath, err)
+	}
+	if err := os.WriteFile(filePath, buf.Bytes(), 0o640); err != nil { //nolint:gosec,mnd
+		return fmt.Errorf("error writing lockfile %s: %w", filePath, err)
+	}
+	return nil
+}
+
+// cmpEntries orders entries by reference, then ref, then SHA, so the lockfile
+// doesn't change when nothing it records has.
+func cmpEntries(a, b Entry) int {
+	if c := strings.Compare(a.Uses, b.Uses); c != 0 {
+		return c
+	}
+	if c := strings.Compare(a.Ref, b.Ref); c != 0 {
+		return c
+	}
+	return strings.Compare(a.SHA, b.SHA)
+}
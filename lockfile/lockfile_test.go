@@ -0,0 +1,84 @@
+// SPDX-License-Identifier: MIT
+
+package lockfile_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/esacteksab/gh-actlock/lockfile"
+)
+
+func TestWriteAndLoad(t *testing.T) {
+	resolved := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	checkout := lockfile.Entry{
+		Uses:     "actions/checkout",
+		Ref:      "v4",
+		SHA:      "11bd71901bbe5b1630ceea73d27597364c9af683",
+		Type:     "annotated-tag",
+		Resolved: resolved,
+	}
+	workflow := lockfile.Entry{
+		Uses:     "owner/repo/.github/workflows/ci.yml",
+		Ref:      "main",
+		SHA:      "2222222222222222222222222222222222222222",
+		Type:     "branch",
+		Resolved: resolved,
+	}
+
+	lock := &lockfile.Lockfile{}
+	lock.Add(workflow)
+	lock.Add(checkout)
+	lock.Add(checkout) // The same reference and SHA is only recorded once
+
+	path := lockfile.Path(t.TempDir())
+	require.NoError(t, lock.Write(path))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `# Generated by actlock. Do not edit by hand; run 'gh actlock' instead.
+version: 1
+actions:
+  - uses: actions/checkout
+    ref: v4
+    sha: 11bd71901bbe5b1630ceea73d27597364c9af683
+    type: annotated-tag
+    resolved: 2026-10-01T12:00:00Z
+  - uses: owner/repo/.github/workflows/ci.yml
+    ref: main
+    sha: "2222222222222222222222222222222222222222"
+    type: branch
+    resolved: 2026-10-01T12:00:00Z
+`, string(data))
+
+	loaded, err := lockfile.Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, []lockfile.Entry{checkout, workflow}, loaded.Actions)
+
+	found, ok := loaded.Find("actions/checkout", "v4", checkout.SHA)
+	assert.True(t, ok)
+	assert.Equal(t, checkout, found)
+	_, ok = loaded.Find("actions/checkout", "v4", workflow.SHA)
+	assert.False(t, ok)
+	assert.Len(t, loaded.Lookup("actions/checkout", "v4"), 1)
+}
+
+func TestLoad_Errors(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := lockfile.Load(filepath.Join(dir, "missing.lock"))
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+
+	path := filepath.Join(dir, "future.lock")
+	require.NoError(t, os.WriteFile(path, []byte("version: 2\n"), 0o600))
+	_, err = lockfile.Load(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "version 2")
+}
@@ -9,17 +9,16 @@ import (
 	"strings"
 
 	"gopkg.in/yaml.v3"
-
-	"github.com/esacteksab/gh-actlock/githubclient"
 )
 
 // WorkflowAction represents an action reference (uses: xxx/yyy@version)
 // This struct holds the parsed components of a GitHub Action reference.
 type WorkflowAction struct {
-	Name string // Owner or organization name
-	Repo string // Repository name (potentially including subpath)
-	Ref  string // Tag, branch, or SHA reference
-	Type string // Action type: "github", "docker", "local", or "unknown"
+	Name    string // Owner or organization name
+	Repo    string // Repository name (potentially including subpath)
+	Ref     string // Tag, branch, or SHA reference
+	Type    string // Action type: "github", "docker", "local", or "unknown"
+	Comment string // Trailing comment without the '#' (e.g., "v4" after a pinned SHA)
 }
 
 // Workflow represents the GitHub Actions workflow file structure
@@ -144,13 +143,20 @@ func ParseActionReference(uses string) (WorkflowAction, error) {
 		action.Type = "docker"
 		fullImage := uses[len("docker://"):] // Remove the "docker://" prefix
 
-		// Split the Docker image reference into name and tag parts
-		parts := strings.SplitN(fullImage, ":", 2) //nolint:mnd // Split at first colon
-		action.Repo = parts[0]                     // Store Docker image name in Repo field
+		// Split off a digest (image@sha256:...), which pins the image regardless of any tag
+		name, digest, hasDigest := strings.Cut(fullImage, "@")
+
+		// Split the Docker image reference into name and tag parts. The tag follows the
+		// last colon after the last slash, so a registry port (localhost:5000/image) isn't mistaken for one.
+		if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+			action.Ref = name[i+1:] // Store Docker tag in Ref field
+			name = name[:i]
+		}
+		action.Repo = name // Store Docker image name in Repo field
 
-		if len(parts) > 1 {
-			action.Ref = parts[1] // Store Docker tag in Ref field
-		} else {
+		if hasDigest {
+			action.Ref = digest // A digest takes precedence over the tag
+		} else if action.Ref == "" {
 			action.Ref = "latest" // Default Docker tag if none specified
 		}
 		return action, nil
@@ -189,6 +195,68 @@ func ParseActionReference(uses string) (WorkflowAction, error) {
 	return action, nil
 }
 
+// ParseActionNode parses the scalar value node of a "uses:" key, including the
+// inline comment that records which ref a pinned SHA tracks.
+//
+// - node: The YAML scalar node holding the action reference.
+//
+// Returns: A WorkflowAction struct with the parsed components and an error if parsing fails
+func ParseActionNode(node *yaml.Node) (WorkflowAction, error) {
+	action, err := ParseActionReference(node.Value)
+	// yaml.v3 keeps the comment's '#' and any spacing after it.
+	action.Comment = strings.TrimSpace(strings.TrimPrefix(node.LineComment, "#"))
+	return action, err
+}
+
+// TrackedRef returns the ref this reference names: the ref itself, or for a reference
+// pinned to a commit SHA, the first word of its inline comment. For a comment naming a
+// specific tag and the ref it came from, as in "v4.2.2 (v4)", that is the specific tag,
+// which the pinned SHA must match; see MovingRef for the ref it came from.
+//
+// Returns: The tracked ref, or an empty string if a pinned SHA has no comment.
+func (a WorkflowAction) TrackedRef() string {
+	if len(a.Ref) != SHALength || !IsHexString(a.Ref) {
+		return a.Ref
+	}
+	if fields := strings.Fields(a.Comment); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
+
+// MovingRef returns the ref this reference follows when it's updated. It is the
+// TrackedRef, except for a comment naming a specific tag and the ref it came from, as
+// in "v4.2.2 (v4)", which follows the latter: the pinned SHA matches v4.2.2, but v4 is
+// where updates come from.
+//
+// Returns: The moving ref, or an empty string if a pinned SHA has no comment.
+func (a WorkflowAction) MovingRef() string {
+	tracked := a.TrackedRef()
+	if len(a.Ref) != SHALength || !IsHexString(a.Ref) {
+		return tracked
+	}
+	fields := strings.Fields(a.Comment)
+	if len(fields) > 1 && strings.HasPrefix(fields[1], "(") && strings.HasSuffix(fields[1], ")") {
+		// Only a shorter form of the same version counts, so "main (pinned)" still follows main.
+		if moving := strings.Trim(fields[1], "()"); strings.HasPrefix(fields[0], moving+".") {
+			return moving
+		}
+	}
+	return tracked
+}
+
+// CommentRef returns the refs an inline comment of this reference names, in the form
+// they were written: "v4.2.2 (v4)" for a specific tag and its moving ref, or just the
+// TrackedRef otherwise.
+//
+// Returns: The comment's refs, or an empty string if a pinned SHA has no comment.
+func (a WorkflowAction) CommentRef() string {
+	if tracked, moving := a.TrackedRef(), a.MovingRef(); tracked != moving {
+		return tracked + " (" + moving + ")"
+	}
+	return a.TrackedRef()
+}
+
 // FindAllActions finds all action references in a workflow struct
 //
 // - workflow: The parsed workflow structure to analyze
@@ -232,6 +300,31 @@ func FindAllActions(workflow *Workflow) []WorkflowAction {
 	return actions
 }
 
+// SHALength is the standard length of a Git SHA-1 hash.
+const SHALength = 40
+
+// isHexDigit checks if a byte is a valid hexadecimal digit (0-9, a-f, A-F).
+//
+// - b: The byte to check.
+// Returns: true if the byte is a valid hex digit, false otherwise.
+func isHexDigit(b byte) bool {
+	return (b >= '0' && b <= '9') || (b >= 'a' && b <= 'f') || (b >= 'A' && b <= 'F')
+}
+
+// IsHexString checks if a string consists entirely of valid hexadecimal digits.
+// This is used to determine if a string is likely a Git SHA.
+//
+// - s: The string to check.
+// Returns: true if the string contains only hexadecimal characters, false otherwise.
+func IsHexString(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isHexDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
 // GetRefType determines the type of a Git reference string.
 //
 // - ref: The Git reference string to analyze
@@ -248,8 +341,8 @@ func GetRefType(ref string) string {
 	// --- Check for specific formats in order of specificity ---
 
 	// 1. Check if it's a full SHA (40 hexadecimal characters)
-	if refLength == githubclient.SHALength {
-		if githubclient.IsHexString(ref) {
+	if refLength == SHALength {
+		if IsHexString(ref) {
 			return "sha" // It's a full-length SHA hash
 		}
 		// If it's 40 characters but not hex, fall through to other checks
@@ -257,8 +350,8 @@ func GetRefType(ref string) string {
 
 	// 2. Check for short SHA (7-39 hexadecimal characters)
 	const minShortSHALength = 7 // GitHub often uses 7 characters as minimum for short SHAs
-	if refLength >= minShortSHALength && refLength < githubclient.SHALength {
-		if githubclient.IsHexString(ref) {
+	if refLength >= minShortSHALength && refLength < SHALength {
+		if IsHexString(ref) {
 			return "short_sha" // It's a shortened SHA hash
 		}
 		// If it's in the right length range but not hex, fall through
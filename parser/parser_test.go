@@ -210,3 +210,98 @@ func TestFindAllActions(t *testing.T) {
 		})
 	}
 }
+
+func TestParseActionReference_Docker(t *testing.T) {
+	tests := []struct {
+		name string
+		uses string
+		want WorkflowAction
+	}{
+		{
+			name: "tag",
+			uses: "docker://node:18-alpine",
+			want: WorkflowAction{Repo: "node", Ref: "18-alpine", Type: "docker"},
+		},
+		{
+			name: "default_tag",
+			uses: "docker://alpine",
+			want: WorkflowAction{Repo: "alpine", Ref: "latest", Type: "docker"},
+		},
+		{
+			name: "registry_with_port",
+			uses: "docker://localhost:5000/owner/image",
+			want: WorkflowAction{Repo: "localhost:5000/owner/image", Ref: "latest", Type: "docker"},
+		},
+		{
+			name: "digest",
+			uses: "docker://ghcr.io/owner/image:v1@sha256:abc",
+			want: WorkflowAction{Repo: "ghcr.io/owner/image", Ref: "sha256:abc", Type: "docker"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseActionReference(tt.uses)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseActionNode(t *testing.T) {
+	const sha = "11d5960a326750d5838078e36cf38b85af677262"
+	tests := []struct {
+		name        string
+		yaml        string
+		wantComment string
+		wantTracked string
+		wantMoving  string
+	}{
+		{
+			name:        "pinned_with_comment",
+			yaml:        "uses: actions/checkout@" + sha + "  # v4\n",
+			wantComment: "v4",
+			wantTracked: "v4",
+			wantMoving:  "v4",
+		},
+		{
+			name:        "pinned_with_extra_comment_text",
+			yaml:        "uses: actions/checkout@" + sha + " # main (pinned)\n",
+			wantComment: "main (pinned)",
+			wantTracked: "main",
+			wantMoving:  "main",
+		},
+		{
+			name:        "pinned_with_specific_tag",
+			yaml:        "uses: actions/checkout@" + sha + "  # v4.2.2 (v4)\n",
+			wantComment: "v4.2.2 (v4)",
+			wantTracked: "v4.2.2",
+			wantMoving:  "v4",
+		},
+		{
+			name:        "pinned_without_comment",
+			yaml:        "uses: actions/checkout@" + sha + "\n",
+			wantTracked: "",
+			wantMoving:  "",
+		},
+		{
+			name:        "tag_ignores_comment",
+			yaml:        "uses: actions/checkout@v4 # Known tag/branch\n",
+			wantComment: "Known tag/branch",
+			wantTracked: "v4",
+			wantMoving:  "v4",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := ParseWorkflowYAML("test.yml", []byte(tt.yaml))
+			assert.NoError(t, err)
+			valueNode := root.Content[0].Content[1]
+
+			action, err := ParseActionNode(valueNode)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantComment, action.Comment)
+			assert.Equal(t, tt.wantTracked, action.TrackedRef())
+			assert.Equal(t, tt.wantMoving, action.MovingRef())
+		})
+	}
+}
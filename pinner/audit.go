@@ -0,0 +1,247 @@
+// SPDX-License-Identifier: MIT
+
+package pinner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/esacteksab/gh-actlock/githubclient"
+)
+
+// AuditNode is a line of the audit tree: a file, or a reference and what it depends on.
+type AuditNode struct {
+	Label    string       // The file path, or the reference as written after 'uses:'
+	Notes    []string     // Problems and reasons the reference wasn't followed
+	Children []*AuditNode // The references it contains
+}
+
+// Print writes the node and its children as a tree.
+//
+// - w: Where to write the tree.
+func (n *AuditNode) Print(w io.Writer) {
+	n.print(w, "", "")
+}
+
+// print writes the node and its children as a tree.
+//
+// - w: Where to write the tree.
+// - prefix: What to write before the node's own label.
+// - childPrefix: What to write before the lines of the node's children.
+func (n *AuditNode) print(w io.Writer, prefix, childPrefix string) {
+	line := prefix + n.Label
+	for _, note := range n.Notes {
+		line += " [" + note + "]"
+	}
+	fmt.Fprintln(w, line)
+
+	for i, child := range n.Children {
+		if i == len(n.Children)-1 {
+			child.print(w, childPrefix+"└── ", childPrefix+"    ")
+		} else {
+			child.print(w, childPrefix+"├── ", childPrefix+"│   ")
+		}
+	}
+}
+
+// Auditor builds audit trees, counting the problems it finds.
+type Auditor struct {
+	Unpinned int // References not pinned to a commit SHA
+	Failed   int // Dependencies that couldn't be fetched or parsed
+
+	pinner   *Pinner // Scans the files; fetches dependencies only with a GitHub client
+	maxDepth int     // How many levels of dependencies to fetch below a local file's references
+}
+
+// NewAuditor creates an Auditor. The files it audits are scanned by a Pinner, and when
+// the Pinner has a GitHub client, the action.yml of every action and the file of every
+// reusable workflow they use are fetched at the pinned ref and audited too.
+//
+// - p: The Pinner that scans the files and fetches their dependencies.
+// - maxDepth: How many levels of dependencies to fetch, 0 for none.
+// Returns: The Auditor.
+func NewAuditor(p *Pinner, maxDepth int) *Auditor {
+	return &Auditor{pinner: p, maxDepth: maxDepth}
+}
+
+// Audit builds the tree of the actions and reusable workflows a workflow or action file
+// depends on, adding to the counts of problems found.
+//
+// - ctx: The context for API calls.
+// - filePath: The path of the file, inside the Pinner's root.
+// Returns: The tree, labeled with the file's path, and an error if the file can't be
+// read or parsed.
+func (a *Auditor) Audit(ctx context.Context, filePath string) (*AuditNode, error) {
+	scan, err := a.pinner.scanFile(filePath, true)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditNode{Label: filePath, Children: a.expand(ctx, scan, 0, nil)}, nil
+}
+
+// auditKey identifies a file the audit fetches, whichever reference led to it, so that
+// the same commit reached through a tag and through its SHA is recognized as a cycle.
+type auditKey struct {
+	owner string // The repository owner, lowercased
+	repo  string // The repository name, lowercased
+	sha   string // The commit the reference resolves to, or the ref itself if it can't be resolved
+	path  string // The action's directory or the reusable workflow's file, "." for the root
+}
+
+// expand builds the tree nodes for the references of a scanned file.
+//
+// - ctx: The context for API calls.
+// - scan: The scanned file, in check mode.
+// - depth: How many levels of dependencies were fetched to reach the references, 0 for
+// a local file's references.
+// - stack: The files being expanded above this one, for cycle detection; the last one is
+// the file scanned, which its ./ references are resolved against. Empty for a local file.
+// Returns: One node per action or reusable workflow reference.
+func (a *Auditor) expand(ctx context.Context, scan *fileScan, depth int, stack []auditKey) []*AuditNode {
+	refs := make(map[int]*reference, len(scan.refs))
+	for _, ref := range scan.refs {
+		refs[ref.entry] = ref
+	}
+
+	var nodes []*AuditNode
+	for i, entry := range scan.entries {
+		// A ./ reference in a fetched file points into the repository it was fetched from.
+		ref, remote := refs[i]
+		local := !remote && entry.Type == "local" && len(stack) > 0
+		if !remote && !local {
+			continue
+		}
+
+		node := &AuditNode{Label: entry.Uses}
+		nodes = append(nodes, node)
+		if remote && !ref.isSHA {
+			node.Notes = append(node.Notes, "unpinned")
+			a.Unpinned++
+		}
+		if a.pinner.resolver == nil {
+			continue
+		}
+
+		// Remote references are fetched at the ref they're written with, ./ ones at the
+		// commit of the file containing them.
+		var key auditKey
+		var err error
+		owner, repo, subpath, gitRef, workflow := entry.Owner, entry.Repo, entry.Subpath, "", false
+		if local {
+			key, err = localKey(stack[len(stack)-1], entry.Uses)
+			owner, repo, subpath, gitRef, workflow = key.owner, key.repo, key.path, key.sha, isWorkflowPath(key.path)
+		} else {
+			key = a.remoteKey(ctx, entry.Owner, ref)
+			gitRef, workflow = ref.action.Ref, ref.isWorkflow
+		}
+		if err == nil && slices.Contains(stack, key) {
+			node.Notes = append(node.Notes, "cycle")
+			continue
+		}
+		if err == nil && depth >= a.maxDepth {
+			node.Notes = append(node.Notes, "depth limit reached")
+			continue
+		}
+
+		var filePath string
+		var data []byte
+		if err == nil {
+			filePath, data, err = a.fetch(ctx, owner, repo, subpath, gitRef, workflow)
+		}
+		child := newFileScan(filePath)
+		if err == nil {
+			_, err = a.pinner.scanData(child, data, true)
+		}
+		if err != nil {
+			a.pinner.logger.Debugf("Could not audit %s: %v", entry.Uses, err)
+			node.Notes = append(node.Notes, "error: "+err.Error())
+			a.Failed++
+			continue
+		}
+		node.Children = a.expand(ctx, child, depth+1, append(stack, key))
+	}
+	return nodes
+}
+
+// remoteKey identifies the file a reference to another repository points to, resolving
+// the ref it is written with to a commit.
+//
+// - ctx: The context for API calls.
+// - owner: The repository owner.
+// - ref: The reference.
+// Returns: The key.
+func (a *Auditor) remoteKey(ctx context.Context, owner string, ref *reference) auditKey {
+	repo, subpath, _ := strings.Cut(ref.action.Repo, "/")
+	sha := ref.action.Ref
+	if !ref.isSHA {
+		resolved, err := a.pinner.resolver.ResolveRefToSHA(ctx, owner, repo, sha)
+		if err == nil {
+			sha = resolved
+		} else {
+			a.pinner.logger.Debugf("Could not resolve %s for the audit: %v", ref.value, err)
+		}
+	}
+	return auditKey{
+		owner: strings.ToLower(owner),
+		repo:  strings.ToLower(repo),
+		sha:   strings.ToLower(sha),
+		path:  path.Clean(subpath),
+	}
+}
+
+// localKey identifies the file a ./ reference points to: a path from the root of the
+// repository, at the commit, of the file containing it.
+//
+// - parent: The key of the file containing the reference.
+// - uses: The reference, e.g. "./.github/actions/setup".
+// Returns: The key, and an error if the path leaves the repository.
+func localKey(parent auditKey, uses string) (auditKey, error) {
+	p := path.Clean(uses)
+	if p == ".." || strings.HasPrefix(p, "../") {
+		return auditKey{}, fmt.Errorf("%s leaves %s/%s", uses, parent.owner, parent.repo)
+	}
+	return auditKey{owner: parent.owner, repo: parent.repo, sha: parent.sha, path: p}, nil
+}
+
+// isWorkflowPath reports whether a path names a reusable workflow rather than an
+// action's directory.
+//
+// - p: The path inside the repository.
+// Returns: true for a .yml or .yaml file.
+func isWorkflowPath(p string) bool {
+	return strings.HasSuffix(p, ".yml") || strings.HasSuffix(p, ".yaml")
+}
+
+// fetch retrieves the file a reference points to at a ref: the reusable workflow
+// itself, or the action's action.yml (or action.yaml).
+//
+// - ctx: The context for API calls.
+// - owner: The repository owner.
+// - repo: The repository name.
+// - subpath: The workflow file, or the action's directory, "." for the repository's root.
+// - gitRef: The ref to fetch the file at.
+// - workflow: Whether the reference is a reusable workflow.
+// Returns: A name for the file, its contents, and an error if it can't be retrieved.
+func (a *Auditor) fetch(ctx context.Context, owner, repo, subpath, gitRef string, workflow bool) (string, []byte, error) {
+	name := func(file string) string {
+		return owner + "/" + repo + "/" + file + "@" + gitRef
+	}
+
+	if workflow {
+		data, err := a.pinner.resolver.GetFileContent(ctx, owner, repo, subpath, gitRef)
+		return name(subpath), data, err
+	}
+	for _, file := range []string{"action.yml", "action.yaml"} {
+		file = path.Join(subpath, file)
+		data, err := a.pinner.resolver.GetFileContent(ctx, owner, repo, file, gitRef)
+		if !errors.Is(err, githubclient.ErrFileNotFound) {
+			return name(file), data, err
+		}
+	}
+	return "", nil, fmt.Errorf("no action.yml or action.yaml in %s@%s", path.Join(owner, repo, subpath), gitRef)
+}
@@ -0,0 +1,213 @@
+// SPDX-License-Identifier: MIT
+
+package pinner
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v82/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/esacteksab/gh-actlock/utils"
+)
+
+// auditSHA1 and auditSHA2 are the commits the audit fixtures are pinned to.
+var (
+	auditSHA1 = strings.Repeat("1", 40)
+	auditSHA2 = strings.Repeat("2", 40)
+)
+
+// auditPinner creates a Pinner whose client talks to a fake contents API.
+//
+// - files: "owner/repo/path@ref" -> the file's contents.
+// - tags: "owner/repo@tag" -> the commit the tag points to.
+// Returns: The Pinner, and its root.
+func auditPinner(t *testing.T, files, tags map[string]string) (*Pinner, string) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/repos/"), "/", 4) //nolint:mnd
+		if len(parts) == 4 && strings.HasPrefix(parts[3], "ref/tags/") {
+			if sha, ok := tags[parts[0]+"/"+parts[1]+"@"+strings.TrimPrefix(parts[3], "ref/tags/")]; ok {
+				_ = json.NewEncoder(w).Encode(map[string]any{ //nolint:errchkjson
+					"ref":    "refs/" + strings.TrimPrefix(parts[3], "ref/"),
+					"object": map[string]string{"type": "commit", "sha": sha},
+				})
+				return
+			}
+		}
+		if len(parts) != 4 || parts[2] != "contents" {
+			http.NotFound(w, r)
+			return
+		}
+		content, ok := files[parts[0]+"/"+parts[1]+"/"+parts[3]+"@"+r.URL.Query().Get("ref")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{ //nolint:errchkjson
+			"type":     "file",
+			"encoding": "base64",
+			"content":  base64.StdEncoding.EncodeToString([]byte(content)),
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	client := github.NewClient(nil)
+	baseURL, err := url.Parse(srv.URL + "/")
+	require.NoError(t, err)
+	client.BaseURL = baseURL
+
+	root := t.TempDir()
+	p, err := New(client, WithLogger(utils.Logger), WithRoot(root))
+	require.NoError(t, err)
+	return p, root
+}
+
+// auditTree scans a workflow depending on composite actions and a reusable workflow,
+// served by a fake contents API, and audits it down to maxDepth levels.
+//
+// - maxDepth: How many levels of dependencies to fetch.
+// Returns: The printed tree, and the auditor with its counts.
+func auditTree(t *testing.T, maxDepth int) (string, *Auditor) {
+	t.Helper()
+	utils.CreateLogger(false)
+
+	sha1, sha2 := auditSHA1, auditSHA2
+
+	// "owner/repo/path@ref" -> file contents
+	files := map[string]string{
+		"org/composite/action.yml@" + sha1: "runs:\n" +
+			"  using: composite\n" +
+			"  steps:\n" +
+			"    - uses: other/action@v1\n" +
+			"    - uses: org/loop@" + sha1 + "\n",
+		"other/action/action.yaml@v1": "runs:\n" +
+			"  using: composite\n" +
+			"  steps:\n" +
+			"    - uses: deep/one@" + sha1 + "\n",
+		"org/loop/action.yml@" + sha1: "runs:\n" +
+			"  using: composite\n" +
+			"  steps:\n" +
+			"    - uses: org/composite@" + sha1 + "\n",
+		"actions/checkout/action.yml@v4": "runs:\n  using: node20\n  main: dist/index.js\n",
+		"org/repo/.github/workflows/reuse.yml@" + sha2: "on: workflow_call\n" +
+			"jobs:\n" +
+			"  build:\n" +
+			"    steps:\n" +
+			"      - uses: missing/action@" + sha2 + "\n",
+	}
+	p, root := auditPinner(t, files, nil)
+
+	workflow := filepath.Join(root, "workflow.yml")
+	content := "jobs:\n" +
+		"  build:\n" +
+		"    steps:\n" +
+		"      - uses: org/composite@" + sha1 + "  # v1\n" +
+		"      - uses: actions/checkout@v4\n" +
+		"  reuse:\n" +
+		"    uses: org/repo/.github/workflows/reuse.yml@" + sha2 + "\n"
+	require.NoError(t, os.WriteFile(workflow, []byte(content), 0o600))
+
+	a := NewAuditor(p, maxDepth)
+	tree, err := a.Audit(context.Background(), workflow)
+	require.NoError(t, err)
+	tree.Label = "workflow.yml"
+	var out bytes.Buffer
+	tree.Print(&out)
+	return out.String(), a
+}
+
+func TestAuditTransitive(t *testing.T) {
+	sha1, sha2 := auditSHA1, auditSHA2
+	out, a := auditTree(t, 2)
+
+	assert.Equal(t, "workflow.yml\n"+
+		"├── org/composite@"+sha1+"\n"+
+		"│   ├── other/action@v1 [unpinned]\n"+
+		"│   │   └── deep/one@"+sha1+" [depth limit reached]\n"+
+		"│   └── org/loop@"+sha1+"\n"+
+		"│       └── org/composite@"+sha1+" [cycle]\n"+
+		"├── actions/checkout@v4 [unpinned]\n"+
+		"└── org/repo/.github/workflows/reuse.yml@"+sha2+"\n"+
+		"    └── missing/action@"+sha2+" [error: no action.yml or action.yaml in missing/action@"+sha2+"]\n",
+		out)
+	assert.Equal(t, 2, a.Unpinned)
+	assert.Equal(t, 1, a.Failed)
+}
+
+func TestAuditTransitive_DepthOne(t *testing.T) {
+	sha1, sha2 := auditSHA1, auditSHA2
+	out, a := auditTree(t, 1)
+
+	// --depth 1 shows the direct dependencies of the workflow's references, and no further.
+	assert.Equal(t, "workflow.yml\n"+
+		"├── org/composite@"+sha1+"\n"+
+		"│   ├── other/action@v1 [unpinned] [depth limit reached]\n"+
+		"│   └── org/loop@"+sha1+" [depth limit reached]\n"+
+		"├── actions/checkout@v4 [unpinned]\n"+
+		"└── org/repo/.github/workflows/reuse.yml@"+sha2+"\n"+
+		"    └── missing/action@"+sha2+" [depth limit reached]\n",
+		out)
+	assert.Equal(t, 2, a.Unpinned)
+	assert.Equal(t, 0, a.Failed)
+}
+
+func TestAuditTransitive_CommitsAndLocalReferences(t *testing.T) {
+	utils.CreateLogger(false)
+	sha1, sha3 := auditSHA1, strings.Repeat("3", 40)
+
+	files := map[string]string{
+		// v1 is sha3: the action's reference to itself by SHA is the same commit.
+		"org/tool/action.yml@v1": "runs:\n" +
+			"  using: composite\n" +
+			"  steps:\n" +
+			"    - uses: org/tool@" + sha3 + "\n" +
+			"    - uses: ./.github/actions/setup\n" +
+			"    - uses: ../outside\n",
+		// ./ references are fetched from the action's own repository, at its commit.
+		"org/tool/.github/actions/setup/action.yml@" + sha3: "runs:\n" +
+			"  using: composite\n" +
+			"  steps:\n" +
+			"    - uses: deep/one@" + sha1 + "\n",
+		"deep/one/action.yml@" + sha1: "runs:\n  using: node20\n  main: index.js\n",
+	}
+	p, root := auditPinner(t, files, map[string]string{"org/tool@v1": sha3})
+
+	// The local file's own ./ references point into the project, which isn't fetched.
+	workflow := filepath.Join(root, "workflow.yml")
+	content := "jobs:\n" +
+		"  build:\n" +
+		"    steps:\n" +
+		"      - uses: ./local\n" +
+		"      - uses: org/tool@v1\n"
+	require.NoError(t, os.WriteFile(workflow, []byte(content), 0o600))
+
+	a := NewAuditor(p, 5) //nolint:mnd
+	tree, err := a.Audit(context.Background(), workflow)
+	require.NoError(t, err)
+	tree.Label = "workflow.yml"
+	var out bytes.Buffer
+	tree.Print(&out)
+
+	assert.Equal(t, "workflow.yml\n"+
+		"└── org/tool@v1 [unpinned]\n"+
+		"    ├── org/tool@"+sha3+" [cycle]\n"+
+		"    ├── ./.github/actions/setup\n"+
+		"    │   └── deep/one@"+sha1+"\n"+
+		"    └── ../outside [error: ../outside leaves org/tool]\n",
+		out.String())
+	assert.Equal(t, 1, a.Unpinned)
+	assert.Equal(t, 1, a.Failed)
+}
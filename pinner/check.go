@@ -0,0 +1,63 @@
+// SPDX-License-Identifier: MIT
+
+package pinner
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// Finding is a problem with a reference, reported at the line it is on.
+type Finding struct {
+	Line    int    // The line number of the reference
+	Message string // What is wrong with the reference
+}
+
+// CheckResult is what Check found in a file.
+type CheckResult struct {
+	Unpinned   []Finding // The references not pinned to a commit SHA, top to bottom
+	Impostors  []Finding // The references pinned to a commit that doesn't belong to their repository
+	Unverified int       // The pinned commits that don't exist, or couldn't be verified for any reason
+}
+
+// Check reports the references of a workflow or action file that aren't pinned to a
+// full commit SHA, without modifying the file. When the Pinner has a GitHub client,
+// the commit of every pinned reference is also checked to be reachable from a branch
+// or tag of its repository; without one, no API calls are made.
+//
+// - ctx: The context for API calls.
+// - filePath: The path of the file, inside the Pinner's root.
+// Returns: What was found, and an error if the file can't be read or parsed.
+func (p *Pinner) Check(ctx context.Context, filePath string) (*CheckResult, error) {
+	// Check mode makes the walker collect unpinned references instead of resolving them.
+	scan, err := p.scanFile(filePath, true)
+	if err != nil {
+		return nil, err
+	}
+	unpinned := scan.updates
+
+	// Sort the line numbers so the report reads top to bottom.
+	lines := make([]int, 0, len(unpinned))
+	for line := range unpinned {
+		lines = append(lines, line)
+	}
+	slices.Sort(lines)
+
+	result := &CheckResult{}
+	for _, line := range lines {
+		result.Unpinned = append(result.Unpinned,
+			Finding{Line: line, Message: fmt.Sprintf("%s is not pinned to a commit SHA", unpinned[line])})
+	}
+
+	// The commits are only verified with a client, as it is the one part of a check that needs the API.
+	if p.resolver != nil {
+		found, failed := p.findImpostors(ctx, scan)
+		for _, ref := range found {
+			result.Impostors = append(result.Impostors,
+				Finding{Line: ref.line, Message: impostorMessage(&scan.entries[ref.entry], ref)})
+		}
+		result.Unverified = failed
+	}
+	return result, nil
+}
@@ -0,0 +1,32 @@
+// SPDX-License-Identifier: MIT
+
+package pinner
+
+import (
+	"strings"
+
+	"github.com/esacteksab/gh-actlock/githubclient"
+)
+
+// updatePolicyFor returns the update policy for an action: the one configured for it,
+// or else the Pinner's.
+//
+// - owner: The repository owner.
+// - repo: The repository name, without any subpath.
+// Returns: The update policy to apply.
+func (p *Pinner) updatePolicyFor(owner, repo string) githubclient.UpdatePolicy {
+	if policy, ok := p.config.PolicyFor(owner, repo); ok {
+		return githubclient.UpdatePolicy(policy)
+	}
+	return p.policy
+}
+
+// pinnedValue formats a pinned reference followed by the ref it tracks as an inline
+// comment, using the configured spacing (e.g., "actions/checkout@<sha>  # v4").
+//
+// - value: The pinned reference, e.g. "owner/repo@sha" or "image@sha256:...".
+// - ref: The ref to record in the comment.
+// Returns: The value to write after the YAML key.
+func (p *Pinner) pinnedValue(value, ref string) string {
+	return value + strings.Repeat(" ", p.config.CommentSpaces()) + "# " + ref
+}
@@ -0,0 +1,159 @@
+// SPDX-License-Identifier: MIT
+
+package pinner
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/esacteksab/gh-actlock/registry"
+	"github.com/esacteksab/gh-actlock/report"
+)
+
+const dockerPrefix = "docker://"
+
+// addDockerReference records an image reference, either from 'uses: docker://image:tag'
+// or a job's container or service image, so it can be pinned to the manifest digest the
+// tag currently points to. References that already carry a digest are left alone.
+//
+// - scan: The scan of the file being walked; its last report entry is the reference's.
+// - image: The image reference, without any docker:// prefix.
+// - prefix: The prefix to put back in front of the pinned image (e.g., "docker://"), may be empty.
+// - lineNum: The line number in the workflow file where this reference appears.
+func (p *Pinner) addDockerReference(scan *fileScan, image, prefix string, lineNum int) {
+	entry := &scan.entries[len(scan.entries)-1]
+	ref, err := registry.ParseReference(image)
+	if err != nil {
+		p.logger.Errorf("❌  Skipping pin for image '%s' on line %d: %v", image, lineNum, err)
+		markFailed(entry, err)
+		return // Continue processing other references
+	}
+
+	// Record the image and tag being resolved.
+	entry.Repo = ref.Name
+	entry.OldRef = ref.Tag
+
+	// A digest is already immutable, so there is nothing to do.
+	if ref.Digest != "" {
+		p.logger.Debugf("ℹ️  Image '%s' on line %d already pinned to digest: %s", image, lineNum, ref.Digest)
+		entry.Action = report.ActionUnchanged
+		entry.ResolvedSHA = ref.Digest
+		return // Already pinned, no update needed
+	}
+
+	scan.refs = append(scan.refs, &reference{
+		entry:  len(scan.entries) - 1,
+		line:   lineNum,
+		value:  image,
+		prefix: prefix,
+		key:    &resolveKey{kind: resolveImage, image: ref},
+	})
+}
+
+// handleDockerReference applies the resolution of an image reference recorded by
+// addDockerReference, pinning it to its digest and keeping the tag as a comment.
+//
+// - ref: The image reference found while scanning.
+// - res: The resolution of the image's tag to a digest.
+// - scan: The scan of the file the reference is in, which receives the update.
+//
+// Returns: An error if a critical operation fails, otherwise nil.
+func (p *Pinner) handleDockerReference(ref *reference, res resolution, scan *fileScan) error {
+	entry := &scan.entries[ref.entry]
+	image := ref.key.image
+	if res.err != nil {
+		p.logger.Errorf("❌  Error resolving digest for image %s:%s: %v. Skipping update for line %d.",
+			image.Name, image.Tag, res.err, ref.line)
+		markFailed(entry, res.err)
+		return nil // Continue processing other references
+	}
+
+	// Create the new image reference string with digest + comment
+	newValue := p.pinnedValue(ref.prefix+image.Name+"@"+res.sha, image.Tag)
+	p.logger.Debugf("  Pinned image %s:%s to digest %s", image.Name, image.Tag, res.sha)
+
+	// Store the update in the map and increment counter
+	scan.updates[ref.line] = newValue
+	scan.updatesMade++
+	entry.Action = report.ActionPinned
+	entry.ResolvedSHA = res.sha
+	entry.CommentRef = image.Tag
+
+	return nil
+}
+
+// findImageUpdates looks for the images of job containers and service containers
+// (jobs.<job>.container, jobs.<job>.container.image and jobs.<job>.services.<name>.image)
+// and records each of them to be pinned to a digest. Images built from expressions are skipped.
+//
+// - node: The top-level mapping node of the workflow.
+// - scan: The scan of the file being walked, which receives one report entry per image found.
+func (p *Pinner) findImageUpdates(node *yaml.Node, scan *fileScan) {
+	jobs := mappingValue(node, "jobs")
+	if jobs == nil || jobs.Kind != yaml.MappingNode {
+		return
+	}
+
+	var images []*yaml.Node
+	// addImage collects an image, unless its mapping is a flow mapping such as
+	// '{image: node:20}': rewriting its line would lose the rest of the mapping.
+	addImage := func(mapping, image *yaml.Node) {
+		if isFlowStyle(mapping) {
+			p.logger.Errorf("⚠️ Skipping image on line %d: images in flow mappings aren't rewritten", image.Line)
+			return
+		}
+		images = append(images, image)
+	}
+	for i := 1; i < len(jobs.Content); i += 2 {
+		job := jobs.Content[i]
+
+		// 'container' is either the image itself or a mapping with an 'image' key.
+		if container := mappingValue(job, "container"); container != nil {
+			if container.Kind == yaml.ScalarNode {
+				addImage(job, container)
+			} else if image := mappingValue(container, "image"); image != nil {
+				addImage(container, image)
+			}
+		}
+
+		if services := mappingValue(job, "services"); services != nil && services.Kind == yaml.MappingNode {
+			for j := 1; j < len(services.Content); j += 2 {
+				if image := mappingValue(services.Content[j], "image"); image != nil {
+					addImage(services.Content[j], image)
+				}
+			}
+		}
+	}
+
+	for _, image := range images {
+		// Expressions like ${{ matrix.image }} are only known at run time.
+		if image.Kind != yaml.ScalarNode || image.Value == "" || strings.Contains(image.Value, "${{") {
+			continue
+		}
+		if scan.pending(image.Line) {
+			continue // This line is already scheduled for an update
+		}
+
+		scan.addEntry(report.Entry{Line: image.Line, Uses: image.Value, Type: "container"})
+		scan.columns[image.Line] = image.Column
+		p.addDockerReference(scan, image.Value, "", image.Line)
+	}
+}
+
+// mappingValue returns the value node for a key in a mapping node.
+//
+// - node: The mapping node to search, may be nil or of another kind.
+// - key: The key to look up.
+// Returns: The value node, or nil if node isn't a mapping or has no such key.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
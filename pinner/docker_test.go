@@ -0,0 +1,117 @@
+// SPDX-License-Identifier: MIT
+
+package pinner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-github/v82/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/esacteksab/gh-actlock/registry"
+	"github.com/esacteksab/gh-actlock/registry/registrytest"
+	"github.com/esacteksab/gh-actlock/report"
+	"github.com/esacteksab/gh-actlock/utils"
+)
+
+func TestPinDockerReferences(t *testing.T) {
+	utils.CreateLogger(false)
+
+	srv := registrytest.NewServer()
+	defer srv.Close()
+	srv.RequireAuth = true
+	digest := srv.SetTag("owner/image", "v1", "first")
+
+	root := t.TempDir()
+	// The GitHub client is never used for docker:// references.
+	p, err := New(github.NewClient(nil), WithLogger(utils.Logger), WithRegistry(registry.NewClient(nil)), WithRoot(root))
+	require.NoError(t, err)
+
+	pinned := "docker://" + srv.Host() + "/owner/image@" + digest + "  # v1"
+	workflow := filepath.Join(root, "workflow.yml")
+	content := "jobs:\n" +
+		"  build:\n" +
+		"    steps:\n" +
+		"      - uses: docker://" + srv.Host() + "/owner/image:v1\n" +
+		"      - uses: docker://" + srv.Host() + "/owner/image@" + digest + "\n" +
+		"      - uses: docker://" + srv.Host() + "/owner/image:missing\n"
+	require.NoError(t, os.WriteFile(workflow, []byte(content), 0o600))
+
+	scan, err := p.scanFile(workflow, false)
+	require.NoError(t, err)
+	p.apply(scan, p.resolveAll(context.Background(), []*fileScan{scan}))
+
+	assert.Equal(t, map[int]string{4: pinned}, scan.updates)
+	assert.Equal(t, 1, scan.updatesMade)
+
+	require.Len(t, scan.entries, 3)
+	assert.Equal(t, report.ActionPinned, scan.entries[0].Action)
+	assert.Equal(t, digest, scan.entries[0].ResolvedSHA)
+	assert.Equal(t, "v1", scan.entries[0].CommentRef)
+	assert.Equal(t, report.ActionUnchanged, scan.entries[1].Action)
+	assert.Equal(t, report.ActionError, scan.entries[2].Action)
+
+	updated, err := p.applyUpdatesToLines(string(scan.data), scan.updates, scan.columns)
+	require.NoError(t, err)
+	assert.Contains(t, updated, "      - uses: "+pinned+"\n")
+}
+
+func TestPinContainerImages(t *testing.T) {
+	utils.CreateLogger(false)
+
+	srv := registrytest.NewServer()
+	defer srv.Close()
+	appDigest := srv.SetTag("owner/app", "v1", "app")
+	dbDigest := srv.SetTag("owner/postgres", "16", "db")
+
+	root := t.TempDir()
+	// The GitHub client is never used for docker:// references.
+	p, err := New(github.NewClient(nil), WithLogger(utils.Logger), WithRegistry(registry.NewClient(nil)), WithRoot(root))
+	require.NoError(t, err)
+
+	host := srv.Host()
+	workflow := filepath.Join(root, "workflow.yml")
+	content := "jobs:\n" +
+		"  build:\n" +
+		"    container: " + host + "/owner/app:v1\n" +
+		"    services:\n" +
+		"      db:\n" +
+		"        image: " + host + "/owner/postgres:16  # database\n" +
+		"      cache:\n" +
+		"        image: ${{ matrix.cache }}\n" +
+		"  test:\n" +
+		"    container:\n" +
+		"      image: " + host + "/owner/app@" + appDigest + "\n"
+	require.NoError(t, os.WriteFile(workflow, []byte(content), 0o600))
+
+	scan, err := p.scanFile(workflow, false)
+	require.NoError(t, err)
+	p.apply(scan, p.resolveAll(context.Background(), []*fileScan{scan}))
+	assert.Equal(t, 2, scan.updatesMade)
+
+	// Expressions are skipped and don't produce an entry.
+	require.Len(t, scan.entries, 3)
+	for _, entry := range scan.entries {
+		assert.Equal(t, "container", entry.Type)
+	}
+	assert.Equal(t, report.ActionUnchanged, scan.entries[2].Action)
+
+	updated, err := p.applyUpdatesToLines(string(scan.data), scan.updates, scan.columns)
+	require.NoError(t, err)
+	want := "jobs:\n" +
+		"  build:\n" +
+		"    container: " + host + "/owner/app@" + appDigest + "  # v1\n" +
+		"    services:\n" +
+		"      db:\n" +
+		"        image: " + host + "/owner/postgres@" + dbDigest + "  # 16\n" +
+		"      cache:\n" +
+		"        image: ${{ matrix.cache }}\n" +
+		"  test:\n" +
+		"    container:\n" +
+		"      image: " + host + "/owner/app@" + appDigest + "\n"
+	assert.Equal(t, want, updated)
+}
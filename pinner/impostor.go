@@ -0,0 +1,149 @@
+// SPDX-License-Identifier: MIT
+
+package pinner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/esacteksab/gh-actlock/githubclient"
+	"github.com/esacteksab/gh-actlock/report"
+)
+
+// reachableKey returns the lookup that checks an already pinned reference's commit
+// belongs to its repository, rather than to a fork in the same network.
+//
+// - entry: The report entry of the reference, which holds the repository.
+// - ref: The reference, pinned to a full commit SHA.
+// Returns: The lookup key.
+func reachableKey(entry *report.Entry, ref *reference) *resolveKey {
+	return &resolveKey{kind: resolveReachable, owner: entry.Owner, repo: entry.Repo, ref: ref.action.Ref}
+}
+
+// impostorMessage describes a reference pinned to a commit that doesn't belong to its repository.
+//
+// - entry: The report entry of the reference.
+// - ref: The reference.
+// Returns: The message.
+func impostorMessage(entry *report.Entry, ref *reference) string {
+	return fmt.Sprintf(
+		"%s is pinned to a commit that is not reachable from any branch or tag of %s/%s (possible impostor commit)",
+		ref.value, entry.Owner, entry.Repo)
+}
+
+// missingCommitMessage describes a reference pinned to a commit that doesn't exist.
+//
+// - entry: The report entry of the reference.
+// - ref: The reference.
+// Returns: The message.
+func missingCommitMessage(entry *report.Entry, ref *reference) string {
+	return fmt.Sprintf("%s is pinned to a commit that doesn't exist in %s/%s", ref.value, entry.Owner, entry.Repo)
+}
+
+// flagImpostor records the outcome of checking an already pinned reference's commit.
+// A commit that couldn't be checked fails like one that doesn't exist: the check is a
+// security gate, so it mustn't pass on an error.
+//
+// - ref: The pinned reference.
+// - res: The resolution of its resolveReachable lookup.
+// - entry: The report entry of the reference.
+// Returns: true if the commit is an impostor, doesn't exist, or couldn't be verified,
+// and the reference must not be treated as pinned.
+func (p *Pinner) flagImpostor(ref *reference, res resolution, entry *report.Entry) bool {
+	switch {
+	case errors.Is(res.err, githubclient.ErrImpostorCommit):
+		p.logger.Errorf("🚨  Line %d: %s", ref.line, impostorMessage(entry, ref))
+		entry.Action = report.ActionImpostor
+		entry.Error = res.err.Error()
+		return true
+	case errors.Is(res.err, githubclient.ErrCommitNotFound):
+		p.logger.Errorf("❌  Line %d: %s", ref.line, missingCommitMessage(entry, ref))
+		markFailed(entry, res.err)
+		return true
+	case res.err != nil:
+		p.logger.Errorf("❌  Could not verify the commit of '%s' on line %d: %v", ref.value, ref.line, res.err)
+		markFailed(entry, res.err)
+		return true
+	}
+	return false
+}
+
+// countUnverified counts the already pinned references whose commit couldn't be shown
+// to belong to its repository, other than impostors: commits that don't exist, and
+// checks that failed for any reason, including offline misses and rate limits.
+//
+// - scans: The scans, after their resolutions were applied.
+// - resolved: The resolutions returned by resolveAll.
+// Returns: The number of unverified commits.
+func countUnverified(scans []*fileScan, resolved map[resolveKey]resolution) int {
+	count := 0
+	for _, scan := range scans {
+		if scan == nil {
+			continue
+		}
+		for _, ref := range scan.refs {
+			if ref.key == nil || ref.key.kind != resolveReachable {
+				continue
+			}
+			if err := resolved[*ref.key].err; err != nil && !errors.Is(err, githubclient.ErrImpostorCommit) {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// countImpostors counts the references found to be pinned to impostor commits.
+//
+// - scans: The scans, after their resolutions were applied.
+// Returns: The number of impostor commits.
+func countImpostors(scans []*fileScan) int {
+	count := 0
+	for _, scan := range scans {
+		if scan == nil {
+			continue
+		}
+		for _, entry := range scan.entries {
+			if entry.Action == report.ActionImpostor {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// findImpostors checks that the commit of every pinned action and workflow in a scan made
+// in check mode belongs to its repository.
+//
+// - ctx: The context for API calls.
+// - scan: The scan, made with checkOnly set.
+// Returns: The references pinned to impostor commits, in file order, and the number of
+// commits that don't exist or couldn't be verified for any reason.
+func (p *Pinner) findImpostors(ctx context.Context, scan *fileScan) ([]*reference, int) {
+	for _, ref := range scan.refs {
+		if entry := &scan.entries[ref.entry]; ref.isSHA && entry.Repo != "" {
+			ref.key = reachableKey(entry, ref)
+		}
+	}
+	resolved := p.resolveAll(ctx, []*fileScan{scan})
+
+	var impostors []*reference
+	unverified := 0
+	for _, ref := range scan.refs {
+		if ref.key == nil {
+			continue
+		}
+		switch res := resolved[*ref.key]; {
+		case errors.Is(res.err, githubclient.ErrImpostorCommit):
+			impostors = append(impostors, ref)
+		case errors.Is(res.err, githubclient.ErrCommitNotFound):
+			p.logger.Errorf("❌  Line %d: %s", ref.line, missingCommitMessage(&scan.entries[ref.entry], ref))
+			unverified++
+		case res.err != nil:
+			p.logger.Errorf("❌  Could not verify the commit of '%s' on line %d: %v", ref.value, ref.line, res.err)
+			unverified++
+		}
+	}
+	return impostors, unverified
+}
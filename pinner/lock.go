@@ -0,0 +1,108 @@
+// SPDX-License-Identifier: MIT
+
+package pinner
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"time"
+
+	"github.com/esacteksab/gh-actlock/githubclient"
+	"github.com/esacteksab/gh-actlock/lockfile"
+	"github.com/esacteksab/gh-actlock/report"
+)
+
+// updateLockfile records every action and reusable workflow reference that ended up
+// pinned in the project's lockfile. Entries whose reference and SHA haven't changed
+// keep the time they were first resolved, so the lockfile only changes when a pin does.
+//
+// - scans: The scanned and applied files.
+// - resolved: The resolutions returned by resolveAll for the scans.
+// - merge: Whether to keep previous entries for references not seen in this run, for
+// runs limited to some of the files.
+// Returns: The path of the lockfile, and an error if it can't be read or written.
+func (p *Pinner) updateLockfile(scans []*fileScan, resolved map[resolveKey]resolution, merge bool) (string, error) {
+	path := lockfile.Path(p.root)
+	previous, err := lockfile.Load(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return path, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	lock := &lockfile.Lockfile{}
+	for _, scan := range scans {
+		for _, ref := range scan.refs {
+			entry, ok := lockEntry(scan, ref, resolved, now)
+			if !ok {
+				continue
+			}
+			if prev, found := previous.Find(entry.Uses, entry.Ref, entry.SHA); found {
+				entry.Resolved = prev.Resolved
+				// A pin that wasn't looked up this run keeps the kind it was resolved through.
+				if entry.Type == string(githubclient.KindCommit) || entry.Type == "" {
+					entry.Type = prev.Type
+				}
+				// A commit found to belong to its repository keeps belonging to it.
+				entry.Verified = entry.Verified || prev.Verified
+			}
+			lock.Add(entry)
+		}
+	}
+
+	if merge && previous != nil {
+		for _, prev := range previous.Actions {
+			if len(lock.Lookup(prev.Uses, prev.Ref)) == 0 {
+				lock.Add(prev)
+			}
+		}
+	}
+
+	if err := lock.Write(path); err != nil {
+		return path, fmt.Errorf("failed to update lockfile: %w", err)
+	}
+	return path, nil
+}
+
+// lockEntry builds the lockfile entry for a reference.
+//
+// - scan: The scan the reference was found in.
+// - ref: The reference.
+// - resolved: The resolutions returned by resolveAll.
+// - now: The time to record for the resolution.
+// Returns: The entry, and false if the reference isn't an action or workflow pinned to a SHA.
+// The entry is Verified when its SHA was resolved from one of the repository's tags or
+// branches, or checked to be reachable from one (see WithVerifyCommits).
+func lockEntry(
+	scan *fileScan,
+	ref *reference,
+	resolved map[resolveKey]resolution,
+	now time.Time,
+) (lockfile.Entry, bool) {
+	if ref.action.Type != "github" {
+		return lockfile.Entry{}, false
+	}
+	entry := scan.entries[ref.entry]
+	switch entry.Action {
+	case report.ActionPinned, report.ActionUpdated, report.ActionUnchanged:
+	default:
+		return lockfile.Entry{}, false
+	}
+
+	locked := lockfile.Entry{Uses: entry.Owner + "/" + ref.action.Repo, Resolved: now}
+	if ref.key != nil && ref.key.kind != resolveReachable {
+		res := resolved[*ref.key]
+		locked.Ref, locked.SHA, locked.Type = res.ref, res.sha, string(res.kind)
+		locked.Verified = res.kind != githubclient.KindCommit
+	} else {
+		// Already pinned: the comment says which ref the SHA came from.
+		locked.Ref, locked.SHA = ref.action.MovingRef(), ref.action.Ref
+		locked.Type = string(githubclient.KindCommit)
+		// Its commit is only known to belong to the repository if it was checked.
+		if ref.key != nil {
+			res, checked := resolved[*ref.key]
+			locked.Verified = checked && res.err == nil
+		}
+	}
+	return locked, true
+}
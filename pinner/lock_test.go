@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: MIT
+
+package pinner
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/esacteksab/gh-actlock/lockfile"
+	"github.com/esacteksab/gh-actlock/utils"
+)
+
+func TestUpdateLockfile(t *testing.T) {
+	utils.CreateLogger(false)
+
+	root := t.TempDir()
+	p, err := New(nil, WithLogger(utils.Logger), WithRoot(root))
+	require.NoError(t, err)
+
+	const checkoutSHA = "11bd71901bbe5b1630ceea73d27597364c9af683"
+	const cacheSHA = "2222222222222222222222222222222222222222"
+	workflow := filepath.Join(root, "workflow.yml")
+	content := "jobs:\n" +
+		"  build:\n" +
+		"    steps:\n" +
+		"      - uses: actions/checkout@" + checkoutSHA + "  # v4\n" +
+		"      - uses: actions/cache@" + cacheSHA + "\n" +
+		"      - uses: ./local-action\n"
+	require.NoError(t, os.WriteFile(workflow, []byte(content), 0o600))
+
+	// The checkout pin was resolved in an earlier run; an entry for a removed action is stale.
+	earlier := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	previous := &lockfile.Lockfile{}
+	previous.Add(lockfile.Entry{
+		Uses: "actions/checkout", Ref: "v4", SHA: checkoutSHA, Type: "annotated-tag", Resolved: earlier,
+	})
+	previous.Add(lockfile.Entry{
+		Uses: "actions/removed", Ref: "v1", SHA: cacheSHA, Type: "branch", Resolved: earlier,
+	})
+	require.NoError(t, previous.Write(lockfile.Path(root)))
+
+	// Pin mode: both references are already pinned, so nothing needs resolving.
+	scan, err := p.scanFile(workflow, false)
+	require.NoError(t, err)
+	p.apply(scan, nil)
+
+	path, err := p.updateLockfile([]*fileScan{scan}, nil, false)
+	require.NoError(t, err)
+	lock, err := lockfile.Load(path)
+	require.NoError(t, err)
+
+	require.Len(t, lock.Actions, 2)
+	// The unchanged pin keeps when and how it was resolved.
+	assert.Equal(t, previous.Actions[0], lock.Actions[1])
+	// A pin without a comment is recorded as a bare commit.
+	assert.Equal(t, "actions/cache", lock.Actions[0].Uses)
+	assert.Empty(t, lock.Actions[0].Ref)
+	assert.Equal(t, cacheSHA, lock.Actions[0].SHA)
+	assert.Equal(t, "commit", lock.Actions[0].Type)
+
+	// It is verified against the workflow it was built from.
+	for _, ref := range scan.refs {
+		assert.Empty(t, verifyReference(lock, scan, ref))
+	}
+
+	// A run limited to some files keeps the entries of the others.
+	require.NoError(t, previous.Write(path))
+	_, err = p.updateLockfile([]*fileScan{scan}, nil, true)
+	require.NoError(t, err)
+	lock, err = lockfile.Load(path)
+	require.NoError(t, err)
+	require.Len(t, lock.Actions, 3)
+	assert.Equal(t, "actions/removed", lock.Actions[2].Uses)
+}
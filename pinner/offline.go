@@ -0,0 +1,82 @@
+// SPDX-License-Identifier: MIT
+
+package pinner
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/esacteksab/gh-actlock/githubclient"
+	"github.com/esacteksab/gh-actlock/lockfile"
+)
+
+// lockedResolution answers a lookup from the lockfile loaded for offline mode. Only
+// pinning a ref and checking a pinned commit can be answered: the lockfile records
+// what refs were resolved to, not which refs exist.
+//
+// - key: The lookup.
+// Returns: The resolution, and false if the lockfile can't answer the lookup.
+func (p *Pinner) lockedResolution(key resolveKey) (resolution, bool) {
+	if p.lock == nil || key.ref == "" {
+		return resolution{}, false
+	}
+
+	var found *lockfile.Entry
+	for i, e := range p.lock.Actions {
+		// The lockfile records owner/repo[/path]; lookups are per repository.
+		if e.Uses != key.owner+"/"+key.repo && !strings.HasPrefix(e.Uses, key.owner+"/"+key.repo+"/") {
+			continue
+		}
+		switch key.kind {
+		case resolvePin:
+			// The same ref may have been locked at different commits in different files.
+			if e.Ref == key.ref && (found == nil || e.Resolved.After(found.Resolved)) {
+				found = &p.lock.Actions[i]
+			}
+		case resolveReachable:
+			// Pins that were never checked are locked too; only verified ones answer.
+			if e.SHA == key.ref && e.Verified {
+				return resolution{sha: key.ref, kind: githubclient.KindCommit}, true
+			}
+		}
+	}
+	if found == nil {
+		return resolution{}, false
+	}
+	p.logger.Debugf("Offline: %s/%s@%s is locked at %s", key.owner, key.repo, key.ref, found.SHA)
+	return resolution{ref: key.ref, sha: found.SHA, kind: githubclient.RefKind(found.Type)}, true
+}
+
+// offlineMiss reports whether a lookup failed because its answer isn't available offline.
+//
+// - res: The resolution of the lookup.
+// Returns: true if the lookup needed a response that isn't in the cache.
+func offlineMiss(res resolution) bool {
+	return errors.Is(res.err, githubclient.ErrOffline)
+}
+
+// countOfflineMisses counts the lookups that failed because they weren't available offline.
+//
+// - resolved: The resolutions returned by resolveAll.
+// Returns: The number of misses.
+func countOfflineMisses(resolved map[resolveKey]resolution) int {
+	misses := 0
+	for _, res := range resolved {
+		if offlineMiss(res) {
+			misses++
+		}
+	}
+	return misses
+}
+
+// OfflineMissError describes the lookups that failed offline, such as the OfflineMisses
+// of a Run.
+//
+// - misses: The number of misses.
+// Returns: The error.
+func OfflineMissError(misses int) error {
+	return fmt.Errorf(
+		"%d reference(s) could not be resolved offline: they are in neither the lockfile nor the cache "+
+			"(run once with network access to fill the cache)", misses)
+}
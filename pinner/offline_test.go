@@ -0,0 +1,102 @@
+// SPDX-License-Identifier: MIT
+
+package pinner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/esacteksab/gh-actlock/githubclient"
+	"github.com/esacteksab/gh-actlock/lockfile"
+	"github.com/esacteksab/gh-actlock/utils"
+)
+
+func TestResolveAll_Offline(t *testing.T) {
+	utils.CreateLogger(false)
+	t.Setenv("XDG_CACHE_HOME", t.TempDir()) // An empty cache: only the lockfile can answer
+
+	checkoutSHA := strings.Repeat("a", 40)
+	pinnedSHA := strings.Repeat("b", 40)
+	lock := &lockfile.Lockfile{Actions: []lockfile.Entry{
+		{Uses: "actions/checkout", Ref: "v4", SHA: strings.Repeat("c", 40), Resolved: time.Unix(1, 0)},
+		{Uses: "actions/checkout", Ref: "v4", SHA: checkoutSHA, Resolved: time.Unix(2, 0)},
+		{Uses: "owner/repo/.github/workflows/ci.yml", Ref: "main", SHA: pinnedSHA, Type: "commit", Verified: true},
+	}}
+
+	client, err := githubclient.NewOfflineClient(githubclient.DefaultHost, "")
+	require.NoError(t, err)
+	root := t.TempDir()
+	p, err := New(client, WithLogger(utils.Logger), WithRoot(root), WithJobs(2), WithOffline(lock))
+	require.NoError(t, err)
+
+	workflow := filepath.Join(root, "ci.yml")
+	content := "jobs:\n" +
+		"  call:\n" +
+		"    uses: owner/repo/.github/workflows/ci.yml@" + pinnedSHA + " # main\n" +
+		"  build:\n" +
+		"    steps:\n" +
+		"      - uses: actions/checkout@v4\n" +
+		"      - uses: actions/setup-go@v5\n"
+	require.NoError(t, os.WriteFile(workflow, []byte(content), 0o600))
+	scan, err := p.scanFile(workflow, false)
+	require.NoError(t, err)
+	resolved := p.resolveAll(context.Background(), []*fileScan{scan})
+
+	// The most recently locked commit of the ref is used.
+	checkout := resolved[resolveKey{kind: resolvePin, owner: "actions", repo: "checkout", ref: "v4"}]
+	require.NoError(t, checkout.err)
+	assert.Equal(t, checkoutSHA, checkout.sha)
+
+	// A verified locked commit is known to belong to its repository.
+	reachable := resolved[resolveKey{kind: resolveReachable, owner: "owner", repo: "repo", ref: pinnedSHA}]
+	require.NoError(t, reachable.err)
+
+	// Anything else would need the network.
+	setupGo := resolved[resolveKey{kind: resolvePin, owner: "actions", repo: "setup-go", ref: "v5"}]
+	require.ErrorIs(t, setupGo.err, githubclient.ErrOffline)
+	assert.Equal(t, 1, countOfflineMisses(resolved))
+}
+
+func TestCheck_OfflineUnverifiedLock(t *testing.T) {
+	utils.CreateLogger(false)
+	t.Setenv("XDG_CACHE_HOME", t.TempDir()) // An empty cache: only the lockfile can answer
+
+	root := t.TempDir()
+	workflow := filepath.Join(root, "ci.yml")
+	pinnedSHA := strings.Repeat("d", 40)
+	content := "jobs:\n" +
+		"  build:\n" +
+		"    steps:\n" +
+		"      - uses: actions/checkout@" + pinnedSHA + "  # v4\n"
+	require.NoError(t, os.WriteFile(workflow, []byte(content), 0o600))
+
+	// A run without --verify-commits locks the pin without checking its commit.
+	p, err := New(nil, WithLogger(utils.Logger), WithRoot(root))
+	require.NoError(t, err)
+	scan, err := p.scanFile(workflow, false)
+	require.NoError(t, err)
+	p.apply(scan, nil)
+	path, err := p.updateLockfile([]*fileScan{scan}, nil, false)
+	require.NoError(t, err)
+	lock, err := lockfile.Load(path)
+	require.NoError(t, err)
+	require.Len(t, lock.Actions, 1)
+	assert.False(t, lock.Actions[0].Verified)
+
+	// So offline, the lockfile can't vouch for the commit.
+	client, err := githubclient.NewOfflineClient(githubclient.DefaultHost, "")
+	require.NoError(t, err)
+	p, err = New(client, WithLogger(utils.Logger), WithRoot(root), WithOffline(lock), WithVerifyCommits())
+	require.NoError(t, err)
+	result, err := p.Check(context.Background(), workflow)
+	require.NoError(t, err)
+	assert.Empty(t, result.Impostors)
+	assert.Equal(t, 1, result.Unverified)
+}
@@ -0,0 +1,841 @@
+// SPDX-License-Identifier: MIT
+
+package pinner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/rogpeppe/go-internal/diff"
+	"gopkg.in/yaml.v3"
+
+	"github.com/esacteksab/gh-actlock/parser"
+	"github.com/esacteksab/gh-actlock/report"
+	"github.com/esacteksab/gh-actlock/utils"
+)
+
+// findUpdatesInNodes recursively searches a YAML node tree for 'uses:' keys and
+// records every reference found. No API calls are made here: references that need
+// resolving are recorded in scan.refs, to be resolved together with those of other files.
+//
+// - node: The current YAML node being processed.
+// - scan: The scan of the file being walked, which receives the references and report entries.
+// - checkOnly: Whether to only collect unpinned references (in scan.updates) instead of scheduling resolutions.
+// Returns: An error if a critical issue occurs during traversal or processing, otherwise nil.
+func (p *Pinner) findUpdatesInNodes(node *yaml.Node, scan *fileScan, checkOnly bool) error {
+	// Different processing based on the type of YAML node
+	switch node.Kind {
+	case yaml.DocumentNode:
+		// A document node represents the root of a YAML document. Iterate its content.
+		for _, contentNode := range node.Content {
+			// Recursively call findUpdatesInNodes on the content node.
+			if err := p.findUpdatesInNodes(contentNode, scan, checkOnly); err != nil {
+				return err // Propagate errors from deeper levels.
+			}
+		}
+	case yaml.MappingNode:
+		// A mapping node represents key-value pairs (like a dictionary).
+		// Content is a slice of nodes: [key1, value1, key2, value2, ...].
+		for i := 0; i < len(node.Content); i += 2 {
+			keyNode := node.Content[i]     // The key node (e.g., 'uses')
+			valueNode := node.Content[i+1] // The value node (e.g., 'actions/checkout@v4')
+
+			// Check if the current key is 'uses' and the value is a simple scalar (a single string).
+			if keyNode.Kind == yaml.ScalarNode && keyNode.Value == "uses" &&
+				valueNode.Kind == yaml.ScalarNode {
+				// If it's a 'uses:' entry, handle its specific value.
+				err := p.handleUsesValue(valueNode, isFlowStyle(node), scan, checkOnly)
+				if err != nil {
+					// Log the error from handling the 'uses' value but continue processing other parts of the file.
+					p.logger.Errorf(
+						"Error processing 'uses' value on line %d: %v. Skipping this entry.",
+						valueNode.Line,
+						err,
+					)
+					// The decision to continue or halt on error here is a design choice.
+					// Returning the error (`return err`) would stop processing the current file.
+					// Continuing (`continue`) processes other key-value pairs in the same mapping.
+					continue // Continue to the next key-value pair in the mapping.
+				}
+			} else {
+				// If the key is not 'uses' or the value is not a scalar (could be a map or list),
+				// recursively check the value node for nested 'uses' entries.
+				if err := p.findUpdatesInNodes(valueNode, scan, checkOnly); err != nil {
+					return err // Propagate errors from deeper levels.
+				}
+			}
+		}
+	case yaml.SequenceNode:
+		// A sequence node represents a list (e.g., a list of steps).
+		// Iterate through each item in the sequence.
+		for _, itemNode := range node.Content {
+			// Recursively call findUpdatesInNodes on each item.
+			if err := p.findUpdatesInNodes(itemNode, scan, checkOnly); err != nil {
+				return err // Propagate errors from deeper levels.
+			}
+		}
+		// Scalar nodes (simple values) and Alias nodes do not contain nested 'uses' entries,
+		// so no recursive call is needed for those kinds.
+	}
+	return nil // Return nil if traversal of this node and its children completes without a critical error.
+}
+
+// handleUsesValue processes a single YAML node representing the value of a 'uses:' key.
+// It parses the action reference and records a report entry for it, along with the
+// lookup needed to pin or update it, if any.
+//
+// - valueNode: The YAML scalar node containing the action string (e.g., "actions/checkout@v4").
+// - inFlow: Whether the 'uses:' key is part of a flow mapping, e.g. '{uses: actions/checkout@v4}'.
+// - scan: The scan of the file being walked.
+// - checkOnly: Whether to only record unpinned references, with their original value, in scan.updates.
+// Returns: An error if a significant issue occurs while processing the reference, otherwise nil.
+func (p *Pinner) handleUsesValue(valueNode *yaml.Node, inFlow bool, scan *fileScan, checkOnly bool) error {
+	usesValue := valueNode.Value // Get the string value from the node
+	lineNum := valueNode.Line    // Get the original line number of this value
+
+	// Check if we have already identified an update for this specific line number
+	// This can happen if an alias points to a node containing 'uses', though rare
+	// It's a safety check to prevent duplicate processing of the same line
+	if scan.pending(lineNum) {
+		return nil // This line is already scheduled for an update, skip reprocessing
+	}
+
+	// Every reference gets a report entry. The outcome is filled in below, or once
+	// the reference has been resolved.
+	entry := scan.addEntry(report.Entry{Line: lineNum, Uses: usesValue})
+	scan.columns[lineNum] = valueNode.Column
+
+	// Use the parser package to break down the 'uses' string (e.g. owner/repo/action@ref)
+	action, err := parser.ParseActionNode(valueNode)
+	entry.Type = action.Type
+	if err != nil {
+		entry.Action = report.ActionError
+		entry.Error = err.Error()
+		// If parsing fails, log a warning and skip this action reference
+		// This is not a fatal error for the entire file
+		p.logger.Errorf(
+			"⚠️ Skipping 'uses: %s' on line %d due to parsing error: %v",
+			usesValue,
+			lineNum,
+			err,
+		)
+		return nil // Indicate that this specific 'uses' value processing failed non-fatally
+	}
+
+	// A flow mapping shares its line with the rest of the mapping, which a rewritten
+	// line would lose, so its references are only reported, never rewritten.
+	if inFlow && !checkOnly {
+		p.logger.Errorf(
+			"⚠️ Skipping 'uses: %s' on line %d: references in flow mappings aren't rewritten",
+			usesValue,
+			lineNum,
+		)
+		entry.Action = report.ActionSkipped
+		return nil
+	}
+
+	// Docker images are only pinned when WithRegistry set up a registry client.
+	if action.Type == "docker" && p.registry != nil && !checkOnly {
+		image := strings.TrimPrefix(usesValue, dockerPrefix)
+		p.addDockerReference(scan, image, dockerPrefix, lineNum)
+		return nil
+	}
+
+	// We are only interested in pinning standard GitHub actions referenced as owner/repo/action@ref.
+	// Skip if it's not a 'github' type action (e.g., 'docker://...'), or if any required part is missing.
+	// Optionally uncomment the log below for more verbose output on skipped items.
+	// log.Printf("Skipping non-GitHub action or incomplete reference: %s", usesValue)
+	if action.Type != "github" || action.Name == "" || action.Repo == "" {
+		entry.Action = report.ActionSkipped
+		return nil
+	}
+
+	// Record where the reference points, splitting any subpath off the repository name.
+	entry.Owner = action.Name
+	entry.Repo, entry.Subpath, _ = strings.Cut(action.Repo, "/")
+	entry.OldRef = action.Ref
+
+	// Actions the configuration says must never be touched are left exactly as they are.
+	if p.config.Skipped(entry.Owner, entry.Repo) {
+		p.logger.Debugf("⏭️  Skipping '%s' on line %d: matched a skip pattern in the config", usesValue, lineNum)
+		entry.Action = report.ActionSkipped
+		return nil
+	}
+
+	// Check if the ref is already a full SHA
+	isSHA := len(action.Ref) == parser.SHALength && parser.IsHexString(action.Ref)
+
+	ref := &reference{
+		entry:  len(scan.entries) - 1,
+		line:   lineNum,
+		value:  usesValue,
+		action: action,
+		isSHA:  isSHA,
+		// Check if it's likely a reusable workflow
+		isWorkflow: strings.Contains(action.Repo, ".yml") || strings.Contains(action.Repo, ".yaml"),
+	}
+
+	// In check mode there is nothing to resolve against, so only record the
+	// references that are not yet pinned. This is what 'check' relies on to run offline.
+	if checkOnly {
+		entry.Action = report.ActionUnchanged
+		if !isSHA {
+			entry.Action = report.ActionUnpinned
+			scan.updates[lineNum] = usesValue
+			scan.updatesMade++
+		}
+		// The reference is kept, without a lookup, for 'verify' to compare against the lockfile.
+		scan.refs = append(scan.refs, ref)
+		return nil
+	}
+
+	// Validate that we were able to extract a repository name for API calls
+	if entry.Repo == "" {
+		p.logger.Debugf(
+			"❌ Could not extract repository name from '%s' on line %d. Skipping.",
+			action.Repo,
+			lineNum,
+		)
+		markFailed(entry, fmt.Errorf("could not extract repository name from '%s'", action.Repo))
+		return nil // Continue processing other references
+	}
+
+	// Work out what needs to be looked up. A reference that is already pinned only has
+	// its commit checked in pin mode, and only on request as it costs API calls; in
+	// update mode the ref it tracks is re-resolved.
+	switch {
+	case p.policy != "":
+		ref.key = &resolveKey{
+			kind:   resolveUpdate,
+			owner:  entry.Owner,
+			repo:   entry.Repo,
+			ref:    action.MovingRef(),
+			policy: p.updatePolicyFor(entry.Owner, entry.Repo),
+		}
+	case !isSHA:
+		ref.key = &resolveKey{kind: resolvePin, owner: entry.Owner, repo: entry.Repo, ref: action.Ref}
+	case p.verifyCommits:
+		ref.key = reachableKey(entry, ref)
+	}
+	scan.refs = append(scan.refs, ref)
+	return nil
+}
+
+// handleWorkflowReference applies the resolution of a reusable workflow reference, either
+// updating it to the version chosen by the update policy or pinning it to the SHA of its
+// current ref based on the Pinner's update policy.
+//
+// - ref: The reusable workflow reference found while scanning.
+// - res: The resolution of ref's lookup, which for a pinned reference is the check of its commit.
+// - scan: The scan of the file the reference is in, which receives the update.
+//
+// Returns: An error if a critical operation fails, otherwise nil.
+func (p *Pinner) handleWorkflowReference(ref *reference, res resolution, scan *fileScan) error {
+	entry := &scan.entries[ref.entry]
+	owner, repoNameForAPI := entry.Owner, entry.Repo
+	lineNum := ref.line
+
+	// Construct the full path for the 'uses' string (owner/repo/path)
+	// This is the complete reference as it appears in the workflow file
+	fullPathForUses := fmt.Sprintf("%s/%s", owner, ref.action.Repo)
+
+	// --- Workflow Update Mode ---
+	// With an update policy, we're finding the latest version and updating all references
+	if p.policy != "" {
+		latestRef, commitSHA := res.comment(), res.sha
+		if res.err != nil || commitSHA == "" || latestRef == "" {
+			// Log an error if latest version discovery fails
+			p.logger.Errorf(
+				"❌ Error finding latest ref/SHA for workflow repo %s/%s: %v. Skipping update for line %d.",
+				owner,
+				repoNameForAPI,
+				res.err,
+				lineNum,
+			)
+			markFailed(entry, res.err)
+			return nil // Continue processing other references
+		}
+
+		entry.ResolvedSHA = commitSHA
+		entry.CommentRef = latestRef
+
+		// Create the new workflow reference string with SHA + comment
+		newUsesValue := p.pinnedValue(fullPathForUses+"@"+commitSHA, latestRef)
+
+		// Log the update details
+		p.logger.Debugf(
+			"  Updating workflow %s to SHA %s (latest ref: %s)",
+			fullPathForUses,
+			commitSHA[:8], // Show only first 8 chars of SHA for readability
+			latestRef,
+		)
+
+		// Check if the workflow is already up-to-date
+		if ref.isSHA && ref.action.Ref == commitSHA {
+			// If current reference is already the latest SHA, no update needed
+			p.logger.Debugf(
+				"  Workflow %s already up-to-date with SHA %s (latest ref: %s). No change needed.",
+				fullPathForUses,
+				commitSHA[:8],
+				latestRef,
+			)
+			entry.Action = report.ActionUnchanged
+		} else {
+			// Store the update in the map and increment counter
+			scan.updates[lineNum] = newUsesValue
+			scan.updatesMade++
+			entry.Action = report.ActionUpdated
+		}
+
+		return nil // Successfully processed workflow in update mode
+
+		// --- Workflow Pinning Mode ---
+		// Without one, we're pinning existing references to their current SHA
+	} else {
+		// If the reference is already a SHA, no need to pin it
+		if ref.isSHA {
+			// A commit from a fork in the same network must not be treated as pinned.
+			if p.flagImpostor(ref, res, entry) {
+				return nil
+			}
+			p.logger.Debugf("ℹ️  Workflow '%s' on line %d already pinned to SHA: %s", ref.value, lineNum, ref.action.Ref)
+			entry.Action = report.ActionUnchanged
+			entry.ResolvedSHA = ref.action.Ref
+			return nil // Already pinned, no update needed
+		}
+
+		commitSHA, originalRefForComment := res.sha, res.comment()
+		if res.err != nil || commitSHA == "" {
+			// Log an error if we can't resolve the reference or its SHA
+			p.logger.Errorf("❌  Skipping pin for workflow '%s' on line %d: %v", ref.value, lineNum, res.err)
+			markFailed(entry, res.err)
+			return nil // Continue processing other references
+		}
+
+		// Create the new workflow reference string with SHA + comment
+		newUsesValue := p.pinnedValue(fullPathForUses+"@"+commitSHA, originalRefForComment)
+		entry.CommentRef = originalRefForComment
+		p.logger.Debugf("  Pinned workflow %s@%s to SHA %s", fullPathForUses, originalRefForComment, commitSHA[:8])
+
+		// Store the update in the map and increment counter
+		scan.updates[lineNum] = newUsesValue
+		scan.updatesMade++
+		entry.Action = report.ActionPinned
+		entry.ResolvedSHA = commitSHA
+
+		return nil // Successfully processed workflow in pinning mode
+	}
+}
+
+// handleActionReference applies the resolution of a GitHub Action reference, either
+// updating it to the version chosen by the update policy or pinning it to the SHA of its
+// current ref based on the Pinner's update policy. This function handles the core logic of
+// determining what changes to make to action references in workflow files.
+//
+// - ref: The action reference found while scanning.
+// - res: The resolution of ref's lookup, which for a pinned reference is the check of its commit.
+// - scan: The scan of the file the reference is in, which receives the update.
+//
+// Returns: An error if a critical operation fails, otherwise nil.
+func (p *Pinner) handleActionReference(ref *reference, res resolution, scan *fileScan) error {
+	entry := &scan.entries[ref.entry]
+	owner, repoNameForAPI := entry.Owner, entry.Repo
+	lineNum := ref.line
+
+	// Construct the full path for the 'uses' string (owner/repo/subpath)
+	// This is the complete reference as it appears in the workflow file
+	fullPathForUses := fmt.Sprintf("%s/%s", owner, ref.action.Repo)
+
+	// Check if we're in update mode (updating existing SHAs to latest)
+	if p.policy != "" {
+		latestRef, commitSHA := res.comment(), res.sha
+		if res.err != nil || commitSHA == "" || latestRef == "" {
+			// Log an error if we can't find the latest version
+			p.logger.Errorf(
+				"❌  Error finding latest ref/SHA for action %s/%s: %v. Skipping update for line %d.",
+				owner,
+				repoNameForAPI,
+				res.err,
+				lineNum,
+			)
+			markFailed(entry, res.err)
+			return nil // Continue processing other actions
+		}
+
+		entry.ResolvedSHA = commitSHA
+		entry.CommentRef = latestRef
+
+		// Create the new action reference string with SHA + comment
+		newUsesValue := p.pinnedValue(
+			fullPathForUses+"@"+commitSHA, // Format: owner/repo/subpath@sha  # ref
+			latestRef,                     // Include latest reference as a comment
+		)
+
+		// Log the update details
+		p.logger.Debugf(
+			"  Updating %s@%s to SHA %s (latest ref: %s)",
+			fullPathForUses,
+			ref.action.Ref,
+			commitSHA[:8], // Show only first 8 chars of SHA for readability
+			latestRef,
+		)
+
+		// Check if the action is already up-to-date
+		if ref.isSHA && ref.action.Ref == commitSHA {
+			// If current reference is already the latest SHA, no update needed
+			p.logger.Debugf(
+				"  Action %s already up-to-date with SHA %s (latest ref: %s). No change needed.",
+				fullPathForUses,
+				commitSHA[:8],
+				latestRef,
+			)
+			entry.Action = report.ActionUnchanged
+		} else {
+			// Store the update in the map and increment counter
+			scan.updates[lineNum] = newUsesValue
+			scan.updatesMade++
+			entry.Action = report.ActionUpdated
+		}
+
+		return nil // Successfully processed action in update mode
+	} else {
+		// Pin mode: Pin existing references to their current SHA
+
+		// If the reference is already a SHA, no need to pin it
+		if ref.isSHA {
+			// A commit from a fork in the same network must not be treated as pinned.
+			if p.flagImpostor(ref, res, entry) {
+				return nil
+			}
+			p.logger.Debugf("ℹ️  Action '%s' on line %d already pinned to SHA: %s", ref.value, lineNum, ref.action.Ref)
+			entry.Action = report.ActionUnchanged
+			entry.ResolvedSHA = ref.action.Ref
+			return nil // Already pinned, no update needed
+		}
+
+		commitSHA, commentRef := res.sha, res.comment()
+		if res.err != nil || commitSHA == "" {
+			// Log an error if we can't resolve the SHA
+			p.logger.Errorf("❌  Error resolving ref '%s' to SHA for action %s/%s: %v. Skipping update for line %d.",
+				ref.action.Ref, owner, repoNameForAPI, res.err, lineNum)
+			markFailed(entry, res.err)
+			return nil // Continue processing other actions
+		}
+
+		// Create the new action reference string with SHA + comment
+		newUsesValue := p.pinnedValue(fullPathForUses+"@"+commitSHA, commentRef)
+		entry.CommentRef = commentRef
+		p.logger.Debugf("  Pinned action %s@%s to SHA %s", fullPathForUses, commentRef, commitSHA[:8])
+
+		// Store the update in the map and increment counter
+		scan.updates[lineNum] = newUsesValue
+		scan.updatesMade++
+		entry.Action = report.ActionPinned
+		entry.ResolvedSHA = commitSHA
+
+		return nil // Successfully processed action in pin mode
+	}
+}
+
+// markFailed records on a report entry that a reference could not be resolved.
+//
+// - entry: The report entry to update.
+// - err: The error that caused the failure, may be nil when the API returned no SHA.
+func markFailed(entry *report.Entry, err error) {
+	entry.Action = report.ActionError
+	if err == nil {
+		err = errors.New("no commit SHA returned")
+	}
+	entry.Error = err.Error()
+}
+
+// resolveWorkflowRef determines the appropriate Git reference to use for a reusable workflow.
+// If no reference is provided, it fetches the repository's default branch.
+//
+// - ctx: The context for API calls, allows for cancellation/timeouts.
+// - owner: The owner (user or organization) of the GitHub repository.
+// - repoNameForAPI: The repository name to use in API calls (without subpaths).
+// - currentRef: The current reference specified in the workflow, may be empty.
+// - fullPathForUses: The complete "uses" path for logging purposes.
+//
+// Returns:
+//   - string: The resolved branch name (either the provided ref or default branch)
+//   - string: The reference to use in comments (for tracking original reference)
+//   - error: An error if default branch resolution fails when needed
+func (p *Pinner) resolveWorkflowRef(
+	ctx context.Context,
+	owner, repoNameForAPI, currentRef, fullPathForUses string,
+) (string, string, error) {
+	branchName := currentRef
+	originalRefForComment := currentRef
+
+	// Check if a reference was provided in the workflow file
+	if branchName == "" {
+		// No reference specified, so we need to get the default branch
+		p.logger.Debugf(
+			"ℹ️ No ref specified for workflow %s. Resolving default branch for %s/%s.",
+			fullPathForUses,
+			owner,
+			repoNameForAPI,
+		)
+
+		// Look up the repository's default branch (once per repository)
+		defaultBranch, err := p.resolver.GetDefaultBranch(ctx, owner, repoNameForAPI)
+		if err != nil {
+			return "", "", err
+		}
+
+		// Use the default branch as the reference
+		branchName = defaultBranch
+
+		// Store the default branch name as the original reference for commenting purposes
+		// This helps track that we automatically resolved to the default branch
+		originalRefForComment = branchName
+
+		p.logger.Debugf("  Using default branch '%s' for %s/%s",
+			branchName,
+			owner,
+			repoNameForAPI,
+		)
+	}
+
+	// Return both the branch to use and the original reference (for comments)
+	return branchName, originalRefForComment, nil
+}
+
+// ApplyUpdatesToLines takes the original content of a file and a map of line numbers
+// to new string values, and reconstructs the content with the specified lines replaced.
+// Each line is rewritten from the column its value starts at, as recorded by the YAML
+// parser, so the indentation, dashes, and key in front of it are preserved exactly.
+//
+// - originalContent: The string content of the file before modification.
+// - updates: A map where keys are 1-based line numbers and values are the replacement strings.
+// - columns: A map where keys are 1-based line numbers and values are the 1-based column
+// the replaced value starts at.
+//
+// Returns: The modified content as a string, and an error if processing fails
+func (p *Pinner) applyUpdatesToLines(originalContent string, updates map[int]string, columns map[int]int) (string, error) {
+	// Split the original content into individual lines. strings.Split handles various line endings.
+	lines := strings.Split(originalContent, "\n")
+	var output strings.Builder
+	// Pre-allocate capacity for the output string builder to improve performance,
+	// estimating the potential increase in size due to added comments.
+	output.Grow(
+		len(originalContent) + len(updates)*20,
+	) // Rough estimate: 20 characters per update comment.
+
+	// Iterate through the lines, using a 0-based index `i`.
+	for i, line := range lines {
+		// Calculate the 1-based line number for lookup in the 'updates' map.
+		lineNumber := i + 1
+		// Check if there is an update specified for the current line number.
+		if newUsesValue, ok := updates[lineNumber]; ok {
+			// An update exists for this line.
+			// Keep everything in front of the value (indentation, dashes, and the key) as it is.
+			if before, found := cutAtColumn(line, columns[lineNumber]); found {
+				// Replace the value, and any comment after it, with the new value.
+				output.WriteString(before + newUsesValue)
+			} else {
+				// If an update was mapped to this line number, but its value's column isn't
+				// known or is past the end of the line, log a warning. This indicates a
+				// potential issue with the position reported by the YAML parser. In this
+				// case, we append the original line to avoid corrupting the file.
+				p.logger.Debugf("Warning: Update found for line %d, but line content '%s' has no value at column %d. Appending original.", lineNumber, line, columns[lineNumber])
+				output.WriteString(line)
+			}
+		} else {
+			// No update for this line, append the original line content.
+			output.WriteString(line)
+		}
+
+		// Add the newline character back. This is added after each line except potentially the very last one.
+		// strings.Split(content, "\n") will produce a final empty string if the original content ended with a newline.
+		// We want to preserve the original ending: if the original content ended with a newline, the split results
+		// in `len(lines)` items, and the last item is empty. If it didn't end with a newline, `len(lines)` is
+		// the number of visual lines, and the last item contains content.
+		// The condition `i < len(lines)-1` correctly adds a newline after every line *except* the last one produced by the split.
+		if i < len(lines)-1 {
+			output.WriteString("\n")
+		}
+	}
+
+	// Return the accumulated content from the string builder.
+	return output.String(), nil
+}
+
+// cutAtColumn returns the part of a line in front of a column. Columns count
+// characters, as the YAML parser does, not bytes.
+//
+// - line: The original line.
+// - column: The 1-based column, 0 if unknown.
+// Returns: The text before the column, and whether the line reaches the column.
+func cutAtColumn(line string, column int) (string, bool) {
+	if column < 1 {
+		return "", false
+	}
+	runes := []rune(line)
+	if column > len(runes) {
+		return "", false
+	}
+	return string(runes[:column-1]), true
+}
+
+// isFlowStyle reports whether a node is written in flow style, e.g. '{image: node:20}',
+// and so may share its line with other keys.
+//
+// - node: The node.
+// Returns: Whether the node uses flow style.
+func isFlowStyle(node *yaml.Node) bool {
+	return node.Style&yaml.FlowStyle != 0
+}
+
+// fileScan holds what was found while walking a single workflow file.
+type fileScan struct {
+	path        string         // The path of the workflow file
+	data        []byte         // The original file content
+	updates     map[int]string // Line number -> new 'uses:' value
+	columns     map[int]int    // Line number -> column the value to replace starts at
+	updatesMade int            // The number of updates found
+	entries     []report.Entry // One report entry per 'uses:' reference
+	refs        []*reference   // The references whose outcome depends on a lookup
+}
+
+// reference is a reference found while scanning whose outcome is only known once
+// it has been resolved: a GitHub action or reusable workflow, or a docker image.
+type reference struct {
+	entry      int                   // Index of the reference's report entry in fileScan.entries
+	line       int                   // The line number of the reference
+	value      string                // The original value, e.g. "actions/checkout@v4"
+	action     parser.WorkflowAction // The parsed 'uses:' reference (actions and workflows only)
+	prefix     string                // The prefix to put back in front of a pinned image, e.g. "docker://"
+	isSHA      bool                  // Whether the ref is already a full commit SHA
+	isWorkflow bool                  // Whether the reference is a reusable workflow
+	key        *resolveKey           // The lookup the reference needs, nil if none
+}
+
+// newFileScan creates an empty scan of a file.
+//
+// - path: The path of the file.
+// Returns: The scan.
+func newFileScan(path string) *fileScan {
+	return &fileScan{path: path, updates: make(map[int]string), columns: make(map[int]int)}
+}
+
+// pending reports whether a line already has an update or a reference awaiting resolution.
+func (s *fileScan) pending(line int) bool {
+	if _, exists := s.updates[line]; exists {
+		return true
+	}
+	return slices.ContainsFunc(s.refs, func(r *reference) bool {
+		return r.line == line && r.key != nil
+	})
+}
+
+// addEntry records a report entry for a reference found while scanning.
+//
+// - entry: The entry to record.
+// Returns: A pointer to the recorded entry, valid until the next entry is added.
+func (s *fileScan) addEntry(entry report.Entry) *report.Entry {
+	s.entries = append(s.entries, entry)
+	return &s.entries[len(s.entries)-1]
+}
+
+// apply fills in the outcome of every reference of a scan awaiting resolution and
+// schedules the resulting line updates.
+//
+// - scan: The scan whose references were resolved.
+// - resolved: The resolutions returned by resolveAll.
+func (p *Pinner) apply(scan *fileScan, resolved map[resolveKey]resolution) {
+	for _, ref := range scan.refs {
+		var res resolution
+		if ref.key != nil {
+			res = resolved[*ref.key]
+		}
+		// Errors are recorded on the report entry, so applying continues with the next reference.
+		switch {
+		case ref.action.Type != "github":
+			_ = p.handleDockerReference(ref, res, scan)
+		case ref.isWorkflow:
+			_ = p.handleWorkflowReference(ref, res, scan)
+		default:
+			_ = p.handleActionReference(ref, res, scan)
+		}
+	}
+}
+
+// scanFile reads and parses a workflow file and walks its YAML tree to collect the
+// references it contains. No API calls are made.
+//
+// - filePath: The path to the workflow file to scan.
+// - checkOnly: Whether to only collect unpinned references instead of scheduling resolutions.
+//
+// Returns: The scan results (never nil), and an error if reading, parsing, or traversal fails.
+func (p *Pinner) scanFile(filePath string, checkOnly bool) (*fileScan, error) {
+	// Initialize the scan, whose maps store the identified updates
+	// Keys are line numbers, values are the new 'uses:' strings and where they go
+	scan := newFileScan(filePath)
+
+	// Validate the workflow file path to prevent security issues
+	// This ensures the path doesn't contain dangerous patterns like path traversal
+	if err := utils.ValidateWorkflowFilePathInRoot(filePath, p.root); err != nil {
+		return scan, err // Return the validation error without modification
+	}
+
+	// Read the file content into memory
+	// The nolint:gosec comment suppresses a security scanner warning about using
+	// a variable filepath - we've already validated it above
+	data, err := os.ReadFile(filePath) //nolint:gosec
+	if err != nil {
+		return scan, fmt.Errorf("error reading file %s: %w", filePath, err)
+	}
+	return p.scanData(scan, data, checkOnly)
+}
+
+// scanData parses workflow or action content and walks its YAML tree to collect the
+// references it contains. The content doesn't have to come from a local file: 'audit'
+// scans files fetched from GitHub.
+//
+// - scan: The scan to fill in; its path is used in messages and report entries.
+// - data: The YAML content.
+// - checkOnly: Whether to only collect unpinned references instead of scheduling resolutions.
+//
+// Returns: The scan, and an error if parsing or traversal fails.
+func (p *Pinner) scanData(scan *fileScan, data []byte, checkOnly bool) (*fileScan, error) {
+	filePath := scan.path
+	scan.data = data
+
+	// Skip processing if the file is empty
+	if len(data) == 0 {
+		p.logger.Debugf("Skipping empty file: %s", filePath)
+		return scan, nil // Return 0 updates and no error
+	}
+
+	// Parse the workflow YAML into a structured AST (Abstract Syntax Tree)
+	// This preserves line numbers and structure for precise updates
+	root, err := parser.ParseWorkflowYAML(filePath, data)
+	if err != nil {
+		return scan, err // Return any parsing errors
+	}
+
+	// If the parser returned nil (e.g., for an empty document), skip processing
+	if root == nil {
+		return scan, nil // Return 0 updates and no error
+	}
+
+	// Recursively traverse the YAML AST to find 'uses:' keys and collect the references
+	// We start from the first content node of the root (usually a DocumentNode or MappingNode)
+	if len(root.Content) > 0 {
+		err = p.findUpdatesInNodes(root.Content[0], scan, checkOnly)
+
+		// Container and service images are pinned alongside docker:// actions.
+		if err == nil && p.registry != nil && !checkOnly {
+			p.findImageUpdates(root.Content[0], scan)
+		}
+	}
+
+	// The walker doesn't know which file it is in, so fill that in for every entry.
+	for i := range scan.entries {
+		scan.entries[i].File = filePath
+	}
+
+	// Return the references found (before any error) and the error itself
+	return scan, err
+}
+
+// applyScan applies the resolved references of a scanned workflow file and writes the
+// modified content back. In a dry run, the diff is written instead and the file is
+// left untouched.
+//
+// - scan: The scan of the workflow file to update.
+// - resolved: The resolutions returned by resolveAll for the scan's references.
+//
+// Returns:
+//   - int: The number of actions updated in the file
+//   - error: An error if applying or writing the updates fails
+func (p *Pinner) applyScan(scan *fileScan, resolved map[resolveKey]resolution) (int, error) {
+	p.apply(scan, resolved)
+	if p.report != nil {
+		p.report.Add(scan.entries...)
+	}
+	return p.writeUpdates(scan)
+}
+
+// diffPath returns the name a dry-run diff gives a file: its path relative to the
+// root, with forward slashes, as git writes it. Prefixed with a/ and b/, an absolute
+// or ../ path would be rejected by 'git apply' and 'patch -p1'.
+//
+// - filePath: The path of the file, inside the root.
+// Returns: The file's name in the diff.
+func (p *Pinner) diffPath(filePath string) string {
+	root, err := filepath.Abs(p.root)
+	if err != nil {
+		return filepath.ToSlash(filePath)
+	}
+	abs, err := filepath.Abs(filePath)
+	if err != nil {
+		return filepath.ToSlash(filePath)
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil {
+		return filepath.ToSlash(filePath)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// writeUpdates writes the line updates collected in a scan back to its file. In a dry
+// run, a unified diff is written instead and the file is left untouched.
+//
+// - scan: The scan of the workflow file to update.
+//
+// Returns:
+//   - int: The number of lines updated in the file
+//   - error: An error if applying or writing the updates fails
+func (p *Pinner) writeUpdates(scan *fileScan) (int, error) {
+	filePath, data, updates, updatesMade := scan.path, scan.data, scan.updates, scan.updatesMade
+
+	// Apply updates if any were identified
+	if updatesMade > 0 {
+		p.logger.Debugf("Applying %d update(s) to %s", updatesMade, filePath)
+
+		// Modify the original file content line by line with the updates
+		updatedContent, err := p.applyUpdatesToLines(string(data), updates, scan.columns)
+		if err != nil {
+			return updatesMade, fmt.Errorf(
+				"error applying updates to lines for %s: %w",
+				filePath,
+				err,
+			)
+		}
+
+		// In dry-run mode, write a unified diff instead of touching the file.
+		// The a/ and b/ prefixes match git's output so the diff can be applied with 'git apply'.
+		if p.dryRun {
+			if p.diff != nil {
+				name := p.diffPath(filePath)
+				fmt.Fprint(p.diff, string(diff.Diff("a/"+name, data, "b/"+name, []byte(updatedContent))))
+			}
+			return updatesMade, nil
+		}
+
+		// Write the modified content back to the original file
+		// The nolint comments suppress security scanner warnings:
+		// - gosec: for using a variable filepath (already validated)
+		// - mnd: for using a "magic number" for file permissions
+		err = os.WriteFile( //nolint:gosec //nolint:mnd
+			filePath,
+			[]byte(updatedContent),
+			0o640, //nolint:mnd
+		)
+		if err != nil {
+			return updatesMade, fmt.Errorf("error writing updated file %s: %w", filePath, err)
+		}
+	}
+
+	// Return the total number of updates made and nil error if successful
+	return updatesMade, nil
+}
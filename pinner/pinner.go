@@ -0,0 +1,333 @@
+// SPDX-License-Identifier: MIT
+
+// Package pinner pins the actions, reusable workflows, and docker images of GitHub
+// workflow and action files to commit SHAs and digests. It is what the actlock commands
+// are built on, and depends on neither their flags nor the command line.
+package pinner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/charmbracelet/log"
+	"github.com/google/go-github/v82/github"
+
+	"github.com/esacteksab/gh-actlock/config"
+	"github.com/esacteksab/gh-actlock/githubclient"
+	"github.com/esacteksab/gh-actlock/lockfile"
+	"github.com/esacteksab/gh-actlock/registry"
+	"github.com/esacteksab/gh-actlock/report"
+)
+
+// Pinner pins the actions, reusable workflows, and (optionally) docker images of
+// workflow files to commit SHAs and digests, or moves them along an update policy.
+// Everything it needs is given to New, so that programs embedding actlock can
+// run several at once: it reads no flags, environment variables, or other globals.
+type Pinner struct {
+	resolver   *githubclient.Resolver    // Remembers lookups for the Pinner's lifetime; nil to only scan
+	registry   *registry.Client          // Resolves image tags to digests; nil skips docker images
+	logger     *log.Logger               // Where progress and problems are logged
+	config     *config.Config            // The project's configuration; nil for the defaults
+	policy     githubclient.UpdatePolicy // The update policy; empty to pin references as they are
+	dryRun     bool                      // Whether to leave files untouched
+	diff       io.Writer                 // Where a dry run prints its unified diff; nil for nowhere
+	commentTag string                    // Which tag inline comments name: "ref", "specific", or "both"
+	root       string                    // Project root; files outside it are never modified
+	jobs       int                       // How many references to resolve concurrently
+	report     *report.Report            // Receives a report entry per reference; nil for none
+	offline    bool                      // Whether lookups are answered from the lockfile and the cache only
+	lock       *lockfile.Lockfile        // The lockfile offline lookups try first; nil for none
+
+	verifyCommits bool // Whether already pinned commits are checked against impostor commits
+}
+
+// Option configures a Pinner created by New.
+type Option func(*Pinner)
+
+// WithUpdatePolicy makes the Pinner update references along a policy, as -u/--update
+// does, instead of pinning them to the commit of the ref they name.
+//
+// - policy: The update policy, see githubclient.ParseUpdatePolicy.
+// Returns: The option.
+func WithUpdatePolicy(policy githubclient.UpdatePolicy) Option {
+	return func(p *Pinner) {
+		p.policy = policy
+	}
+}
+
+// WithDryRun makes the Pinner leave files untouched, as --dry-run does, and write a
+// unified diff of the changes it would have made instead.
+//
+// - diff: Where to write the diff, nil to write nothing.
+// Returns: The option.
+func WithDryRun(diff io.Writer) Option {
+	return func(p *Pinner) {
+		p.dryRun = true
+		p.diff = diff
+	}
+}
+
+// WithLogger makes the Pinner log to a logger of its own. Without it, the Pinner logs
+// to charmbracelet/log's default logger.
+//
+// - logger: The logger.
+// Returns: The option.
+func WithLogger(logger *log.Logger) Option {
+	return func(p *Pinner) {
+		p.logger = logger
+	}
+}
+
+// WithConfig applies a project's configuration: its skip patterns, per-action update
+// policies, and comment spacing.
+//
+// - cfg: The configuration, see config.Load.
+// Returns: The option.
+func WithConfig(cfg *config.Config) Option {
+	return func(p *Pinner) {
+		p.config = cfg
+	}
+}
+
+// WithRegistry makes the Pinner pin docker:// actions and job container and service
+// images to their manifest digests, as --pin-docker does.
+//
+// - client: The registry client the digests are resolved with.
+// Returns: The option.
+func WithRegistry(client *registry.Client) Option {
+	return func(p *Pinner) {
+		p.registry = client
+	}
+}
+
+// WithRoot sets the project root. Files outside it are never modified.
+//
+// - root: The project root, "." unless set.
+// Returns: The option.
+func WithRoot(root string) Option {
+	return func(p *Pinner) {
+		p.root = root
+	}
+}
+
+// WithJobs sets how many references are resolved concurrently, as --jobs does.
+//
+// - jobs: The number of concurrent lookups, at least 1.
+// Returns: The option.
+func WithJobs(jobs int) Option {
+	return func(p *Pinner) {
+		p.jobs = jobs
+	}
+}
+
+// WithCommentTag sets which tag inline comments name, as --comment-tag does.
+//
+// - tag: "ref" (the default), "specific", or "both".
+// Returns: The option.
+func WithCommentTag(tag string) Option {
+	return func(p *Pinner) {
+		p.commentTag = tag
+	}
+}
+
+// WithReport records a report entry for every reference the Pinner processes.
+//
+// - r: The report to add the entries to.
+// Returns: The option.
+func WithReport(r *report.Report) Option {
+	return func(p *Pinner) {
+		p.report = r
+	}
+}
+
+// WithVerifyCommits makes the Pinner check that every commit that is already pinned is
+// reachable from a branch or tag of its repository, as --verify-commits does. Without
+// it, already pinned references cost no API calls.
+//
+// Returns: The option.
+func WithVerifyCommits() Option {
+	return func(p *Pinner) {
+		p.verifyCommits = true
+	}
+}
+
+// WithOffline answers lookups from a lockfile before the client, as --offline does.
+// The client should be one that never opens a connection, see
+// githubclient.NewOfflineClient; image digests aren't resolved at all.
+//
+// - lock: The project's lockfile, nil to use the client's cache only.
+// Returns: The option.
+func WithOffline(lock *lockfile.Lockfile) Option {
+	return func(p *Pinner) {
+		p.offline = true
+		p.lock = lock
+	}
+}
+
+// New creates a Pinner that resolves references with a GitHub client.
+//
+// - client: The GitHub client, see githubclient.New; nil for a Pinner that only scans
+// files for unpinned references.
+// - opts: The options.
+// Returns: The Pinner, and an error if an option's value is invalid.
+func New(client *github.Client, opts ...Option) (*Pinner, error) {
+	p := &Pinner{commentTag: CommentTagRef, root: ".", jobs: DefaultJobs}
+	if client != nil {
+		p.resolver = githubclient.NewResolver(client)
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.logger == nil {
+		p.logger = log.Default()
+	}
+
+	if p.policy != "" {
+		if _, err := githubclient.ParseUpdatePolicy(string(p.policy)); err != nil {
+			return nil, err
+		}
+	}
+	if p.jobs < 1 {
+		return nil, fmt.Errorf("invalid number of jobs %d: must be at least 1", p.jobs)
+	}
+	switch p.commentTag {
+	case CommentTagRef, CommentTagSpecific, CommentTagBoth:
+	default:
+		return nil, fmt.Errorf("unknown comment tag %q: expected ref, specific, or both", p.commentTag)
+	}
+	return p, nil
+}
+
+// UpdateWorkflowActionSHAs pins, or updates along the Pinner's policy, every reference
+// in a workflow or action file and writes the file back. In a dry run, the diff is
+// written instead and the file is left untouched.
+//
+// References that can't be resolved are logged and left as they are. Those left
+// because they weren't available offline or because of a rate limit, those pinned to a
+// commit that doesn't belong to their repository, and those whose commit couldn't be
+// verified are also reported in the error (see githubclient.ErrOffline and
+// githubclient.ErrImpostorCommit), after the file was written.
+//
+// - ctx: The context for API calls.
+// - filePath: The path of the file, inside the Pinner's root.
+// Returns: The number of references updated, and an error if the file can't be
+// processed or some of its references were left.
+func (p *Pinner) UpdateWorkflowActionSHAs(ctx context.Context, filePath string) (int, error) {
+	if p.resolver == nil {
+		return 0, errors.New("a Pinner without a GitHub client can't resolve references")
+	}
+	scan, err := p.scanFile(filePath, false)
+	if err != nil {
+		return 0, err
+	}
+	scans := []*fileScan{scan}
+	resolved := p.resolveAll(ctx, scans)
+	updated, err := p.applyScan(scan, resolved)
+	if err != nil {
+		return updated, err
+	}
+
+	var errs []error
+	if misses := countOfflineMisses(resolved); misses > 0 {
+		errs = append(errs, fmt.Errorf("%w (%w)", OfflineMissError(misses), githubclient.ErrOffline))
+	}
+	if skipped := p.reportRateLimited(scans, resolved); skipped > 0 {
+		errs = append(errs, fmt.Errorf("skipped %d reference(s) because of GitHub's rate limit", skipped))
+	}
+	if impostors := countImpostors(scans); impostors > 0 {
+		errs = append(errs, fmt.Errorf("%d reference(s) pinned to an impostor commit: %w",
+			impostors, githubclient.ErrImpostorCommit))
+	}
+	if unverified := countUnverified(scans, resolved); unverified > 0 {
+		errs = append(errs, fmt.Errorf("could not verify %d pinned commit(s)", unverified))
+	}
+	return updated, errors.Join(errs...)
+}
+
+// Run is the outcome of pinning a set of files with PinFiles.
+type Run struct {
+	Updated       int // The references updated, or that would be in a dry run, across all files
+	Unreadable    int // The files that couldn't be read or parsed
+	OfflineMisses int // The lookups that failed because they weren't available offline
+	RateLimited   int // The references skipped because of GitHub's rate limit
+	Impostors     int // The references pinned to a commit that doesn't belong to its repository
+	Unverified    int // The pinned commits that don't exist, or couldn't be verified for any reason
+
+	scans    []*fileScan               // The scanned and applied files, for UpdateLockfile
+	resolved map[resolveKey]resolution // Their resolutions
+}
+
+// PinFiles pins, or updates along the Pinner's policy, every reference in a set of
+// workflow and action files, as UpdateWorkflowActionSHAs does for one. Every file is
+// scanned first, so references shared between files are resolved only once. A file
+// that can't be processed is logged, and the others are processed anyway.
+//
+// - ctx: The context for API calls.
+// - files: The paths of the files, inside the Pinner's root.
+// Returns: The outcome of the run, and an error if the Pinner has no GitHub client.
+func (p *Pinner) PinFiles(ctx context.Context, files []string) (*Run, error) {
+	if p.resolver == nil {
+		return nil, errors.New("a Pinner without a GitHub client can't resolve references")
+	}
+
+	scans := make([]*fileScan, len(files))
+	scanErrs := make([]error, len(files))
+	for i, filePath := range files {
+		p.logger.Debugf("Scanning workflow: %s", filePath)
+		scans[i], scanErrs[i] = p.scanFile(filePath, false)
+	}
+	// Lookups are remembered for the whole run, across files and update policies.
+	resolved := p.resolveAll(ctx, scans)
+	run := &Run{scans: scans, resolved: resolved}
+
+	// Apply the resolutions file by file, in the order the files were given.
+	for i, filePath := range files {
+		p.logger.Printf("Processing workflow: %s", filePath)
+
+		// Update SHAs within this specific workflow file, unless it couldn't be scanned.
+		updated, err := 0, scanErrs[i]
+		if err == nil {
+			updated, err = p.applyScan(scans[i], resolved)
+		} else {
+			run.Unreadable++
+		}
+		switch {
+		case err != nil:
+			// Log errors related to processing a single file but continue to the next.
+			p.logger.Errorf("❌  Failed to process %s: %v", filePath, err)
+		case updated > 0 && p.dryRun:
+			// Nothing was written, so only report what would have changed.
+			p.logger.Printf("📝  Would update %d action(s) in %s", updated, filePath)
+			run.Updated += updated
+		case updated > 0:
+			p.logger.Printf("✅  Updated %d action(s) in %s", updated, filePath)
+			run.Updated += updated
+		default:
+			p.logger.Printf("ℹ️  No actions needed updating in %s", filePath)
+		}
+	}
+
+	run.OfflineMisses = countOfflineMisses(resolved)
+	run.RateLimited = p.reportRateLimited(scans, resolved)
+	run.Impostors = countImpostors(scans)
+	run.Unverified = countUnverified(scans, resolved)
+	return run, nil
+}
+
+// UpdateLockfile records every reference a run left pinned in the project's lockfile,
+// see lockfile.Path. A run with files that couldn't be read or parsed would drop their
+// entries, so the lockfile is only written when every file was processed.
+//
+// - run: The run, as returned by PinFiles.
+// - merge: Whether to keep previous entries for references not seen in the run, for
+// runs limited to some of the files.
+// Returns: The path of the lockfile, and an error if it can't be read or written, or
+// some files of the run weren't processed.
+func (p *Pinner) UpdateLockfile(run *Run, merge bool) (string, error) {
+	if run.Unreadable > 0 {
+		return "", fmt.Errorf("not updating the lockfile: %d file(s) could not be processed", run.Unreadable)
+	}
+	return p.updateLockfile(run.scans, run.resolved, merge)
+}
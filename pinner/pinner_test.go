@@ -0,0 +1,171 @@
+// SPDX-License-Identifier: MIT
+
+package pinner
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-github/v82/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/esacteksab/gh-actlock/githubclient"
+	"github.com/esacteksab/gh-actlock/githubclient/githubtest"
+	"github.com/esacteksab/gh-actlock/utils"
+)
+
+const pinnerWorkflow = `on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+`
+
+// newTestPinner creates a Pinner rooted in a fresh directory holding pinnerWorkflow,
+// whose client talks to a fake GitHub API.
+func newTestPinner(t *testing.T, opts ...Option) (*Pinner, string) {
+	t.Helper()
+	utils.CreateLogger(false)
+
+	srv := githubtest.NewServer()
+	t.Cleanup(srv.Close)
+	require.NoError(t, srv.SeedFile("../testdata/github.txtar"))
+	client, err := githubclient.New(
+		githubclient.WithBaseURL(srv.APIURL()), githubclient.WithLogger(utils.Logger))
+	require.NoError(t, err)
+
+	root := t.TempDir()
+	path := filepath.Join(root, "ci.yml")
+	require.NoError(t, os.WriteFile(path, []byte(pinnerWorkflow), 0o600))
+
+	opts = append([]Option{WithLogger(utils.Logger), WithRoot(root)}, opts...)
+	p, err := New(client, opts...)
+	require.NoError(t, err)
+	return p, path
+}
+
+func TestNewPinner_InvalidOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  Option
+		want string
+	}{
+		{"policy", WithUpdatePolicy("sideways"), "sideways"},
+		{"jobs", WithJobs(0), "invalid number of jobs 0"},
+		{"comment tag", WithCommentTag("all"), `unknown comment tag "all"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(nil, tt.opt)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestPinner_UpdateWorkflowActionSHAs(t *testing.T) {
+	p, path := newTestPinner(t)
+
+	updated, err := p.UpdateWorkflowActionSHAs(context.Background(), path)
+	require.NoError(t, err)
+	assert.Equal(t, 1, updated)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "uses: actions/checkout@11d5960a326750d5838078e36cf38b85af677262  # v4")
+}
+
+func TestPinner_UpdateWorkflowActionSHAs_DryRun(t *testing.T) {
+	var diff bytes.Buffer
+	p, path := newTestPinner(t, WithDryRun(&diff))
+
+	updated, err := p.UpdateWorkflowActionSHAs(context.Background(), path)
+	require.NoError(t, err)
+	assert.Equal(t, 1, updated)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, pinnerWorkflow, string(data), "a dry run must leave the file untouched")
+	// The file is named relative to the (absolute) root, so 'git apply' takes the diff.
+	assert.Contains(t, diff.String(), "--- a/ci.yml\n+++ b/ci.yml\n")
+	assert.Contains(t, diff.String(), "-      - uses: actions/checkout@v4")
+	assert.Contains(t, diff.String(), "+      - uses: actions/checkout@11d5960a326750d5838078e36cf38b85af677262  # v4")
+}
+
+func TestPinner_UpdateWorkflowActionSHAs_WithoutClient(t *testing.T) {
+	p, err := New(nil, WithRoot(t.TempDir()))
+	require.NoError(t, err)
+
+	_, err = p.UpdateWorkflowActionSHAs(context.Background(), "ci.yml")
+	require.Error(t, err)
+}
+
+func TestPinner_PinFiles(t *testing.T) {
+	p, path := newTestPinner(t)
+	missing := filepath.Join(filepath.Dir(path), "missing.yml")
+
+	run, err := p.PinFiles(context.Background(), []string{path, missing})
+	require.NoError(t, err)
+	assert.Equal(t, 1, run.Updated)
+	assert.Equal(t, 1, run.Unreadable)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "uses: actions/checkout@11d5960a326750d5838078e36cf38b85af677262  # v4")
+
+	// The missing file's entries would be dropped, so the lockfile is left alone.
+	_, err = p.UpdateLockfile(run, false)
+	require.ErrorContains(t, err, "1 file(s) could not be processed")
+}
+
+func TestPinner_Check(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "ci.yml")
+	require.NoError(t, os.WriteFile(path, []byte(pinnerWorkflow), 0o600))
+	// Without a client, nothing is resolved.
+	p, err := New(nil, WithLogger(utils.Logger), WithRoot(root))
+	require.NoError(t, err)
+
+	result, err := p.Check(context.Background(), path)
+	require.NoError(t, err)
+	assert.Equal(t, []Finding{{Line: 6, Message: "actions/checkout@v4 is not pinned to a commit SHA"}}, result.Unpinned)
+	assert.Empty(t, result.Impostors)
+	assert.Zero(t, result.Unverified)
+}
+
+func TestPinner_Check_FailsClosed(t *testing.T) {
+	utils.CreateLogger(false)
+	// Every lookup fails, as it would with a bad token or an outage.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "unavailable", http.StatusBadGateway)
+	}))
+	defer srv.Close()
+	client := github.NewClient(nil)
+	baseURL, err := url.Parse(srv.URL + "/")
+	require.NoError(t, err)
+	client.BaseURL = baseURL
+
+	root := t.TempDir()
+	path := filepath.Join(root, "ci.yml")
+	content := "jobs:\n" +
+		"  build:\n" +
+		"    steps:\n" +
+		"      - uses: actions/checkout@11bd71901bbe5b1630ceea73d27597364c9af683  # v4\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	p, err := New(client, WithLogger(utils.Logger), WithRoot(root))
+	require.NoError(t, err)
+
+	// A commit that couldn't be checked doesn't pass for one that was.
+	result, err := p.Check(context.Background(), path)
+	require.NoError(t, err)
+	assert.Empty(t, result.Impostors)
+	assert.Equal(t, 1, result.Unverified)
+}
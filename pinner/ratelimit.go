@@ -0,0 +1,41 @@
+// SPDX-License-Identifier: MIT
+
+package pinner
+
+import (
+	"github.com/esacteksab/gh-actlock/githubclient"
+)
+
+// rateLimited reports whether a lookup failed because of a GitHub rate limit.
+//
+// - res: The resolution of the lookup.
+// Returns: true if the lookup was refused by a primary or secondary rate limit.
+func rateLimited(res resolution) bool {
+	return githubclient.IsRateLimitError(res.err)
+}
+
+// reportRateLimited logs every reference that was skipped because a rate limit
+// outlasted the client's maximum wait, so a partial run doesn't pass for a complete one.
+//
+// - scans: The scans, after their resolutions were applied.
+// - resolved: The resolutions returned by resolveAll.
+// Returns: The number of references skipped.
+func (p *Pinner) reportRateLimited(scans []*fileScan, resolved map[resolveKey]resolution) int {
+	skipped := 0
+	for _, scan := range scans {
+		if scan == nil {
+			continue
+		}
+		for _, ref := range scan.refs {
+			if ref.key == nil || !rateLimited(resolved[*ref.key]) {
+				continue
+			}
+			if skipped == 0 {
+				p.logger.Errorf("⏳  Skipped because of GitHub's rate limit:")
+			}
+			p.logger.Errorf("  %s:%d: %s", scan.path, ref.line, ref.value)
+			skipped++
+		}
+	}
+	return skipped
+}
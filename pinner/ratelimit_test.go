@@ -0,0 +1,47 @@
+// SPDX-License-Identifier: MIT
+
+package pinner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-github/v82/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/esacteksab/gh-actlock/utils"
+)
+
+func TestReportRateLimited(t *testing.T) {
+	utils.CreateLogger(false)
+
+	root := t.TempDir()
+	p, err := New(nil, WithLogger(utils.Logger), WithRoot(root))
+	require.NoError(t, err)
+
+	workflow := filepath.Join(root, "ci.yml")
+	content := "jobs:\n" +
+		"  build:\n" +
+		"    steps:\n" +
+		"      - uses: actions/checkout@v4\n" +
+		"      - uses: actions/setup-go@v5\n" +
+		"      - uses: actions/cache@v4\n"
+	require.NoError(t, os.WriteFile(workflow, []byte(content), 0o600))
+	scan, err := p.scanFile(workflow, false)
+	require.NoError(t, err)
+
+	rateErr := &github.RateLimitError{Message: "API rate limit exceeded"}
+	resolved := map[resolveKey]resolution{
+		{kind: resolvePin, owner: "actions", repo: "checkout", ref: "v4"}: {ref: "v4", sha: "sha"},
+		{kind: resolvePin, owner: "actions", repo: "setup-go", ref: "v5"}: {
+			err: fmt.Errorf("reference 'v5' of actions/setup-go can't be resolved: %w", rateErr),
+		},
+		// Other failures are reported where they happen.
+		{kind: resolvePin, owner: "actions", repo: "cache", ref: "v4"}: {err: errors.New("not found")},
+	}
+	assert.Equal(t, 1, p.reportRateLimited([]*fileScan{scan}, resolved))
+}
@@ -0,0 +1,211 @@
+// SPDX-License-Identifier: MIT
+
+package pinner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/esacteksab/gh-actlock/githubclient"
+	"github.com/esacteksab/gh-actlock/registry"
+)
+
+// DefaultJobs is how many references are resolved at once unless WithJobs says otherwise.
+// It is kept small so a run doesn't trip GitHub's secondary rate limits.
+const DefaultJobs = 4
+
+// Which tag an inline comment names, as chosen by WithCommentTag.
+const (
+	CommentTagRef      = "ref"      // The ref as written or chosen by the update policy, e.g. "v4"
+	CommentTagSpecific = "specific" // The most specific tag pointing at the commit, e.g. "v4.2.2"
+	CommentTagBoth     = "both"     // The most specific tag, then the ref, e.g. "v4.2.2 (v4)"
+)
+
+// resolveKind says which lookup a resolveKey needs.
+type resolveKind int
+
+const (
+	resolvePin       resolveKind = iota // Resolve a ref (tag or branch) to its commit SHA
+	resolveUpdate                       // Find the ref an update policy moves to, and its commit SHA
+	resolveImage                        // Resolve an image tag to its manifest digest
+	resolveReachable                    // Check a pinned commit is reachable from a branch or tag
+)
+
+// resolveKey identifies a single lookup. References that need the same lookup share
+// a key, so e.g. actions/checkout@v4 used in many workflows is resolved only once.
+type resolveKey struct {
+	kind   resolveKind
+	owner  string                    // Repository owner (GitHub lookups only)
+	repo   string                    // Repository name without any subpath (GitHub lookups only)
+	ref    string                    // The ref to pin, the tracked ref to update from, or the commit to check
+	policy githubclient.UpdatePolicy // Update policy (resolveUpdate only)
+	image  registry.Reference        // Image to resolve (resolveImage only)
+}
+
+// resolution is the outcome of a lookup.
+type resolution struct {
+	ref  string               // The ref to record in the inline comment
+	sha  string               // The commit SHA or image digest the ref points to
+	kind githubclient.RefKind // What the ref resolved through (GitHub lookups only)
+	tag  string               // A more specific tag to name alongside ref, with CommentTagBoth
+	err  error                // Why the lookup failed, if it did
+}
+
+// comment returns the ref to write in the inline comment after the pinned SHA.
+//
+// Returns: The ref, preceded by the more specific tag when there is one.
+func (r resolution) comment() string {
+	if r.tag == "" {
+		return r.ref
+	}
+	return r.tag + " (" + r.ref + ")"
+}
+
+// resolveAll performs the lookups needed by every reference in the given scans using
+// a pool of workers. Each distinct key is looked up once. Only the results are shared
+// with the caller, so the order in which lookups finish doesn't affect the output.
+//
+// - ctx: The context for API calls, allows for cancellation/timeouts.
+// - scans: The scanned files whose references should be resolved.
+// Returns: The resolution for every key needed by the scans.
+func (p *Pinner) resolveAll(
+	ctx context.Context,
+	scans []*fileScan,
+) map[resolveKey]resolution {
+	// Collect the distinct keys, in the order they were found.
+	var keys []resolveKey
+	seen := make(map[resolveKey]bool)
+	for _, scan := range scans {
+		for _, ref := range scan.refs {
+			if ref.key != nil && !seen[*ref.key] {
+				seen[*ref.key] = true
+				keys = append(keys, *ref.key)
+			}
+		}
+	}
+	p.logger.Debugf("Resolving %d distinct reference(s) with %d job(s)", len(keys), p.jobs)
+	p.prefetchRefs(ctx, keys)
+
+	resolved := make(map[resolveKey]resolution, len(keys))
+	var mu sync.Mutex
+	work := make(chan resolveKey)
+	var wg sync.WaitGroup
+	for range max(1, min(p.jobs, len(keys))) {
+		wg.Go(func() {
+			for key := range work {
+				res := p.resolveOne(ctx, key)
+				mu.Lock()
+				resolved[key] = res
+				mu.Unlock()
+			}
+		})
+	}
+	for _, key := range keys {
+		work <- key
+	}
+	close(work)
+	wg.Wait()
+
+	return resolved
+}
+
+// prefetchRefs resolves the tags and branches the keys pin, or track for updates, in a
+// few batched GraphQL queries, so the workers find most of them already resolved.
+// Whatever the batch doesn't resolve is looked up by the workers with the REST API.
+//
+// - ctx: The context for API calls.
+// - keys: The lookups about to be performed.
+func (p *Pinner) prefetchRefs(ctx context.Context, keys []resolveKey) {
+	// Offline, nothing can be fetched; the lockfile and the cache answer the workers.
+	if p.resolver == nil || p.offline {
+		return
+	}
+	var refs []githubclient.RepoRef
+	for _, key := range keys {
+		tracked := key.kind == resolveUpdate && key.policy == githubclient.UpdateTracked
+		if (key.kind == resolvePin || tracked) && key.ref != "" {
+			refs = append(refs, githubclient.RepoRef{Owner: key.owner, Repo: key.repo, Ref: key.ref})
+		}
+	}
+	// A single ref gains nothing from a batch.
+	if len(refs) > 1 {
+		p.resolver.ResolveBatch(ctx, refs)
+	}
+}
+
+// resolveOne performs a single lookup.
+//
+// - ctx: The context for API calls.
+// - key: The lookup to perform.
+// Returns: The resolution, with err set if the lookup failed.
+func (p *Pinner) resolveOne(ctx context.Context, key resolveKey) resolution {
+	// Offline, what the lockfile records is used before the cache.
+	if res, ok := p.lockedResolution(key); ok {
+		return res
+	}
+	switch key.kind {
+	case resolveUpdate:
+		p.logger.Debugf("🔍  Finding %s update for %s/%s from '%s'", key.policy, key.owner, key.repo, key.ref)
+		res, err := p.resolver.Update(ctx, key.owner, key.repo, key.ref, key.policy)
+		return p.withSpecificTag(ctx, key, resolution{ref: res.Ref, sha: res.SHA, kind: res.Kind, err: err})
+	case resolveImage:
+		p.logger.Debugf("🔍  Resolving digest for image: %s:%s", key.image.Name, key.image.Tag)
+		if p.offline {
+			// Image digests are neither locked nor cached.
+			err := fmt.Errorf("%s:%s: %w", key.image.Name, key.image.Tag, githubclient.ErrOffline)
+			return resolution{ref: key.image.Tag, err: err}
+		}
+		digest, err := p.registry.ResolveDigest(ctx, key.image)
+		return resolution{ref: key.image.Tag, sha: digest, err: err}
+	case resolveReachable:
+		p.logger.Debugf("🔍  Checking %s/%s@%s is reachable from a branch or tag", key.owner, key.repo, key.ref)
+		ok, err := p.resolver.Reachable(ctx, key.owner, key.repo, key.ref)
+		if err == nil && !ok {
+			err = fmt.Errorf("%s/%s@%s: %w", key.owner, key.repo, key.ref, githubclient.ErrImpostorCommit)
+		}
+		return resolution{sha: key.ref, kind: githubclient.KindCommit, err: err}
+	default:
+		fullPath := key.owner + "/" + key.repo
+		// Resolve the ref to use (handles empty refs by finding the default branch)
+		branchName, refForComment, err := p.resolveWorkflowRef(ctx, key.owner, key.repo, key.ref, fullPath)
+		if err != nil {
+			return resolution{err: err}
+		}
+		p.logger.Debugf("🔍  Resolving SHA for %s@%s", fullPath, branchName)
+		res, err := p.resolver.Resolve(ctx, key.owner, key.repo, branchName)
+		return p.withSpecificTag(ctx, key, resolution{ref: refForComment, sha: res.SHA, kind: res.Kind, err: err})
+	}
+}
+
+// withSpecificTag looks up the most specific tag pointing at a resolved commit when
+// WithCommentTag asks for it. Branches and commits are left alone: a commit on main
+// that happens to be tagged v4.2.2 still tracks main.
+//
+// - ctx: The context for API calls.
+// - key: The lookup that was performed.
+// - res: Its resolution.
+// Returns: The resolution, naming the more specific tag if one was found.
+func (p *Pinner) withSpecificTag(
+	ctx context.Context,
+	key resolveKey,
+	res resolution,
+) resolution {
+	if p.commentTag == CommentTagRef || res.err != nil ||
+		res.kind == githubclient.KindBranch || res.kind == githubclient.KindCommit {
+		return res
+	}
+	tag, err := p.resolver.MostSpecificTag(ctx, key.owner, key.repo, res.sha)
+	if err != nil {
+		p.logger.Debugf("Could not find a more specific tag for %s/%s@%s: %v", key.owner, key.repo, res.ref, err)
+		return res
+	}
+	switch {
+	case tag == "" || tag == res.ref:
+	case p.commentTag == CommentTagSpecific:
+		res.ref = tag
+	default:
+		res.tag = tag
+	}
+	return res
+}
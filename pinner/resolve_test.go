@@ -0,0 +1,126 @@
+// SPDX-License-Identifier: MIT
+
+package pinner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v82/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/esacteksab/gh-actlock/registry"
+	"github.com/esacteksab/gh-actlock/registry/registrytest"
+	"github.com/esacteksab/gh-actlock/utils"
+)
+
+func TestResolveAll_DedupesAcrossFiles(t *testing.T) {
+	utils.CreateLogger(false)
+
+	srv := registrytest.NewServer()
+	defer srv.Close()
+	appDigest := srv.SetTag("owner/app", "v1", "app")
+	dbDigest := srv.SetTag("owner/db", "16", "db")
+
+	root := t.TempDir()
+	p, err := New(github.NewClient(nil),
+		WithLogger(utils.Logger), WithRegistry(registry.NewClient(nil)), WithRoot(root), WithJobs(3))
+	require.NoError(t, err)
+
+	host := srv.Host()
+	var scans []*fileScan
+	for i := range 5 {
+		workflow := filepath.Join(root, fmt.Sprintf("workflow%d.yml", i))
+		content := "jobs:\n" +
+			"  build:\n" +
+			"    container: " + host + "/owner/app:v1\n" +
+			"    services:\n" +
+			"      db:\n" +
+			"        image: " + host + "/owner/db:16\n" +
+			"    steps:\n" +
+			"      - uses: docker://" + host + "/owner/app:v1\n"
+		require.NoError(t, os.WriteFile(workflow, []byte(content), 0o600))
+
+		scan, err := p.scanFile(workflow, false)
+		require.NoError(t, err)
+		scans = append(scans, scan)
+	}
+
+	resolved := p.resolveAll(context.Background(), scans)
+	assert.Len(t, resolved, 2)
+
+	// Each distinct image is resolved once, however many files use it.
+	assert.Equal(t, 1, srv.Requests("owner/app", "v1"))
+	assert.Equal(t, 1, srv.Requests("owner/db", "16"))
+
+	for _, scan := range scans {
+		p.apply(scan, resolved)
+		assert.Equal(t, map[int]string{
+			3: host + "/owner/app@" + appDigest + "  # v1",
+			6: host + "/owner/db@" + dbDigest + "  # 16",
+			8: "docker://" + host + "/owner/app@" + appDigest + "  # v1",
+		}, scan.updates)
+
+		// Entries keep the order the references appear in, whatever order they resolved in.
+		require.Len(t, scan.entries, 3)
+		assert.Equal(t, []int{8, 3, 6}, []int{scan.entries[0].Line, scan.entries[1].Line, scan.entries[2].Line})
+	}
+}
+
+func TestResolveAll_CommentTag(t *testing.T) {
+	utils.CreateLogger(false)
+
+	sha := strings.Repeat("a", 40)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/actions/checkout/git/ref/tags/v4":
+			_ = json.NewEncoder(w).Encode(map[string]any{ //nolint:errchkjson
+				"ref":    "refs/tags/v4",
+				"object": map[string]string{"type": "commit", "sha": sha},
+			})
+		case "/repos/actions/checkout/tags":
+			_ = json.NewEncoder(w).Encode([]map[string]any{ //nolint:errchkjson
+				{"name": "v4", "commit": map[string]string{"sha": sha}},
+				{"name": "v4.2.2", "commit": map[string]string{"sha": sha}},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	client := github.NewClient(nil)
+	baseURL, err := url.Parse(srv.URL + "/")
+	require.NoError(t, err)
+	client.BaseURL = baseURL
+
+	root := t.TempDir()
+	workflow := filepath.Join(root, "workflow.yml")
+	require.NoError(t, os.WriteFile(workflow, []byte("steps:\n  - uses: actions/checkout@v4\n"), 0o600))
+
+	tests := map[string]string{
+		CommentTagRef:      "actions/checkout@" + sha + "  # v4",
+		CommentTagSpecific: "actions/checkout@" + sha + "  # v4.2.2",
+		CommentTagBoth:     "actions/checkout@" + sha + "  # v4.2.2 (v4)",
+	}
+	for style, want := range tests {
+		t.Run(style, func(t *testing.T) {
+			p, err := New(client, WithLogger(utils.Logger), WithRoot(root), WithJobs(1), WithCommentTag(style))
+			require.NoError(t, err)
+
+			scan, err := p.scanFile(workflow, false)
+			require.NoError(t, err)
+			p.apply(scan, p.resolveAll(context.Background(), []*fileScan{scan}))
+			assert.Equal(t, map[int]string{2: want}, scan.updates)
+		})
+	}
+}
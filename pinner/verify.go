@@ -0,0 +1,61 @@
+// SPDX-License-Identifier: MIT
+
+package pinner
+
+import (
+	"fmt"
+
+	"github.com/esacteksab/gh-actlock/lockfile"
+)
+
+// VerifyLockfile compares every action and reusable workflow reference in a workflow or
+// action file with a lockfile. Nothing is resolved: the references are compared as they
+// are written, so no API calls are made.
+//
+// - filePath: The path of the file, inside the Pinner's root.
+// - lock: The project's lockfile, see lockfile.Load.
+// Returns: The references that aren't pinned, or don't match the lockfile, and an error
+// if the file can't be read or parsed.
+func (p *Pinner) VerifyLockfile(filePath string, lock *lockfile.Lockfile) ([]Finding, error) {
+	scan, err := p.scanFile(filePath, true)
+	if err != nil {
+		return nil, err
+	}
+	var findings []Finding
+	for _, ref := range scan.refs {
+		if problem := verifyReference(lock, scan, ref); problem != "" {
+			findings = append(findings, Finding{Line: ref.line, Message: problem})
+		}
+	}
+	return findings, nil
+}
+
+// verifyReference compares a reference with the lockfile.
+//
+// - lock: The lockfile.
+// - scan: The scan the reference was found in.
+// - ref: The reference to verify.
+// Returns: A description of the problem, or an empty string if the reference matches the lockfile.
+func verifyReference(lock *lockfile.Lockfile, scan *fileScan, ref *reference) string {
+	entry := scan.entries[ref.entry]
+	uses := entry.Owner + "/" + ref.action.Repo
+	if !ref.isSHA {
+		return fmt.Sprintf("%s is not pinned to a commit SHA", ref.value)
+	}
+
+	// The lockfile records the ref a reference was resolved from, e.g. v4 for '# v4.2.2 (v4)'.
+	trackedRef := ref.action.MovingRef()
+	if _, ok := lock.Find(uses, trackedRef, ref.action.Ref); ok {
+		return ""
+	}
+
+	display := uses
+	if trackedRef != "" {
+		display += "@" + trackedRef
+	}
+	if locked := lock.Lookup(uses, trackedRef); len(locked) > 0 {
+		return fmt.Sprintf("%s is pinned to %s, but the lockfile records %s",
+			display, ref.action.Ref, locked[0].SHA)
+	}
+	return fmt.Sprintf("%s (%s) is not in the lockfile", display, ref.action.Ref)
+}
@@ -0,0 +1,154 @@
+// SPDX-License-Identifier: MIT
+
+package pinner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// CommentFix says how VerifyComments resolves a mismatch.
+type CommentFix string
+
+const (
+	FixNone    CommentFix = ""        // Only report the mismatch
+	FixComment CommentFix = "comment" // Rewrite the comment to a tag that points at the pinned SHA
+	FixPin     CommentFix = "pin"     // Re-pin the reference to the SHA the comment's ref points at
+)
+
+// VerifyComments resolves the comment ref of every pinned reference in a set of files,
+// e.g. v4.1.1 in 'actions/checkout@<sha>  # v4.1.1', reports the references whose
+// comment points at a different commit, and fixes them as asked. Every file is scanned
+// first, so a ref commented in many files is resolved only once.
+//
+// - ctx: The context for API calls.
+// - out: The writer mismatches that are left are reported to.
+// - files: The paths of the files, inside the Pinner's root.
+// - fix: How to fix a mismatch, FixNone to only report it.
+// Returns: The number of mismatches left, the number fixed, and an error if the fix is
+// unknown, the Pinner has no GitHub client, or a file can't be read, parsed, or written.
+func (p *Pinner) VerifyComments(
+	ctx context.Context,
+	out io.Writer,
+	files []string,
+	fix CommentFix,
+) (int, int, error) {
+	switch fix {
+	case FixNone, FixComment, FixPin:
+	default:
+		return 0, 0, fmt.Errorf("unknown fix %q: expected comment or pin", fix)
+	}
+	if p.resolver == nil {
+		return 0, 0, errors.New("a Pinner without a GitHub client can't resolve references")
+	}
+
+	scans := make([]*fileScan, 0, len(files))
+	for _, filePath := range files {
+		p.logger.Debugf("Verifying comments in: %s", filePath)
+		scan, err := p.scanFile(filePath, true)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to verify %s: %w", filePath, err)
+		}
+		scans = append(scans, scan)
+	}
+
+	for _, scan := range scans {
+		// Check mode records the unpinned references as updates; only fixes may be written.
+		clear(scan.updates)
+		scan.updatesMade = 0
+		for _, ref := range scan.refs {
+			entry := &scan.entries[ref.entry]
+			if tracked := ref.action.TrackedRef(); ref.isSHA && tracked != "" && entry.Repo != "" {
+				ref.key = &resolveKey{kind: resolvePin, owner: entry.Owner, repo: entry.Repo, ref: tracked}
+			}
+		}
+	}
+	resolved := p.resolveAll(ctx, scans)
+
+	mismatches, fixed := 0, 0
+	for _, scan := range scans {
+		for _, ref := range scan.refs {
+			if ref.key == nil {
+				continue
+			}
+			res := resolved[*ref.key]
+			problem := commentMismatch(ref, res)
+			if problem == "" {
+				continue
+			}
+			if fix == FixNone {
+				fmt.Fprintf(out, "%s:%d: %s\n", scan.path, ref.line, problem)
+				mismatches++
+				continue
+			}
+			if err := p.fixCommentMismatch(ctx, scan, ref, res, fix); err != nil {
+				fmt.Fprintf(out, "%s:%d: %s (not fixed: %v)\n", scan.path, ref.line, problem, err)
+				mismatches++
+				continue
+			}
+			p.logger.Printf("🔧  %s:%d: %s", scan.path, ref.line, scan.updates[ref.line])
+			fixed++
+		}
+		if _, err := p.writeUpdates(scan); err != nil {
+			return mismatches, fixed, err
+		}
+	}
+	return mismatches, fixed, nil
+}
+
+// commentMismatch compares a pinned reference with the resolution of its comment's ref.
+//
+// - ref: The reference, pinned to a commit SHA.
+// - res: The resolution of the ref in its inline comment.
+// Returns: A description of the mismatch, or an empty string if the comment matches.
+func commentMismatch(ref *reference, res resolution) string {
+	tracked := ref.action.TrackedRef()
+	switch {
+	case res.err != nil:
+		return fmt.Sprintf("%s: the comment ref '%s' could not be resolved: %v", ref.value, tracked, res.err)
+	case res.sha != ref.action.Ref:
+		return fmt.Sprintf("%s: the comment ref '%s' points to %s, not the pinned commit", ref.value, tracked, res.sha)
+	}
+	return ""
+}
+
+// fixCommentMismatch schedules the line update that fixes a mismatched comment.
+//
+// - ctx: The context for API calls.
+// - scan: The scan of the file the reference is in, which receives the update.
+// - ref: The mismatched reference.
+// - res: The resolution of the ref in its inline comment.
+// - fix: How to fix the mismatch, FixComment or FixPin.
+// Returns: An error if the mismatch can't be fixed this way.
+func (p *Pinner) fixCommentMismatch(
+	ctx context.Context,
+	scan *fileScan,
+	ref *reference,
+	res resolution,
+	fix CommentFix,
+) error {
+	entry := &scan.entries[ref.entry]
+	fullPathForUses := entry.Owner + "/" + ref.action.Repo
+
+	if fix == FixPin {
+		if res.err != nil {
+			return res.err
+		}
+		scan.updates[ref.line] = p.pinnedValue(fullPathForUses+"@"+res.sha, ref.action.CommentRef())
+		scan.updatesMade++
+		return nil
+	}
+
+	tag, err := p.resolver.MostSpecificTag(ctx, entry.Owner, entry.Repo, ref.action.Ref)
+	if err != nil {
+		return err
+	}
+	if tag == "" {
+		return fmt.Errorf("no tag of %s/%s points at %s", entry.Owner, entry.Repo, ref.action.Ref)
+	}
+	scan.updates[ref.line] = p.pinnedValue(fullPathForUses+"@"+ref.action.Ref, tag)
+	scan.updatesMade++
+	return nil
+}
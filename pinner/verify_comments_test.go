@@ -0,0 +1,147 @@
+// SPDX-License-Identifier: MIT
+
+package pinner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v82/github"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/esacteksab/gh-actlock/githubclient"
+	"github.com/esacteksab/gh-actlock/githubclient/githubtest"
+	"github.com/esacteksab/gh-actlock/utils"
+)
+
+func TestVerifyComments(t *testing.T) {
+	utils.CreateLogger(false)
+
+	sha1 := strings.Repeat("1", 40)
+	sha2 := strings.Repeat("2", 40)
+
+	tags := map[string]string{"v1.0.0": sha1, "v2": sha2}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if tag, ok := strings.CutPrefix(r.URL.Path, "/repos/org/act/git/ref/tags/"); ok && tags[tag] != "" {
+			_ = json.NewEncoder(w).Encode(map[string]any{ //nolint:errchkjson
+				"ref":    "refs/tags/" + tag,
+				"object": map[string]string{"type": "commit", "sha": tags[tag]},
+			})
+			return
+		}
+		if r.URL.Path == "/repos/org/act/tags" {
+			_ = json.NewEncoder(w).Encode([]map[string]any{ //nolint:errchkjson
+				{"name": "v2", "commit": map[string]string{"sha": sha2}},
+				{"name": "v1.0.0", "commit": map[string]string{"sha": sha1}},
+			})
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	client := github.NewClient(nil)
+	baseURL, err := url.Parse(srv.URL + "/")
+	require.NoError(t, err)
+	client.BaseURL = baseURL
+
+	root := t.TempDir()
+
+	content := "jobs:\n" +
+		"  build:\n" +
+		"    steps:\n" +
+		"      - uses: org/act@" + sha1 + "  # v1.0.0\n" +
+		"      - uses: org/act@" + sha1 + "  # v2\n" +
+		"      - uses: org/act@v2\n"
+
+	tests := []struct {
+		fix        CommentFix
+		mismatches int
+		want       string
+	}{
+		{fix: "", mismatches: 1, want: content},
+		{fix: FixComment, want: strings.Replace(content, sha1+"  # v2", sha1+"  # v1.0.0", 1)},
+		{fix: FixPin, want: strings.Replace(content, sha1+"  # v2", sha2+"  # v2", 1)},
+	}
+	for _, tt := range tests {
+		t.Run("fix="+string(tt.fix), func(t *testing.T) {
+			workflow := filepath.Join(root, "workflow.yml")
+			require.NoError(t, os.WriteFile(workflow, []byte(content), 0o600))
+			p, err := New(client, WithLogger(utils.Logger), WithRoot(root))
+			require.NoError(t, err)
+
+			var out bytes.Buffer
+			mismatches, _, err := p.VerifyComments(context.Background(), &out, []string{workflow}, tt.fix)
+			require.NoError(t, err)
+			assert.Equal(t, tt.mismatches, mismatches)
+			if tt.mismatches > 0 {
+				assert.Equal(t, workflow+":5: org/act@"+sha1+": the comment ref 'v2' points to "+sha2+
+					", not the pinned commit\n", out.String())
+			}
+
+			// The unpinned reference on line 6 is left alone.
+			got, err := os.ReadFile(workflow)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestVerifyComments_CommentTagBoth(t *testing.T) {
+	utils.CreateLogger(false)
+
+	old, moved := strings.Repeat("a", 40), strings.Repeat("b", 40)
+	srv := githubtest.NewServer()
+	defer srv.Close()
+	require.NoError(t, srv.AddRepo("actions/checkout", githubtest.Repo{
+		Commits: []string{old},
+		Tags:    map[string]string{"v4": old, "v4.2.2": old},
+	}))
+	client, err := githubclient.New(
+		githubclient.WithBaseURL(srv.APIURL()), githubclient.WithLogger(utils.Logger))
+	require.NoError(t, err)
+
+	root := t.TempDir()
+	workflow := filepath.Join(root, "workflow.yml")
+	require.NoError(t, os.WriteFile(workflow, []byte("steps:\n  - uses: actions/checkout@v4\n"), 0o600))
+	p, err := New(client, WithLogger(utils.Logger), WithRoot(root), WithCommentTag(CommentTagBoth))
+	require.NoError(t, err)
+	_, err = p.UpdateWorkflowActionSHAs(context.Background(), workflow)
+	require.NoError(t, err)
+	pinned := "steps:\n  - uses: actions/checkout@" + old + "  # v4.2.2 (v4)\n"
+	got, err := os.ReadFile(workflow)
+	require.NoError(t, err)
+	require.Equal(t, pinned, string(got))
+
+	// v4 moves on, but the pin still matches the v4.2.2 its comment names first.
+	require.NoError(t, srv.AddRepo("actions/checkout", githubtest.Repo{
+		Commits: []string{old, moved},
+		Tags:    map[string]string{"v4": moved, "v4.2.2": old, "v4.3.0": moved},
+	}))
+	for _, fix := range []CommentFix{FixNone, FixPin} {
+		t.Run("fix="+string(fix), func(t *testing.T) {
+			p, err := New(client, WithLogger(utils.Logger), WithRoot(root))
+			require.NoError(t, err)
+
+			var out bytes.Buffer
+			mismatches, fixed, err := p.VerifyComments(context.Background(), &out, []string{workflow}, fix)
+			require.NoError(t, err)
+			assert.Zero(t, mismatches)
+			assert.Zero(t, fixed)
+			assert.Empty(t, out.String())
+
+			got, err := os.ReadFile(workflow)
+			require.NoError(t, err)
+			assert.Equal(t, pinned, string(got))
+		})
+	}
+}
@@ -0,0 +1,316 @@
+// SPDX-License-Identifier: MIT
+
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Docker Hub is addressed as docker.io in image references, but its registry API lives elsewhere.
+const (
+	dockerHubDomain   = "docker.io"
+	dockerHubRegistry = "registry-1.docker.io"
+	defaultTag        = "latest"
+	requestTimeout    = 30 * time.Second
+)
+
+// digestPattern matches the only digests written to workflows: a registry's answer ends
+// up in a uses: or image: line, so anything else is refused rather than written there.
+var digestPattern = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+
+// manifestMediaTypes are the manifest formats we accept, most preferred first. Asking for
+// index/list types means multi-arch images resolve to the digest `docker pull` would use.
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// Reference is a parsed container image reference such as "ghcr.io/owner/image:tag".
+type Reference struct {
+	Domain     string // Registry host as written (defaults to docker.io), may include a port
+	Repository string // Repository path within the registry (e.g., "library/alpine")
+	Tag        string // Tag, "latest" if none was given and there is no digest
+	Digest     string // Digest (e.g., "sha256:..."), empty if the reference isn't pinned
+	Name       string // The image name as written, without tag or digest
+}
+
+// ParseReference parses an image reference into its parts. It follows the docker
+// convention that the first path component is a registry host only if it contains
+// a '.' or ':' or is "localhost"; otherwise the image lives on Docker Hub.
+//
+// - image: The image reference, without any docker:// prefix.
+// Returns: The parsed Reference, and an error if the reference is malformed.
+func ParseReference(image string) (Reference, error) {
+	if image == "" {
+		return Reference{}, errors.New("empty image reference")
+	}
+	var ref Reference
+
+	// A digest follows the '@'.
+	name, digest, hasDigest := strings.Cut(image, "@")
+	if hasDigest {
+		if !strings.Contains(digest, ":") {
+			return Reference{}, fmt.Errorf("invalid digest in image reference %q", image)
+		}
+		ref.Digest = digest
+	}
+
+	// A tag follows the last ':' after the last '/', so a registry port isn't mistaken for one.
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		ref.Tag = name[i+1:]
+		name = name[:i]
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = defaultTag
+	}
+	if name == "" {
+		return Reference{}, fmt.Errorf("invalid image reference %q", image)
+	}
+	ref.Name = name
+
+	ref.Domain, ref.Repository = dockerHubDomain, name
+	if first, rest, ok := strings.Cut(name, "/"); ok &&
+		(strings.ContainsAny(first, ".:") || first == "localhost") {
+		ref.Domain, ref.Repository = first, rest
+	}
+	// Official Docker Hub images live under the "library" namespace.
+	if ref.Domain == dockerHubDomain && !strings.Contains(ref.Repository, "/") {
+		ref.Repository = "library/" + ref.Repository
+	}
+	return ref, nil
+}
+
+// Client resolves image tags to manifest digests using the OCI distribution API.
+type Client struct {
+	httpClient *http.Client
+	// PlainHTTP reports whether a registry host should be contacted over http instead
+	// of https. By default only loopback hosts are, matching docker's behavior.
+	PlainHTTP func(host string) bool
+}
+
+// NewClient returns a registry client using the given HTTP client, or a default one if nil.
+//
+// - httpClient: The HTTP client for registry requests, may be nil.
+// Returns: An initialized *Client.
+func NewClient(httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: requestTimeout}
+	}
+	return &Client{httpClient: httpClient, PlainHTTP: isLoopbackHost}
+}
+
+// ResolveDigest returns the manifest digest the reference's tag currently points to.
+// Anonymous bearer tokens are requested when the registry asks for them, which is
+// enough for public images on Docker Hub, ghcr.io and most other registries.
+//
+// - ctx: The context for the HTTP requests.
+// - ref: The parsed image reference.
+// Returns: The digest (e.g., "sha256:..."), and an error if it cannot be resolved.
+func (c *Client) ResolveDigest(ctx context.Context, ref Reference) (string, error) {
+	if ref.Tag == "" {
+		return "", fmt.Errorf("image %q has no tag to resolve", ref.Name)
+	}
+
+	host := ref.Domain
+	if host == dockerHubDomain {
+		host = dockerHubRegistry
+	}
+	scheme := "https"
+	if c.PlainHTTP != nil && c.PlainHTTP(host) {
+		scheme = "http"
+	}
+	manifestURL := fmt.Sprintf("%s://%s/v2/%s/manifests/%s", scheme, host, ref.Repository, url.PathEscape(ref.Tag))
+
+	// HEAD is enough when the registry reports the digest in a header, and doesn't count
+	// against Docker Hub's pull rate limit.
+	resp, err := c.fetchManifest(ctx, http.MethodHead, manifestURL, "")
+	if err != nil {
+		return "", err
+	}
+
+	// Registries requiring auth answer 401 with a challenge describing where to get a token.
+	token := ""
+	if resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close() //nolint:errcheck,gosec
+		token, err = c.fetchToken(ctx, resp.Header.Get("WWW-Authenticate"))
+		if err != nil {
+			return "", fmt.Errorf("failed to authenticate to %s: %w", host, err)
+		}
+		resp, err = c.fetchManifest(ctx, http.MethodHead, manifestURL, token)
+		if err != nil {
+			return "", err
+		}
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	// Fall back to downloading the manifest and hashing it ourselves when there's no digest header.
+	if resp.StatusCode == http.StatusOK && resp.Header.Get("Docker-Content-Digest") == "" {
+		return c.digestFromBody(ctx, manifestURL, token, ref)
+	}
+	return digestFromResponse(resp, ref)
+}
+
+// fetchManifest sends a manifest request, with a bearer token if one is given.
+func (c *Client) fetchManifest(ctx context.Context, method, manifestURL, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, manifestURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %s: %w", manifestURL, err)
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request to %s failed: %w", manifestURL, err)
+	}
+	return resp, nil
+}
+
+// digestFromResponse reads the Docker-Content-Digest header of a manifest response,
+// which must be a sha256 digest (see digestPattern).
+func digestFromResponse(resp *http.Response, ref Reference) (string, error) {
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", fmt.Errorf("tag '%s' not found for image %s", ref.Tag, ref.Name)
+	default:
+		return "", fmt.Errorf("unexpected status %s resolving %s:%s", resp.Status, ref.Name, ref.Tag)
+	}
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", errors.New("registry did not return a Docker-Content-Digest header")
+	}
+	if !digestPattern.MatchString(digest) {
+		return "", fmt.Errorf("registry returned an invalid digest %q for %s:%s", digest, ref.Name, ref.Tag)
+	}
+	return digest, nil
+}
+
+// digestFromBody downloads a manifest and computes its sha256 digest, for registries
+// that don't send a Docker-Content-Digest header. If the GET response has one, it must
+// match the manifest.
+func (c *Client) digestFromBody(ctx context.Context, manifestURL, token string, ref Reference) (string, error) {
+	resp, err := c.fetchManifest(ctx, http.MethodGet, manifestURL, token)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusOK {
+		return digestFromResponse(resp, ref)
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, resp.Body); err != nil {
+		return "", fmt.Errorf("failed to read manifest for %s:%s: %w", ref.Name, ref.Tag, err)
+	}
+	digest := "sha256:" + hex.EncodeToString(h.Sum(nil))
+	if header := resp.Header.Get("Docker-Content-Digest"); header != "" && header != digest {
+		return "", fmt.Errorf("registry returned digest %q for %s:%s, but the manifest hashes to %s",
+			header, ref.Name, ref.Tag, digest)
+	}
+	return digest, nil
+}
+
+// fetchToken requests an anonymous bearer token as described by a
+// 'WWW-Authenticate: Bearer realm="...",service="...",scope="..."' challenge.
+func (c *Client) fetchToken(ctx context.Context, challenge string) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", fmt.Errorf("unsupported auth challenge %q", challenge)
+	}
+	attrs := parseChallengeParams(params)
+	realm := attrs["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("auth challenge %q has no realm", challenge)
+	}
+
+	tokenURL, err := url.Parse(realm)
+	if err != nil {
+		return "", fmt.Errorf("invalid auth realm %q: %w", realm, err)
+	}
+	q := tokenURL.Query()
+	for _, key := range []string{"service", "scope"} {
+		if v := attrs[key]; v != "" {
+			q.Set(key, v)
+		}
+	}
+	tokenURL.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request returned %s", resp.Status)
+	}
+
+	// Registries use either field name for the token.
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	if body.AccessToken != "" {
+		return body.AccessToken, nil
+	}
+	return "", errors.New("token response did not contain a token")
+}
+
+// parseChallengeParams parses the comma-separated key="value" pairs of an auth challenge.
+func parseChallengeParams(params string) map[string]string {
+	attrs := make(map[string]string)
+	for params != "" {
+		var key, value string
+		key, params, _ = strings.Cut(strings.TrimLeft(params, " ,"), "=")
+		if strings.HasPrefix(params, `"`) {
+			// Quoted values may contain commas (e.g., multiple scopes).
+			end := strings.Index(params[1:], `"`)
+			if end < 0 {
+				value, params = params[1:], ""
+			} else {
+				value, params = params[1:end+1], params[end+2:]
+			}
+		} else {
+			value, params, _ = strings.Cut(params, ",")
+		}
+		attrs[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+	return attrs
+}
+
+// isLoopbackHost reports whether a registry host (with optional port) is a loopback address.
+func isLoopbackHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,179 @@
+// SPDX-License-Identifier: MIT
+
+package registry_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/esacteksab/gh-actlock/registry"
+	"github.com/esacteksab/gh-actlock/registry/registrytest"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		name  string
+		image string
+		want  registry.Reference
+	}{
+		{
+			name:  "official_image",
+			image: "alpine:3.20",
+			want: registry.Reference{
+				Domain: "docker.io", Repository: "library/alpine", Tag: "3.20", Name: "alpine",
+			},
+		},
+		{
+			name:  "default_tag",
+			image: "owner/image",
+			want: registry.Reference{
+				Domain: "docker.io", Repository: "owner/image", Tag: "latest", Name: "owner/image",
+			},
+		},
+		{
+			name:  "ghcr",
+			image: "ghcr.io/owner/image:v1",
+			want: registry.Reference{
+				Domain: "ghcr.io", Repository: "owner/image", Tag: "v1", Name: "ghcr.io/owner/image",
+			},
+		},
+		{
+			name:  "registry_with_port",
+			image: "localhost:5000/image:v1",
+			want: registry.Reference{
+				Domain: "localhost:5000", Repository: "image", Tag: "v1", Name: "localhost:5000/image",
+			},
+		},
+		{
+			name:  "registry_with_port_no_tag",
+			image: "localhost:5000/image",
+			want: registry.Reference{
+				Domain: "localhost:5000", Repository: "image", Tag: "latest", Name: "localhost:5000/image",
+			},
+		},
+		{
+			name:  "digest",
+			image: "alpine@sha256:abc",
+			want: registry.Reference{
+				Domain: "docker.io", Repository: "library/alpine", Digest: "sha256:abc", Name: "alpine",
+			},
+		},
+		{
+			name:  "tag_and_digest",
+			image: "ghcr.io/owner/image:v1@sha256:abc",
+			want: registry.Reference{
+				Domain: "ghcr.io", Repository: "owner/image", Tag: "v1", Digest: "sha256:abc",
+				Name: "ghcr.io/owner/image",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := registry.ParseReference(tt.image)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	for _, bad := range []string{"", ":tag", "alpine@abc"} {
+		_, err := registry.ParseReference(bad)
+		assert.Error(t, err, "expected an error for %q", bad)
+	}
+}
+
+func TestResolveDigest(t *testing.T) {
+	tests := []struct {
+		name             string
+		requireAuth      bool
+		omitDigestHeader bool
+	}{
+		{name: "anonymous"},
+		{name: "bearer_token", requireAuth: true},
+		{name: "hash_manifest_body", omitDigestHeader: true},
+		{name: "bearer_token_hash_manifest_body", requireAuth: true, omitDigestHeader: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := registrytest.NewServer()
+			defer srv.Close()
+			srv.RequireAuth = tt.requireAuth
+			srv.OmitDigestHeader = tt.omitDigestHeader
+			want := srv.SetTag("owner/image", "v1", "first")
+
+			ref, err := registry.ParseReference(srv.Host() + "/owner/image:v1")
+			require.NoError(t, err)
+
+			client := registry.NewClient(nil)
+			got, err := client.ResolveDigest(context.Background(), ref)
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+
+			// Moving the tag changes the resolved digest.
+			moved := srv.SetTag("owner/image", "v1", "second")
+			got, err = client.ResolveDigest(context.Background(), ref)
+			require.NoError(t, err)
+			assert.Equal(t, moved, got)
+			assert.NotEqual(t, want, moved)
+		})
+	}
+}
+
+func TestResolveDigest_InvalidDigest(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+	}{
+		{name: "not_a_digest", header: "latest # injected"},
+		{name: "other_algorithm", header: "sha512:" + strings.Repeat("a", 128)},
+		{name: "uppercase", header: "sha256:" + strings.Repeat("A", 64)},
+		{name: "short", header: "sha256:abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := registrytest.NewServer()
+			defer srv.Close()
+			srv.DigestHeader = tt.header
+			srv.SetTag("owner/image", "v1", "first")
+
+			ref, err := registry.ParseReference(srv.Host() + "/owner/image:v1")
+			require.NoError(t, err)
+			_, err = registry.NewClient(nil).ResolveDigest(context.Background(), ref)
+			require.ErrorContains(t, err, "invalid digest")
+		})
+	}
+}
+
+func TestResolveDigest_ManifestMismatch(t *testing.T) {
+	// The HEAD response has no digest, and the GET one names another manifest's.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			return
+		}
+		w.Header().Set("Docker-Content-Digest", registrytest.Digest([]byte("other")))
+		_, _ = w.Write([]byte("manifest"))
+	}))
+	defer srv.Close()
+
+	ref, err := registry.ParseReference(strings.TrimPrefix(srv.URL, "http://") + "/owner/image:v1")
+	require.NoError(t, err)
+	_, err = registry.NewClient(nil).ResolveDigest(context.Background(), ref)
+	require.ErrorContains(t, err, "but the manifest hashes to "+registrytest.Digest([]byte("manifest")))
+}
+
+func TestResolveDigest_NotFound(t *testing.T) {
+	srv := registrytest.NewServer()
+	defer srv.Close()
+
+	ref, err := registry.ParseReference(srv.Host() + "/owner/image:missing")
+	require.NoError(t, err)
+
+	_, err = registry.NewClient(nil).ResolveDigest(context.Background(), ref)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "tag 'missing' not found")
+}
@@ -0,0 +1,142 @@
+// SPDX-License-Identifier: MIT
+
+// Package registrytest provides an in-process stand-in for an OCI distribution
+// registry, serving just enough of the API to resolve tags to digests in tests.
+package registrytest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+const (
+	manifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	testToken         = "registrytest-token"
+)
+
+// Server is a fake registry. Its Host can be used as the registry part of an
+// image reference, e.g. Host()+"/owner/image:tag".
+type Server struct {
+	*httptest.Server
+
+	// RequireAuth makes manifest requests demand an anonymous bearer token, the way
+	// Docker Hub and ghcr.io do.
+	RequireAuth bool
+	// OmitDigestHeader stops the server from sending Docker-Content-Digest, forcing
+	// clients to hash the manifest body.
+	OmitDigestHeader bool
+	// DigestHeader, when set, is sent as Docker-Content-Digest instead of the manifest's
+	// digest, the way a misbehaving registry or proxy might.
+	DigestHeader string
+
+	mu        sync.Mutex
+	manifests map[string][]byte // "repository:tag" -> manifest body
+	requests  map[string]int    // "repository:tag" -> authorized manifest requests
+}
+
+// NewServer starts a fake registry. Call Close when done.
+//
+// Returns: The started *Server.
+func NewServer() *Server {
+	s := &Server{manifests: make(map[string][]byte), requests: make(map[string]int)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", s.handleToken)
+	mux.HandleFunc("/v2/", s.handleManifest)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Host returns the host:port of the server, for use in image references.
+//
+// Returns: The registry host.
+func (s *Server) Host() string {
+	return strings.TrimPrefix(s.URL, "http://")
+}
+
+// SetTag points repository:tag at a generated manifest and returns its digest.
+// Calling it again with a different seed simulates the tag moving.
+//
+// - repository: The repository path, e.g. "owner/image".
+// - tag: The tag name.
+// - seed: Any string that makes the manifest (and so the digest) unique.
+// Returns: The manifest digest, e.g. "sha256:...".
+func (s *Server) SetTag(repository, tag, seed string) string {
+	manifest := fmt.Appendf(nil,
+		`{"schemaVersion":2,"mediaType":%q,"annotations":{"seed":%q}}`,
+		manifestMediaType, seed)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.manifests[repository+":"+tag] = manifest
+	return Digest(manifest)
+}
+
+// Requests returns how many authorized manifest requests were made for repository:tag.
+//
+// - repository: The repository path, e.g. "owner/image".
+// - tag: The tag name.
+// Returns: The number of requests.
+func (s *Server) Requests(repository, tag string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[repository+":"+tag]
+}
+
+// Digest returns the sha256 digest of a manifest body.
+//
+// - manifest: The manifest bytes.
+// Returns: The digest, e.g. "sha256:...".
+func Digest(manifest []byte) string {
+	sum := sha256.Sum256(manifest)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// handleToken issues the anonymous token requested by the auth challenge.
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{"token": testToken}) //nolint:errchkjson
+}
+
+// handleManifest serves /v2/<repository>/manifests/<tag>.
+func (s *Server) handleManifest(w http.ResponseWriter, r *http.Request) {
+	repository, tag, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v2/"), "/manifests/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if s.RequireAuth && r.Header.Get("Authorization") != "Bearer "+testToken {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(
+			`Bearer realm="%s/token",service="registrytest",scope="repository:%s:pull"`,
+			s.URL, repository))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	s.mu.Lock()
+	manifest, found := s.manifests[repository+":"+tag]
+	s.requests[repository+":"+tag]++
+	s.mu.Unlock()
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", manifestMediaType)
+	switch {
+	case s.DigestHeader != "":
+		w.Header().Set("Docker-Content-Digest", s.DigestHeader)
+	case !s.OmitDigestHeader:
+		w.Header().Set("Docker-Content-Digest", Digest(manifest))
+	}
+	if r.Method == http.MethodHead {
+		return
+	}
+	_, _ = w.Write(manifest)
+}
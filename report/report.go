@@ -0,0 +1,121 @@
+// SPDX-License-Identifier: MIT
+
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Actions describing what happened to a 'uses:' reference during a run.
+const (
+	ActionPinned    = "pinned"    // A tag or branch was replaced with its commit SHA
+	ActionUpdated   = "updated"   // A reference was moved to a newer commit SHA
+	ActionUnchanged = "unchanged" // The reference was already pinned or up to date
+	ActionUnpinned  = "unpinned"  // The reference is not pinned and was not resolved (check mode)
+	ActionSkipped   = "skipped"   // The reference is not a GitHub action or workflow (local, docker)
+	ActionError     = "error"     // The reference could not be parsed or resolved
+	ActionImpostor  = "impostor"  // The pinned commit is not reachable from any branch or tag of its repository
+)
+
+// Entry describes a single 'uses:' reference found in a workflow or action file
+// and the outcome of processing it.
+type Entry struct {
+	File        string `json:"file"`                   // Path of the file containing the reference
+	Line        int    `json:"line"`                   // 1-based line number of the 'uses:' value
+	Uses        string `json:"uses"`                   // The original 'uses:' value, or image for "container"
+	Type        string `json:"type"`                   // Reference type: "github", "docker", "container", "local", or "unknown"
+	Owner       string `json:"owner,omitempty"`        // Repository owner
+	Repo        string `json:"repo,omitempty"`         // Repository name without subpath
+	Subpath     string `json:"subpath,omitempty"`      // Path within the repository (actions or reusable workflows)
+	OldRef      string `json:"old_ref,omitempty"`      // The ref before processing
+	ResolvedSHA string `json:"resolved_sha,omitempty"` // The commit SHA the reference resolved to
+	CommentRef  string `json:"comment_ref,omitempty"`  // The ref written into the inline comment
+	Action      string `json:"action"`                 // What was done, one of the Action* constants
+	Error       string `json:"error,omitempty"`        // Why processing failed, if it did
+}
+
+// Report collects entries from a run. It is safe for concurrent use.
+type Report struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// New returns an empty Report.
+func New() *Report {
+	return &Report{}
+}
+
+// Add appends entries to the report.
+//
+// - entries: The entries to add.
+func (r *Report) Add(entries ...Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entries...)
+}
+
+// Entries returns a copy of the collected entries ordered by file and line.
+//
+// Returns: The sorted entries.
+func (r *Report) Entries() []Entry {
+	r.mu.Lock()
+	entries := slices.Clone(r.entries)
+	r.mu.Unlock()
+
+	slices.SortStableFunc(entries, func(a, b Entry) int {
+		if c := strings.Compare(a.File, b.File); c != 0 {
+			return c
+		}
+		return a.Line - b.Line
+	})
+	return entries
+}
+
+// WriteJSON writes the report as an indented JSON document.
+//
+// - w: The writer to write the JSON document to.
+// Returns: An error if encoding or writing fails.
+func (r *Report) WriteJSON(w io.Writer) error {
+	doc := struct {
+		Entries []Entry `json:"entries"`
+	}{Entries: r.Entries()}
+	// Always emit an array, even when nothing was found.
+	if doc.Entries == nil {
+		doc.Entries = []Entry{}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("failed to write JSON report: %w", err)
+	}
+	return nil
+}
+
+// Write writes the report in the given format.
+//
+// - w: The writer to write the report to.
+// - format: Either "json" or "sarif".
+// - version: The tool version recorded in SARIF output.
+// Returns: An error if the format is unknown or writing fails.
+func (r *Report) Write(w io.Writer, format, version string) error {
+	switch format {
+	case "json":
+		return r.WriteJSON(w)
+	case "sarif":
+		return r.WriteSARIF(w, version)
+	default:
+		return fmt.Errorf("unknown report format %q", format)
+	}
+}
+
+// artifactURI converts a file path to the forward-slash form SARIF expects.
+func artifactURI(path string) string {
+	return filepath.ToSlash(filepath.Clean(path))
+}
@@ -0,0 +1,162 @@
+// SPDX-License-Identifier: MIT
+
+package report_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/esacteksab/gh-actlock/report"
+)
+
+const pinnedSHA = "11d5960a326750d5838078e36cf38b85af677262"
+
+func sampleReport() *report.Report {
+	r := report.New()
+	r.Add(
+		report.Entry{
+			File: ".github/workflows/b.yml", Line: 9, Uses: "actions/checkout@" + pinnedSHA,
+			Type: "github", Owner: "actions", Repo: "checkout", OldRef: pinnedSHA,
+			ResolvedSHA: pinnedSHA, Action: report.ActionUnchanged,
+		},
+		report.Entry{
+			File: ".github/workflows/a.yml", Line: 12, Uses: "actions/checkout@vNope",
+			Type: "github", Owner: "actions", Repo: "checkout", OldRef: "vNope",
+			Action: report.ActionError, Error: "reference 'vNope' not found",
+		},
+		report.Entry{
+			File: ".github/workflows/a.yml", Line: 8, Uses: "gradle/actions/setup-gradle@v4",
+			Type: "github", Owner: "gradle", Repo: "actions", Subpath: "setup-gradle", OldRef: "v4",
+			ResolvedSHA: pinnedSHA, CommentRef: "v4", Action: report.ActionPinned,
+		},
+		report.Entry{
+			File: ".github/workflows/a.yml", Line: 10, Uses: "docker://alpine:3.20",
+			Type: "docker", Action: report.ActionSkipped,
+		},
+	)
+	return r
+}
+
+func TestEntries_SortedByFileAndLine(t *testing.T) {
+	entries := sampleReport().Entries()
+	require.Len(t, entries, 4)
+
+	got := make([]int, 0, len(entries))
+	for _, e := range entries {
+		got = append(got, e.Line)
+	}
+	assert.Equal(t, []int{8, 10, 12, 9}, got)
+	assert.Equal(t, ".github/workflows/b.yml", entries[3].File)
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, sampleReport().Write(&buf, "json", ""))
+
+	var doc struct {
+		Entries []map[string]any `json:"entries"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+	require.Len(t, doc.Entries, 4)
+
+	first := doc.Entries[0]
+	assert.Equal(t, "gradle", first["owner"])
+	assert.Equal(t, "actions", first["repo"])
+	assert.Equal(t, "setup-gradle", first["subpath"])
+	assert.Equal(t, "v4", first["old_ref"])
+	assert.Equal(t, pinnedSHA, first["resolved_sha"])
+	assert.Equal(t, "v4", first["comment_ref"])
+	assert.Equal(t, report.ActionPinned, first["action"])
+	assert.NotContains(t, first, "error")
+}
+
+func TestWriteJSON_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, report.New().WriteJSON(&buf))
+	assert.JSONEq(t, `{"entries": []}`, buf.String())
+}
+
+func TestWriteSARIF(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, sampleReport().Write(&buf, "sarif", "v1.2.3"))
+
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name    string `json:"name"`
+					Version string `json:"version"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Level     string `json:"level"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine int `json:"startLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &log))
+
+	assert.Equal(t, "2.1.0", log.Version)
+	require.Len(t, log.Runs, 1)
+	assert.Equal(t, "gh-actlock", log.Runs[0].Tool.Driver.Name)
+	assert.Equal(t, "v1.2.3", log.Runs[0].Tool.Driver.Version)
+
+	// The pinned SHA and the docker reference produce no results.
+	results := log.Runs[0].Results
+	require.Len(t, results, 2)
+	assert.Equal(t, report.RuleUnpinned, results[0].RuleID)
+	assert.Equal(t, "error", results[0].Level)
+	assert.Equal(t, ".github/workflows/a.yml", results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Equal(t, 8, results[0].Locations[0].PhysicalLocation.Region.StartLine)
+	assert.Equal(t, report.RuleResolutionError, results[1].RuleID)
+	assert.Equal(t, "warning", results[1].Level)
+	assert.Equal(t, 12, results[1].Locations[0].PhysicalLocation.Region.StartLine)
+}
+
+func TestWriteSARIF_Impostor(t *testing.T) {
+	r := report.New()
+	r.Add(report.Entry{
+		File: ".github/workflows/a.yml", Line: 7, Uses: "actions/checkout@" + pinnedSHA,
+		Type: "github", Owner: "actions", Repo: "checkout", OldRef: pinnedSHA,
+		Action: report.ActionImpostor, Error: "commit is not reachable from any branch or tag",
+	})
+	var buf bytes.Buffer
+	require.NoError(t, r.Write(&buf, "sarif", ""))
+
+	var log struct {
+		Runs []struct {
+			Results []struct {
+				RuleID string `json:"ruleId"`
+				Level  string `json:"level"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &log))
+
+	// A pinned SHA is only reported when it doesn't belong to the repository.
+	require.Len(t, log.Runs[0].Results, 1)
+	assert.Equal(t, report.RuleImpostorCommit, log.Runs[0].Results[0].RuleID)
+	assert.Equal(t, "error", log.Runs[0].Results[0].Level)
+}
+
+func TestWrite_UnknownFormat(t *testing.T) {
+	var buf bytes.Buffer
+	err := report.New().Write(&buf, "xml", "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown report format")
+}
@@ -0,0 +1,176 @@
+// SPDX-License-Identifier: MIT
+
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/esacteksab/gh-actlock/parser"
+)
+
+// SARIF rule identifiers reported by actlock.
+const (
+	RuleUnpinned        = "unpinned-action"      // A 'uses:' reference is not pinned to a commit SHA
+	RuleResolutionError = "unresolved-reference" // A 'uses:' reference could not be resolved
+	RuleImpostorCommit  = "impostor-commit"      // A pinned commit is not reachable from its repository's branches or tags
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	toolName     = "gh-actlock"
+	toolURI      = "https://github.com/esacteksab/gh-actlock"
+)
+
+// The types below model the subset of SARIF 2.1.0 needed to upload findings
+// to GitHub code scanning.
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	HelpURI          string       `json:"helpUri,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// sarifRules lists the rules every run declares, whether or not they produced results.
+var sarifRules = []sarifRule{
+	{
+		ID:               RuleUnpinned,
+		ShortDescription: sarifMessage{Text: "Action or reusable workflow is not pinned to a commit SHA"},
+		HelpURI:          toolURI + "#why-pin-github-actions",
+	},
+	{
+		ID:               RuleResolutionError,
+		ShortDescription: sarifMessage{Text: "Action or reusable workflow reference could not be resolved"},
+		HelpURI:          toolURI + "#troubleshooting",
+	},
+	{
+		ID:               RuleImpostorCommit,
+		ShortDescription: sarifMessage{Text: "Pinned commit is not reachable from any branch or tag of the repository"},
+		HelpURI:          toolURI + "#impostor-commits",
+	},
+}
+
+// WriteSARIF writes the report as a SARIF 2.1.0 log. Each GitHub reference whose
+// original ref was not a full commit SHA becomes an "unpinned-action" result, and
+// each reference that failed to resolve becomes an "unresolved-reference" result.
+// A commit that doesn't belong to the referenced repository becomes an "impostor-commit" result.
+//
+// - w: The writer to write the SARIF log to.
+// - version: The tool version to record in the log, may be empty.
+// Returns: An error if encoding or writing fails.
+func (r *Report) WriteSARIF(w io.Writer, version string) error {
+	results := []sarifResult{}
+	for _, e := range r.Entries() {
+		var result sarifResult
+		switch {
+		case e.Action == ActionImpostor:
+			result = sarifResult{
+				RuleID: RuleImpostorCommit,
+				Level:  "error",
+				Message: sarifMessage{Text: fmt.Sprintf(
+					"'%s' is pinned to a commit that is not reachable from any branch or tag of %s/%s",
+					e.Uses, e.Owner, e.Repo)},
+			}
+		case e.Action == ActionError:
+			result = sarifResult{
+				RuleID:  RuleResolutionError,
+				Level:   "warning",
+				Message: sarifMessage{Text: fmt.Sprintf("Could not resolve '%s': %s", e.Uses, e.Error)},
+			}
+		case e.Type == "github" && !isFullSHA(e.OldRef):
+			result = sarifResult{
+				RuleID: RuleUnpinned,
+				Level:  "error",
+				Message: sarifMessage{
+					Text: fmt.Sprintf("'%s' is not pinned to a full-length commit SHA", e.Uses),
+				},
+			}
+		default:
+			continue
+		}
+		result.Locations = []sarifLocation{{
+			PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: artifactURI(e.File)},
+				Region:           sarifRegion{StartLine: e.Line},
+			},
+		}}
+		results = append(results, result)
+	}
+
+	log := sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           toolName,
+				Version:        version,
+				InformationURI: toolURI,
+				Rules:          sarifRules,
+			}},
+			Results: results,
+		}},
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(log); err != nil {
+		return fmt.Errorf("failed to write SARIF report: %w", err)
+	}
+	return nil
+}
+
+// isFullSHA reports whether ref is a full-length hexadecimal commit SHA.
+func isFullSHA(ref string) bool {
+	return len(ref) == parser.SHALength && parser.IsHexString(ref)
+}
@@ -0,0 +1,191 @@
+// SPDX-License-Identifier: MIT
+
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// ignoreRule is a single compiled pattern from a .gitignore file.
+type ignoreRule struct {
+	base     string         // Directory of the .gitignore file, slash-separated and relative to the root ("" for the root)
+	re       *regexp.Regexp // The compiled pattern
+	negate   bool           // Pattern started with '!' and re-includes matching paths
+	dirOnly  bool           // Pattern ended with '/' and only matches directories
+	anchored bool           // Pattern contains a '/' and matches relative to base instead of any name
+}
+
+// GitIgnore matches paths against the rules of one or more .gitignore files.
+// It supports the commonly used subset of the gitignore format: comments, negation,
+// directory-only patterns, anchored patterns, and the '*', '?', '[...]' and '**' wildcards.
+type GitIgnore struct {
+	rules []ignoreRule
+}
+
+// AddFile reads a .gitignore-style file and adds its rules. A missing file is not an error.
+//
+// -filePath: The path to the ignore file on disk.
+// -base: The directory the rules apply to, slash-separated and relative to the root ("" for the root).
+// Returns: An error if the file exists but cannot be read.
+func (g *GitIgnore) AddFile(filePath, base string) error {
+	f, err := os.Open(filePath) //nolint:gosec
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to open ignore file %q: %w", filePath, err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		g.AddPattern(scanner.Text(), base)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read ignore file %q: %w", filePath, err)
+	}
+	return nil
+}
+
+// AddPattern adds a single gitignore pattern. Blank lines and comments are ignored.
+//
+// -pattern: One line of a .gitignore file.
+// -base: The directory the pattern applies to, slash-separated and relative to the root ("" for the root).
+func (g *GitIgnore) AddPattern(pattern, base string) {
+	// Trailing spaces are ignored unless escaped; we don't bother with the escape.
+	pattern = strings.TrimRight(pattern, " \t\r")
+	if pattern == "" || strings.HasPrefix(pattern, "#") {
+		return
+	}
+
+	rule := ignoreRule{base: strings.Trim(base, "/")}
+	if strings.HasPrefix(pattern, "!") {
+		rule.negate = true
+		pattern = pattern[1:]
+	} else if strings.HasPrefix(pattern, `\`) {
+		// "\#" and "\!" escape a leading special character.
+		pattern = pattern[1:]
+	}
+
+	if strings.HasSuffix(pattern, "/") {
+		rule.dirOnly = true
+		pattern = strings.TrimRight(pattern, "/")
+	}
+	// A slash anywhere but the end anchors the pattern to the .gitignore's directory.
+	if strings.Contains(pattern, "/") {
+		rule.anchored = true
+		pattern = strings.TrimPrefix(pattern, "/")
+	}
+	if pattern == "" {
+		return
+	}
+
+	re, err := regexp.Compile(globToRegexp(pattern))
+	if err != nil {
+		// An unparsable pattern (e.g. an unterminated class) never matches in git either.
+		return
+	}
+	rule.re = re
+	g.rules = append(g.rules, rule)
+}
+
+// Ignored reports whether a path is ignored. As in git, the last matching rule wins.
+// Callers walking a tree should skip ignored directories, since files inside an
+// ignored directory cannot be re-included.
+//
+// -relPath: The path to check, slash-separated and relative to the root.
+// -isDir: Whether the path is a directory.
+// Returns: true if the path is ignored.
+func (g *GitIgnore) Ignored(relPath string, isDir bool) bool {
+	if g == nil {
+		return false
+	}
+	relPath = strings.Trim(relPath, "/")
+
+	ignored := false
+	for _, rule := range g.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+
+		// Rules only apply to paths below the directory of their .gitignore file.
+		sub := relPath
+		if rule.base != "" {
+			var ok bool
+			sub, ok = strings.CutPrefix(relPath, rule.base+"/")
+			if !ok {
+				continue
+			}
+		}
+
+		// Unanchored patterns match the name at any depth.
+		if !rule.anchored {
+			sub = path.Base(sub)
+		}
+		if rule.re.MatchString(sub) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// globToRegexp translates a gitignore glob into an anchored regular expression.
+//
+// -pattern: The glob, without any leading '!' or trailing '/'.
+// Returns: The equivalent regular expression source.
+func globToRegexp(pattern string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				switch {
+				case i+2 < len(pattern) && pattern[i+2] == '/':
+					// "**/" matches zero or more leading directories.
+					b.WriteString("(?:.*/)?")
+					i += 2
+				default:
+					// A trailing "**" (or "/**") matches everything inside.
+					b.WriteString(".*")
+					i++
+				}
+				continue
+			}
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			// Copy a character class through, translating gitignore's '!' negation.
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case '\\':
+			// A backslash escapes the next character.
+			if i+1 < len(pattern) {
+				i++
+				b.WriteString(regexp.QuoteMeta(string(pattern[i])))
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
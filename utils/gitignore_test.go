@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: MIT
+
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitIgnore_Ignored(t *testing.T) {
+	g := &GitIgnore{}
+	for _, p := range []string{
+		"# comment",
+		"",
+		"node_modules",
+		"/build",
+		"*.log",
+		"!keep.log",
+		"tmp/",
+		"docs/**/generated",
+		"vendor/**",
+	} {
+		g.AddPattern(p, "")
+	}
+	// Rules from a nested .gitignore only apply below their directory.
+	g.AddPattern("action.yml", "sub")
+
+	tests := []struct {
+		name  string
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{name: "name_at_root", path: "node_modules", isDir: true, want: true},
+		{name: "name_at_depth", path: "a/b/node_modules", isDir: true, want: true},
+		{name: "anchored_at_root", path: "build", isDir: true, want: true},
+		{name: "anchored_not_at_depth", path: "a/build", isDir: true, want: false},
+		{name: "wildcard", path: "a/debug.log", want: true},
+		{name: "negated", path: "a/keep.log", want: false},
+		{name: "dir_only_matches_dir", path: "a/tmp", isDir: true, want: true},
+		{name: "dir_only_skips_file", path: "a/tmp", want: false},
+		{name: "double_star_middle", path: "docs/x/y/generated", isDir: true, want: true},
+		{name: "double_star_zero_dirs", path: "docs/generated", isDir: true, want: true},
+		{name: "double_star_trailing", path: "vendor/a/action.yml", want: true},
+		{name: "nested_rule_applies", path: "sub/x/action.yml", want: true},
+		{name: "nested_rule_scoped", path: "other/action.yml", want: false},
+		{name: "not_ignored", path: ".github/actions/a/action.yml", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, g.Ignored(tt.path, tt.isDir))
+		})
+	}
+}
+
+func TestGitIgnore_AddFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".gitignore")
+	require.NoError(t, os.WriteFile(path, []byte("dist/\n\\#notacomment\n"), 0o600))
+
+	g := &GitIgnore{}
+	require.NoError(t, g.AddFile(path, ""))
+	require.NoError(t, g.AddFile(filepath.Join(dir, "missing"), ""), "a missing file is not an error")
+
+	assert.True(t, g.Ignored("dist", true))
+	assert.True(t, g.Ignored("#notacomment", false))
+	assert.False(t, g.Ignored("src", true))
+}
@@ -125,41 +125,60 @@ func ValidateWorkflowFilePath(filePath string) error {
 		return fmt.Errorf("workflow path %q contains '..'", filePath)
 	}
 
+	// Get current working directory, which is assumed to be the project root.
+	// This will be used to verify that the file exists within the project boundaries.
+	wd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("could not get working directory: %w", err)
+	}
+
+	return ValidateWorkflowFilePathInRoot(filePath, wd)
+}
+
+// ValidateWorkflowFilePathInRoot validates that a workflow file path resolves to a
+// location inside the given project root. Unlike ValidateWorkflowFilePath, relative
+// paths containing '..' are allowed as long as they still resolve inside the root.
+//
+// -filePath: The path to the workflow file to validate, relative to the working directory or absolute.
+// -root: The project root directory the file must be contained in.
+// Returns: An error if the path traverses outside the root or cannot be resolved.
+func ValidateWorkflowFilePathInRoot(filePath, root string) error {
 	// Resolve the absolute path to eliminate any relative components and
 	// normalize the path for comparison with the project root.
 	absPath, err := filepath.Abs(filePath)
 	if err != nil {
 		return fmt.Errorf("could not get absolute path for %q: %w", filePath, err)
 	}
-
-	// Get current working directory, which is assumed to be the project root.
-	// This will be used to verify that the file exists within the project boundaries.
-	wd, err := os.Getwd()
+	absRoot, err := filepath.Abs(root)
 	if err != nil {
-		return fmt.Errorf("could not get working directory: %w", err)
+		return fmt.Errorf("could not get absolute path for root %q: %w", root, err)
 	}
 
-	// Check if the file is within the working directory or is the working directory itself.
-	// The file must either:
-	// 1. Have a path that begins with the working directory path followed by a separator, or
-	// 2. Be the working directory itself (though this is an edge case)
-	if !strings.HasPrefix(absPath, wd+string(filepath.Separator)) && absPath != wd {
-		return fmt.Errorf("workflow path %q resolves outside project root %q", filePath, wd)
+	// Check if the file is within the root or is the root itself.
+	// A path relative to the root that starts with '..' leaves the root.
+	rel, err := filepath.Rel(absRoot, absPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("workflow path %q resolves outside project root %q", filePath, absRoot)
 	}
 
 	// If all checks pass, the path is considered valid
 	return nil
 }
 
-func LogRateLimitStatus(limitType string) {
+// LogRateLimitStatus logs the authentication state CheckRateLimit found.
+//
+// - logger: Where to log.
+// - limitType: "authenticated", "unauthenticated", or anything else when unknown.
+// - source: Where the token came from, empty if there was none.
+func LogRateLimitStatus(logger *log.Logger, limitType, source string) {
 	switch limitType {
 	case "authenticated":
-		Logger.Print("🔧  Authenticated GitHub API access in effect.")
+		logger.Printf("🔧  Authenticated GitHub API access in effect (token from %s).", source)
 	case "unauthenticated":
-		Logger.Print(
+		logger.Print(
 			"⚠️  Unauthenticated GitHub API access in effect (lower rate limit).",
 		)
 	default:
-		Logger.Print("ℹ️  Could not determine GitHub API authentication status.")
+		logger.Print("ℹ️  Could not determine GitHub API authentication status.")
 	}
 }
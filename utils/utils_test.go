@@ -4,6 +4,7 @@ package utils
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/charmbracelet/log"
@@ -155,3 +156,31 @@ func Test_ValidateFilePath(t *testing.T) {
 		})
 	}
 }
+
+func Test_ValidateWorkflowFilePathInRoot(t *testing.T) {
+	root := t.TempDir()
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{name: "file_in_root", path: filepath.Join(root, ".github", "workflows", "ci.yml")},
+		{name: "nested_file", path: filepath.Join(root, "gen", "sub", "wf.yaml")},
+		{name: "dotdot_inside_root", path: filepath.Join(root, "gen", "..", "ci.yml")},
+		{name: "root_itself", path: root},
+		{name: "sibling_with_root_prefix", path: root + "-other/ci.yml", wantErr: true},
+		{name: "parent_directory", path: filepath.Join(root, "..", "ci.yml"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateWorkflowFilePathInRoot(tt.path, root)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "resolves outside project root")
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}